@@ -0,0 +1,362 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "fmt"
+    "slices"
+)
+
+// battleResult is everything we measure in a single battle.
+type battleResult struct {
+    partyWon            bool
+    enemiesWon          bool
+    rounds              int
+    roundsToKillEnemy   []int
+    roundsToLoseMember  []int
+    partyHits           []int
+    enemyHits           []int
+    partyMisses         int
+    enemyMisses         int
+    enemiesFled         int
+    enemiesSurrendered  int
+    opportunityAttacks  int
+    criticalHits        int
+    fumbles             int
+    companions          int
+}
+
+// battle runs one fight between the party and a group of enemies on a synthetic map.
+// Both sides are controlled by the tactical AI of the game, attacks are resolved by the combat rules of the game.
+type battle struct {
+    engine     *headlessEngine
+    gridMap    *gridmap.GridMap[*game.Actor, game.Item, game.Object]
+    party      *game.Party
+    enemies    []*game.Actor
+    companions []*game.Actor
+    tactics    *game.TacticalAI
+    combat     *game.Combat
+    movesTaken map[*game.Actor]int
+    isDown     map[*game.Actor]bool
+    hasLeft    map[*game.Actor]bool
+    result     battleResult
+}
+
+func newBattle(engine *headlessEngine, gridMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], party *game.Party, enemies []*game.Actor) *battle {
+    b := &battle{
+        engine:     engine,
+        gridMap:    gridMap,
+        party:      party,
+        enemies:    enemies,
+        movesTaken: make(map[*game.Actor]int),
+        isDown:     make(map[*game.Actor]bool),
+        hasLeft:    make(map[*game.Actor]bool),
+    }
+    b.tactics = game.NewTacticalAI(engine, b)
+    b.combat = game.NewCombat(engine, b)
+    b.combat.SetObserver(b)
+    engine.battle = b
+    return b
+}
+
+func (b *battle) GetMovesLeft(actor *game.Actor) int {
+    return actor.GetMovementAllowance() - b.movesTaken[actor]
+}
+
+func (b *battle) GetActiveOpponents(actor *game.Actor) []*game.Actor {
+    if b.IsOnPartySide(actor) {
+        return b.activeOf(b.enemies, actor)
+    }
+    return b.activeOf(b.partySide(), actor)
+}
+
+func (b *battle) GetActiveAllies(actor *game.Actor) []*game.Actor {
+    if b.IsOnPartySide(actor) {
+        return b.activeOf(b.partySide(), actor)
+    }
+    return b.activeOf(b.enemies, actor)
+}
+
+func (b *battle) IsOnPartySide(actor *game.Actor) bool {
+    return b.engine.IsPlayerControlled(actor) || slices.Contains(b.companions, actor)
+}
+
+func (b *battle) IsParticipating(actor *game.Actor) bool {
+    return b.isPresent(actor)
+}
+
+func (b *battle) OnActorDied(actor *game.Actor) {
+    b.checkForDeath(actor)
+}
+
+func (b *battle) OnMiss(attacker *game.Actor) {
+    if b.IsOnPartySide(attacker) {
+        b.result.partyMisses++
+    } else {
+        b.result.enemyMisses++
+    }
+}
+
+func (b *battle) OnFumble(attacker *game.Actor) {
+    b.result.fumbles++
+}
+
+func (b *battle) OnOpportunityAttack(attacker *game.Actor) {
+    b.result.opportunityAttacks++
+}
+
+func (b *battle) partySide() []*game.Actor {
+    return append(slices.Clone(b.party.GetMembers()), b.companions...)
+}
+
+// summon and charm work like in the game, but only for the party.
+func (b *battle) summon(caster *game.Actor, pos geometry.Point, monster game.SummonableMonster, rounds int) {
+    if !b.IsOnPartySide(caster) || b.gridMap.IsActorAt(pos) {
+        return
+    }
+    summoned, err := b.engine.loader.load(monster.NpcName)
+    if err != nil {
+        b.engine.Print(err.Error())
+        return
+    }
+    b.engine.rules.PrepareAsSummon(summoned, caster.GetLevel())
+    b.gridMap.AddActor(summoned, pos)
+    b.addCompanion(summoned, game.CompanionKindSummoned, rounds)
+    b.engine.combatLog.LogEffect(summoned, fmt.Sprintf("is summoned for %d rounds", rounds))
+}
+
+func (b *battle) charm(caster *game.Actor, victim *game.Actor, rounds int) {
+    if !b.IsOnPartySide(caster) || !slices.Contains(b.enemies, victim) {
+        return
+    }
+    b.enemies = slices.DeleteFunc(b.enemies, func(enemy *game.Actor) bool { return enemy == victim })
+    b.addCompanion(victim, game.CompanionKindCharmed, rounds)
+    b.engine.combatLog.LogEffect(victim, fmt.Sprintf("is charmed for %d rounds", rounds))
+}
+
+func (b *battle) addCompanion(actor *game.Actor, kind game.CompanionKind, rounds int) {
+    actor.BecomeCompanion(kind, rounds)
+    b.companions = append(b.companions, actor)
+    b.result.companions++
+}
+
+// dismissCompanion lets summoned creatures vanish and sends charmed ones back to the enemies.
+func (b *battle) dismissCompanion(actor *game.Actor) {
+    isSummoned := actor.IsSummoned()
+    actor.ReleaseCompanion()
+    b.companions = slices.DeleteFunc(b.companions, func(companion *game.Actor) bool { return companion == actor })
+    if isSummoned {
+        b.hasLeft[actor] = true
+        b.gridMap.RemoveActor(actor)
+        b.engine.combatLog.LogEffect(actor, "vanishes")
+        return
+    }
+    b.engine.combatLog.LogEffect(actor, "is no longer charmed")
+    b.enemies = append(b.enemies, actor)
+}
+
+func (b *battle) activeOf(actors []*game.Actor, except *game.Actor) []*game.Actor {
+    var result []*game.Actor
+    for _, actor := range actors {
+        if actor != except && b.isPresent(actor) && !actor.IsSleeping() {
+            result = append(result, actor)
+        }
+    }
+    return result
+}
+
+func (b *battle) isPresent(actor *game.Actor) bool {
+    return actor.IsAlive() && !b.isDown[actor] && !b.hasLeft[actor]
+}
+
+func (b *battle) countPresent(actors []*game.Actor) int {
+    count := 0
+    for _, actor := range actors {
+        if b.isPresent(actor) {
+            count++
+        }
+    }
+    return count
+}
+
+// tickEnvironment lets fire spread, like the game does at the start of each round.
+func (b *battle) tickEnvironment() {
+    if !b.gridMap.HasActiveEnvironment() {
+        return
+    }
+    for _, pos := range b.gridMap.TickEnvironment().Burning {
+        if b.gridMap.IsActorAt(pos) {
+            b.engine.setOnFire(b.gridMap.ActorAt(pos))
+        }
+    }
+}
+
+func (b *battle) isOver() bool {
+    return b.countPresent(b.party.GetMembers()) == 0 || b.countPresent(b.enemies) == 0
+}
+
+func (b *battle) run(maxRounds int) battleResult {
+    // the simulated party always starts the fight
+    b.combat.Start(true)
+    for b.combat.GetRound() < maxRounds && !b.isOver() {
+        b.combat.StartNewRound()
+        b.tickEnvironment()
+        for _, actor := range b.turnOrder() {
+            if b.isOver() {
+                break
+            }
+            if !b.isPresent(actor) {
+                continue
+            }
+            b.movesTaken[actor] = 0
+            if actor.IsCompanion() && actor.TickCompanionRound() {
+                b.dismissCompanion(actor)
+                continue
+            }
+            if actor.IsStunned() {
+                // the stun wears off, but the turn is lost
+                actor.OnNewCombatTurn(b.engine)
+                continue
+            }
+            actor.OnNewCombatTurn(b.engine)
+            b.checkForDeath(actor)
+            if b.isPresent(actor) && actor.CanAct() {
+                b.takeTurn(actor)
+            }
+        }
+    }
+    b.combat.End()
+    b.result.rounds = b.combat.GetRound()
+    b.result.partyWon = b.countPresent(b.enemies) == 0 && b.countPresent(b.party.GetMembers()) > 0
+    b.result.enemiesWon = b.countPresent(b.party.GetMembers()) == 0
+    return b.result
+}
+
+// turnOrder sorts everyone who can act by initiative, like the game does.
+func (b *battle) turnOrder() []*game.Actor {
+    var participants []*game.Actor
+    for _, actor := range append(b.partySide(), b.enemies...) {
+        if b.isPresent(actor) && actor.CanAct() {
+            participants = append(participants, actor)
+        }
+    }
+    return b.combat.SortByInitiative(participants)
+}
+
+func (b *battle) takeTurn(actor *game.Actor) {
+    // spells and thrown items can only target what the party sees
+    b.gridMap.UpdateFieldOfView(b.party.GetFoV(), b.engine.GetAvatar().Pos())
+    action, reachablePositions := b.tactics.ChooseAction(actor)
+    switch action.ActionType {
+    case game.AttackActionTypeSurrender:
+        // the simulated party always accepts
+        b.hasLeft[actor] = true
+        b.result.enemiesSurrendered++
+        return
+    case game.AttackActionTypeEscape:
+        b.hasLeft[actor] = true
+        if !b.IsOnPartySide(actor) {
+            b.result.enemiesFled++
+        }
+        return
+    }
+
+    if !b.moveTo(actor, reachablePositions, action.MovementDestination) {
+        return
+    }
+    if action.ActionTargetLocation == nil || action.ActionType == game.AttackActionTypeFlee {
+        return
+    }
+    targetPos := *action.ActionTargetLocation
+    if b.gridMap.IsActorAt(targetPos) {
+        b.tactics.SetFocusTarget(actor, b.gridMap.ActorAt(targetPos))
+    }
+
+    if action.ActiveAction != nil {
+        b.engine.Print(fmt.Sprintf("%s uses %s", actor.Name(), action.ActiveAction.Name()))
+    }
+    if action.ActionType == game.AttackActionTypeMelee {
+        if b.gridMap.IsActorAt(targetPos) {
+            b.combat.MeleeAttack(actor, b.gridMap.ActorAt(targetPos), nil)
+        }
+    } else if action.ActiveAction != nil && !action.ActiveAction.IsTargeted() {
+        action.ActiveAction.Execute(b.engine, actor)
+    } else if action.ActionType == game.AttackActionTypeActiveSkill || action.ActionType == game.AttackActionTypeSpell {
+        actor.OnAttackPerformed(b.engine)
+        if impactPos, hasImpact := b.impactPosition(actor, targetPos); hasImpact {
+            action.ActiveAction.ExecuteOnTarget(b.engine, actor, impactPos)
+        }
+    } else if action.ActionType == game.AttackActionTypeRanged {
+        b.rangedAttack(actor, targetPos)
+    }
+}
+
+// moveTo walks the actor to its destination, step by step.
+// Returns false, if the actor was killed or pinned down by an opportunity attack on the way.
+func (b *battle) moveTo(actor *game.Actor, reachablePositions map[geometry.Point]int, dest geometry.Point) bool {
+    for _, step := range game.GetCombatPath(b.gridMap, actor, reachablePositions, dest) {
+        from := actor.Pos()
+        b.gridMap.MoveActor(actor, step)
+        b.movesTaken[actor]++
+        if !b.combat.ProvokeOpportunityAttacks(actor, from) {
+            return false
+        }
+    }
+    return true
+}
+
+func (b *battle) rangedAttack(attacker *game.Actor, targetPos geometry.Point) {
+    if !attacker.HasRangedWeaponEquipped() {
+        return
+    }
+    attacker.OnAttackPerformed(b.engine)
+    impactPos, hasImpact := b.impactPosition(attacker, targetPos)
+    if !hasImpact || !b.gridMap.IsActorAt(impactPos) {
+        return
+    }
+    b.combat.ResolveRangedHit(attacker, b.gridMap.ActorAt(impactPos))
+}
+
+// impactPosition is where a projectile aimed at the target will land.
+func (b *battle) impactPosition(attacker *game.Actor, targetPos geometry.Point) (geometry.Point, bool) {
+    lineOfFire := game.GetLineOfFire(b.gridMap, attacker.Pos(), targetPos, game.IsBlockingLineOfFireFor(attacker))
+    if len(lineOfFire) == 0 {
+        return geometry.Point{}, false
+    }
+    return lineOfFire[len(lineOfFire)-1], true
+}
+
+func (b *battle) dealDamage(attacker *game.Actor, victim *game.Actor, amount int) {
+    amount = max(0, amount)
+    if amount > 0 {
+        victim.Damage(b.engine, amount)
+    }
+    if attacker != nil {
+        if b.IsOnPartySide(attacker) {
+            b.result.partyHits = append(b.result.partyHits, amount)
+        } else {
+            b.result.enemyHits = append(b.result.enemyHits, amount)
+        }
+    }
+    b.checkForDeath(victim)
+}
+
+func (b *battle) checkForDeath(actor *game.Actor) {
+    if actor.IsAlive() || b.isDown[actor] {
+        return
+    }
+    b.isDown[actor] = true
+    b.engine.combatLog.LogEffect(actor, "dies")
+    b.tactics.OnCasualty(actor)
+    if actor.IsSummoned() {
+        b.gridMap.RemoveActor(actor)
+    } else {
+        b.gridMap.SetActorToDowned(actor)
+    }
+    if b.engine.IsPlayerControlled(actor) {
+        b.result.roundsToLoseMember = append(b.result.roundsToLoseMember, b.combat.GetRound())
+    } else if !actor.IsCompanion() {
+        b.result.roundsToKillEnemy = append(b.result.roundsToKillEnemy, b.combat.GetRound())
+    }
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "Legacy/renderer/atlas"
+    "Legacy/util"
+    "fmt"
+    "image/color"
+)
+
+// headlessEngine implements game.Engine for automated battles.
+// Everything that needs a screen or the world outside the battle does nothing.
+type headlessEngine struct {
+    rules     *game.Rules
+    combatLog *game.CombatLog
+    flags   *game.Flags
+    battle  *battle
+    loader  *npcLoader
+    verbose bool
+}
+
+func newHeadlessEngine(rules *game.Rules) *headlessEngine {
+    return &headlessEngine{
+        rules:     rules,
+        combatLog: game.NewCombatLog(),
+        flags:     game.NewFlags(),
+    }
+}
+
+func (e *headlessEngine) Print(text string) {
+    if e.verbose {
+        println(text)
+    }
+}
+
+func (e *headlessEngine) GetRules() *game.Rules {
+    return e.rules
+}
+
+func (e *headlessEngine) GetCombatLog() *game.CombatLog {
+    return e.combatLog
+}
+
+func (e *headlessEngine) GetGridMap() *gridmap.GridMap[*game.Actor, game.Item, game.Object] {
+    return e.battle.gridMap
+}
+
+func (e *headlessEngine) IsPlayerControlled(holder game.ItemHolder) bool {
+    for _, member := range e.battle.party.GetMembers() {
+        if member == holder {
+            return true
+        }
+    }
+    return e.battle.party == holder
+}
+
+func (e *headlessEngine) GetAvatar() *game.Actor {
+    return e.battle.party.GetMembers()[0]
+}
+
+func (e *headlessEngine) GetPartyMembers() []*game.Actor {
+    return e.battle.party.GetMembers()
+}
+
+func (e *headlessEngine) GetParty() *game.Party {
+    return e.battle.party
+}
+
+func (e *headlessEngine) GetPartySize() int {
+    return len(e.battle.party.GetMembers())
+}
+
+func (e *headlessEngine) CombatHitAnimation(pos geometry.Point, atlasName atlas.Name, icon int32, tintColor color.Color, whenDone func()) {
+    if whenDone != nil {
+        whenDone()
+    }
+}
+
+func (e *headlessEngine) DeliverMeleeDamage(attacker *game.Actor, victim *game.Actor, isCritical bool) {
+    breakdown := e.rules.GetMeleeDamageBreakdown(attacker, victim, isCritical)
+    e.combatLog.LogDamage(attacker, victim, breakdown)
+    e.battle.dealDamage(attacker, victim, breakdown.Total)
+    attacker.OnMeleeHitPerformed(e, victim)
+    if isCritical {
+        e.battle.result.criticalHits++
+        attacker.OnCriticalHitPerformed(e, victim, false)
+    }
+}
+
+func (e *headlessEngine) DeliverRangedDamage(attacker *game.Actor, victim *game.Actor, isCritical bool) {
+    breakdown := e.rules.GetRangedDamageBreakdown(attacker, victim, isCritical)
+    e.combatLog.LogDamage(attacker, victim, breakdown)
+    e.battle.dealDamage(attacker, victim, breakdown.Total)
+    attacker.OnRangedHitPerformed(e, victim)
+    if isCritical {
+        e.battle.result.criticalHits++
+        attacker.OnCriticalHitPerformed(e, victim, true)
+    }
+}
+
+func (e *headlessEngine) FixedDamageAt(caster *game.Actor, pos geometry.Point, amount int, damageType game.DamageType) {
+    gridMap := e.battle.gridMap
+    if !gridMap.Contains(pos) || !gridMap.IsActorAt(pos) {
+        return
+    }
+    victim := gridMap.ActorAt(pos)
+    breakdown := e.rules.GetSpellDamageBreakdown(victim, amount, damageType)
+    e.combatLog.LogDamage(caster, victim, breakdown)
+    e.battle.dealDamage(caster, victim, breakdown.Total)
+}
+
+func (e *headlessEngine) IgniteAt(pos geometry.Point) {
+    gridMap := e.battle.gridMap
+    gridMap.Ignite(pos)
+    if gridMap.IsActorAt(pos) {
+        e.setOnFire(gridMap.ActorAt(pos))
+    }
+}
+
+func (e *headlessEngine) ExtinguishAt(pos geometry.Point) {
+    gridMap := e.battle.gridMap
+    gridMap.Extinguish(pos)
+    if gridMap.IsActorAt(pos) {
+        gridMap.ActorAt(pos).RemoveStatusEffect(e, game.StatusEffectNameBurning)
+    }
+}
+
+func (e *headlessEngine) setOnFire(actor *game.Actor) {
+    if !actor.IsAlive() || actor.IsImmuneTo(game.StatusEffectNameBurning) {
+        return
+    }
+    e.AddStatusEffect(actor, game.StatusBurning(), 1)
+}
+
+func (e *headlessEngine) WeaponDamageAt(attacker *game.Actor, weapon *game.Weapon, pos geometry.Point, damagePercent int) *game.Actor {
+    gridMap := e.battle.gridMap
+    if !gridMap.Contains(pos) || !gridMap.IsActorAt(pos) {
+        return nil
+    }
+    victim := gridMap.ActorAt(pos)
+    if victim == attacker || !victim.IsAlive() {
+        return nil
+    }
+    var attackRoll game.AttackRoll
+    var breakdown game.DamageBreakdown
+    if weapon.IsRanged() {
+        attackRoll = e.rules.RollRangedAttack(e.combatLog, attacker, victim)
+        breakdown = e.rules.GetRangedDamageBreakdown(attacker, victim, attackRoll.IsCritical())
+    } else {
+        attackRoll = e.rules.RollMeleeAttack(e.combatLog, attacker, victim)
+        breakdown = e.rules.GetMeleeDamageBreakdown(attacker, victim, attackRoll.IsCritical())
+    }
+    if attackRoll.IsFumble() {
+        e.battle.combat.Fumble(attacker)
+    }
+    if !attackRoll.IsHit() || (!weapon.IsRanged() && e.rules.DoesShieldBlock(victim)) {
+        e.battle.OnMiss(attacker)
+        return nil
+    }
+    breakdown = breakdown.Scaled(damagePercent)
+    e.combatLog.LogDamage(attacker, victim, breakdown)
+    e.battle.dealDamage(attacker, victim, breakdown.Total)
+    weapon.OnHitProc(e, attacker, victim)
+    if attackRoll.IsCritical() {
+        e.battle.result.criticalHits++
+        if victim.IsAlive() {
+            weapon.OnCriticalHit(e, attacker, victim)
+        }
+    }
+    return victim
+}
+
+func (e *headlessEngine) KnockBack(attacker *game.Actor, victim *game.Actor) bool {
+    delta := victim.Pos().Sub(attacker.Pos())
+    direction := geometry.Point{X: sign(delta.X), Y: sign(delta.Y)}
+    dest := victim.Pos().Add(direction)
+    gridMap := e.battle.gridMap
+    if direction == geometry.PointZero || !gridMap.Contains(dest) || !gridMap.IsCurrentlyPassable(dest) {
+        return false
+    }
+    gridMap.MoveActor(victim, dest)
+    return true
+}
+
+func (e *headlessEngine) Reposition(actor *game.Actor, dest geometry.Point) {
+    e.battle.gridMap.MoveActor(actor, dest)
+}
+
+func (e *headlessEngine) ProdActor(prodder *game.Actor, victim *game.Actor) {
+    if prodder.IsRightNextTo(victim) {
+        e.KnockBack(prodder, victim)
+    }
+}
+
+func (e *headlessEngine) AddStatusEffect(victim *game.Actor, statusEffect game.StatusEffect, stacks int) {
+    for i := 0; i < stacks; i++ {
+        victim.AddStatusEffect(e, statusEffect)
+    }
+    e.combatLog.LogEffect(victim, fmt.Sprintf("receives %s x%d", statusEffect.Name(), stacks))
+}
+
+func (e *headlessEngine) DrinkPotion(potion *game.Potion, drinker *game.Actor) {
+    potion.OnDrink(e, drinker)
+    e.RemoveItem(potion)
+    potion.SetEmpty()
+}
+
+func (e *headlessEngine) ManaSpent(caster *game.Actor, cost int) {
+    caster.RemoveMana(cost)
+}
+
+func (e *headlessEngine) RemoveItem(item game.Item) {
+    if item.IsHeld() {
+        item.GetHolder().RemoveItem(item)
+    }
+}
+
+func (e *headlessEngine) Kill(actor *game.Actor) {
+    if actor.IsAlive() {
+        e.battle.dealDamage(nil, actor, actor.GetHealth())
+    }
+}
+
+func (e *headlessEngine) DamageAvatar(amount int) {
+    e.battle.dealDamage(nil, e.GetAvatar(), amount)
+}
+
+func (e *headlessEngine) GetAoECircle(source geometry.Point, radius int) []geometry.Point {
+    var result []geometry.Point
+    for x := -radius; x <= radius; x++ {
+        for y := -radius; y <= radius; y++ {
+            if x*x+y*y <= radius*radius {
+                result = append(result, source.Add(geometry.Point{X: x, Y: y}))
+            }
+        }
+    }
+    return result
+}
+
+func (e *headlessEngine) SkillCheck(actor *game.Actor, skill game.SkillName, difficulty game.DifficultyLevel) bool {
+    skills := actor.GetSkills()
+    if !skills.HasSkill(skill) {
+        return game.RollChance(0.01)
+    }
+    isSuccess, _ := e.rules.RollSkillCheckWithLuck(skills.GetLevel(skill), difficulty, actor.GetLuckModifier())
+    return isSuccess
+}
+
+func (e *headlessEngine) SkillCheckAvatar(skill game.SkillName, difficulty game.DifficultyLevel) bool {
+    return e.SkillCheck(e.GetAvatar(), skill, difficulty)
+}
+
+func (e *headlessEngine) HasSkill(skill game.SkillName) bool {
+    return e.GetAvatar().GetSkills().HasSkill(skill)
+}
+
+func (e *headlessEngine) GetRelativeDifficulty(skill game.SkillName, difficulty game.DifficultyLevel) game.DifficultyLevel {
+    return e.rules.GetRelativeDifficulty(e.GetAvatar().GetSkills().GetLevel(skill), difficulty)
+}
+
+func (e *headlessEngine) CanLevelUp(member *game.Actor) (bool, int) {
+    return e.rules.CanLevelUp(member.GetLevel(), member.GetXP())
+}
+
+func (e *headlessEngine) Flags() *game.Flags {
+    return e.flags
+}
+
+func (e *headlessEngine) GetWorldTime() game.WorldTime {
+    return game.NewWorldTime()
+}
+
+func (e *headlessEngine) CurrentTick() uint64 {
+    return 0
+}
+
+func (e *headlessEngine) TicksToSeconds(ticks uint64) float64 {
+    return float64(ticks) / util.TicksPerSecond()
+}
+
+func (e *headlessEngine) GetMapName() string {
+    return "combat_simulator"
+}
+
+func (e *headlessEngine) GetVisibleMap() geometry.Rect {
+    return geometry.NewRect(0, 0, e.battle.gridMap.MapWidth, e.battle.gridMap.MapHeight)
+}
+
+func (e *headlessEngine) GetPartyEquipment() []game.Item {
+    return nil
+}
+
+func (e *headlessEngine) GetActorByInternalName(internalName string) *game.Actor {
+    for _, actor := range e.battle.gridMap.Actors() {
+        if actor.GetInternalName() == internalName {
+            return actor
+        }
+    }
+    return nil
+}
+
+// Everything below has no meaning in an automated battle.
+
+func (e *headlessEngine) StartConversation(a *game.Actor, conversation *game.Dialogue) {}
+func (e *headlessEngine) ShowScrollableText(text []string, textcolor color.Color, autolayout bool) {}
+func (e *headlessEngine) GetScrollFile(filename string) []string { return nil }
+func (e *headlessEngine) PickUpItem(item game.Item) {}
+func (e *headlessEngine) DropItem(item game.Item) {}
+func (e *headlessEngine) SwitchAvatarTo(member *game.Actor) {}
+func (e *headlessEngine) CreateLootForContainer(level int, lootType []game.Loot) []game.Item { return nil }
+func (e *headlessEngine) ShowContainer(container game.ItemContainer) {}
+func (e *headlessEngine) OpenPickpocketMenu(victim *game.Actor) {}
+func (e *headlessEngine) OpenPlantMenu(victim *game.Actor) {}
+func (e *headlessEngine) AddFood(amount int) {}
+func (e *headlessEngine) AddGold(amount int) {}
+func (e *headlessEngine) AddLockpicks(amount int) {}
+func (e *headlessEngine) RemoveLockpick() {}
+func (e *headlessEngine) SpringTrap(trap *game.Trap, victim *game.Actor) {}
+func (e *headlessEngine) ShowDrinkPotionMenu(potion *game.Potion) {}
+func (e *headlessEngine) TriggerEvent(event string) {}
+func (e *headlessEngine) ShowEquipMenu(a game.Equippable) {}
+func (e *headlessEngine) PlayerStartsCombat(opponent *game.Actor) {}
+func (e *headlessEngine) PlayerTriesBackstab(opponent *game.Actor) {}
+func (e *headlessEngine) PlayerStartsOffensiveSpell(caster *game.Actor, spell *game.Spell) {}
+func (e *headlessEngine) FreezeActorAt(pos geometry.Point, turns int) {}
+func (e *headlessEngine) GetBreakingToolName() string { return "" }
+func (e *headlessEngine) AskUserForString(prompt string, maxLength int, onConfirm func(text string)) {}
+func (e *headlessEngine) TeleportTo(text string) {}
+func (e *headlessEngine) GetRandomPositionsInRegion(regionName string, count int) []geometry.Point { return nil }
+func (e *headlessEngine) ChangeAppearance() {}
+func (e *headlessEngine) RemoveDoorAt(pos geometry.Point) {}
+func (e *headlessEngine) SetWallAt(pos geometry.Point) {}
+func (e *headlessEngine) PlayerMovement(point geometry.Point) {}
+func (e *headlessEngine) GetRegion(regionName string) geometry.Rect { return geometry.Rect{} }
+func (e *headlessEngine) DrawCharInWorld(charToDraw rune, pos geometry.Point) {}
+func (e *headlessEngine) RaiseAsUndeadAt(caster *game.Actor, pos geometry.Point) {}
+func (e *headlessEngine) SummonMonsterAt(caster *game.Actor, pos geometry.Point, monster game.SummonableMonster, rounds int) {
+    e.battle.summon(caster, pos, monster, rounds)
+}
+func (e *headlessEngine) Charm(caster *game.Actor, victim *game.Actor, rounds int) {
+    e.battle.charm(caster, victim, rounds)
+}
+func (e *headlessEngine) GetDialogueFromFile(conversationId string) *game.Dialogue { return nil }
+func (e *headlessEngine) OpenMenu(actions []util.MenuItem) {}
+func (e *headlessEngine) OpenEquipmentDetails(partyIndex int) {}
+func (e *headlessEngine) EquipItem(actor *game.Actor, item game.Equippable) {}
+func (e *headlessEngine) OpenPartyInventoryOnPage(page int) {}
+func (e *headlessEngine) CloseAllModals() {}
+func (e *headlessEngine) AdvanceWorldTime(days, hours, minutes int) {}
+func (e *headlessEngine) TakeItem(item game.Item) {}
+func (e *headlessEngine) TransitionToNamedLocation(targetMap string, location string) {}
+func (e *headlessEngine) ResetAllLockedDoorsOnMap(mapName string) {}
+func (e *headlessEngine) GetChestByInternalName(internalName string) *game.Chest { return nil }
+func (e *headlessEngine) CloseConversation() {}
+func (e *headlessEngine) UnlockDoorsByKeyName(keyName string) {}
+func (e *headlessEngine) TryMoveNPCOnPath(actor *game.Actor, dest geometry.Point) {}
+func (e *headlessEngine) IsSneaking() bool { return false }
+func (e *headlessEngine) PickedLockAt(location geometry.Point) {}
+func (e *headlessEngine) ShowMultipleChoiceDialogue(canBeClosed bool, icon int32, text [][]string, choices []util.MenuItem) {
+}
+
+func sign(x int) int {
+    if x < 0 {
+        return -1
+    }
+    if x > 0 {
+        return 1
+    }
+    return 0
+}
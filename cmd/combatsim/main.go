@@ -0,0 +1,258 @@
+// Command combatsim runs automated battles between a party and a group of enemies for balancing.
+// Both sides are built from the files in assets/npc and fight with the rules and the combat AI of the game.
+//
+//  go run ./cmd/combatsim -party knight,ranger -enemies "grey_rat*3,rat_king" -levels 1-5 -runs 1000
+//
+// With -log, the detailed combat log of every battle is written to a text file.
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "bytes"
+    "flag"
+    "fmt"
+    "io"
+    "math/rand"
+    "os"
+    "path"
+    "strconv"
+    "strings"
+    "time"
+)
+
+type simConfig struct {
+    assetPath  string
+    party      []string
+    enemies    []string
+    gear       []string
+    enemyItems []string
+    runs       int
+    maxRounds  int
+    verbose    bool
+    combatLog  io.Writer
+}
+
+func main() {
+    partyFlag := flag.String("party", "knight,ranger", "comma separated npc files for the party")
+    enemiesFlag := flag.String("enemies", "grey_rat*3,rat_king", "comma separated npc files for the enemies, use name*count for groups")
+    gearFlag := flag.String("gear", "", "semicolon separated item predicates equipped by every party member")
+    enemyItemsFlag := flag.String("enemy-items", "", "semicolon separated item predicates given to every enemy")
+    levelsFlag := flag.String("levels", "1-5", "party levels to simulate, eg. 3 or 1-5")
+    runsFlag := flag.Int("runs", 1000, "battles per level")
+    maxRoundsFlag := flag.Int("max-rounds", 50, "rounds until a battle counts as undecided")
+    seedFlag := flag.Int64("seed", 0, "random seed, 0 uses the current time")
+    assetsFlag := flag.String("assets", "assets", "path to the asset directory")
+    verboseFlag := flag.Bool("verbose", false, "print the combat log")
+    logFlag := flag.String("log", "", "file to write the detailed combat log of every battle to")
+    flag.Parse()
+
+    minLevel, maxLevel, err := parseLevels(*levelsFlag)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(2)
+    }
+    seed := *seedFlag
+    if seed == 0 {
+        seed = time.Now().UnixNano()
+    }
+    rand.Seed(seed)
+
+    config := simConfig{
+        assetPath:  *assetsFlag,
+        party:      splitList(*partyFlag, ","),
+        enemies:    expandGroups(splitList(*enemiesFlag, ",")),
+        gear:       splitList(*gearFlag, ";"),
+        enemyItems: splitList(*enemyItemsFlag, ";"),
+        runs:       *runsFlag,
+        maxRounds:  *maxRoundsFlag,
+        verbose:    *verboseFlag,
+    }
+    if len(config.party) == 0 || len(config.enemies) == 0 {
+        fmt.Fprintln(os.Stderr, "need at least one party member and one enemy")
+        os.Exit(2)
+    }
+    if *logFlag != "" {
+        logFile, err := os.Create(*logFlag)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        defer logFile.Close()
+        config.combatLog = logFile
+    }
+
+    loader := newNpcLoader(config.assetPath)
+    fmt.Printf("Party: %s vs. Enemies: %s (%d runs per level, seed %d)\n\n", strings.Join(config.party, ", "), strings.Join(config.enemies, ", "), config.runs, seed)
+    for level := minLevel; level <= maxLevel; level++ {
+        var results []battleResult
+        for i := 0; i < config.runs; i++ {
+            result, err := simulate(loader, config, level)
+            if err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(1)
+            }
+            results = append(results, result)
+        }
+        printReport(os.Stdout, level, results)
+    }
+}
+
+// simulate builds fresh actors and fights one battle.
+func simulate(loader *npcLoader, config simConfig, level int) (battleResult, error) {
+    rules := game.NewRules()
+    engine := newHeadlessEngine(rules)
+    engine.verbose = config.verbose
+    engine.loader = loader
+
+    var party *game.Party
+    for _, name := range config.party {
+        member, err := loader.load(name)
+        if err != nil {
+            return battleResult{}, err
+        }
+        if party == nil {
+            party = game.NewParty(member)
+            party.InitWithRules(rules)
+        } else {
+            party.AddMember(member)
+        }
+    }
+    for _, member := range party.GetMembers() {
+        for i := 1; i < level; i++ {
+            rules.LevelUp(member)
+        }
+        for _, encoded := range config.gear {
+            equip(member, game.NewItemFromString(encoded))
+        }
+    }
+
+    var enemies []*game.Actor
+    for i, name := range config.enemies {
+        enemy, err := loader.load(name)
+        if err != nil {
+            return battleResult{}, err
+        }
+        enemy.SetInternalName(fmt.Sprintf("%s_%d", name, i))
+        enemy.SetCombatFaction("combatsim_enemies")
+        for _, encoded := range config.enemyItems {
+            enemy.AddItem(game.NewItemFromString(encoded))
+        }
+        enemies = append(enemies, enemy)
+    }
+
+    gridMap := newArena(party.GetMembers(), enemies)
+    b := newBattle(engine, gridMap, party, enemies)
+    result := b.run(config.maxRounds)
+    if config.combatLog != nil {
+        if _, err := engine.combatLog.WriteTo(config.combatLog); err != nil {
+            return battleResult{}, err
+        }
+    }
+    return result, nil
+}
+
+func equip(member *game.Actor, item game.Item) {
+    if weapon, isWeapon := item.(*game.Weapon); isWeapon && weapon.IsRanged() {
+        member.EquipRangedWeapon(weapon)
+        return
+    }
+    member.Equip(item)
+}
+
+// newArena is an empty field. The party starts on the left, the enemies on the right.
+func newArena(party []*game.Actor, enemies []*game.Actor) *gridmap.GridMap[*game.Actor, game.Item, game.Object] {
+    width, height := 20, 12
+    gridMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](width, height, 20)
+    placeInColumn(gridMap, party, 2, -1)
+    placeInColumn(gridMap, enemies, width-3, 1)
+    return gridMap
+}
+
+// placeInColumn lines the actors up around the middle row. Large groups get a second line behind the first.
+func placeInColumn(gridMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], actors []*game.Actor, x int, backwards int) {
+    rowsPerColumn := gridMap.MapHeight - 2
+    middle := gridMap.MapHeight / 2
+    for i, actor := range actors {
+        row := i % rowsPerColumn
+        // 0, -1, +1, -2, +2, ...
+        offset := (row + 1) / 2
+        if row%2 == 1 {
+            offset = -offset
+        }
+        column := x + backwards*(i/rowsPerColumn)
+        gridMap.AddActor(actor, geometry.Point{X: column, Y: middle + offset})
+    }
+}
+
+// npcLoader reads every npc file only once.
+type npcLoader struct {
+    assetPath string
+    files     map[string][]byte
+}
+
+func newNpcLoader(assetPath string) *npcLoader {
+    return &npcLoader{
+        assetPath: assetPath,
+        files:     make(map[string][]byte),
+    }
+}
+
+func (l *npcLoader) load(name string) (*game.Actor, error) {
+    data, isCached := l.files[name]
+    if !isCached {
+        var err error
+        data, err = os.ReadFile(path.Join(l.assetPath, "npc", name+".txt"))
+        if err != nil {
+            return nil, fmt.Errorf("could not load npc '%s': %w", name, err)
+        }
+        l.files[name] = data
+    }
+    noPaging := func(height int, inputText []string) [][]string { return [][]string{inputText} }
+    return game.NewActorFromFile(io.NopCloser(bytes.NewReader(data)), 0, noPaging), nil
+}
+
+func parseLevels(value string) (int, int, error) {
+    from, to, isRange := strings.Cut(value, "-")
+    minLevel, err := strconv.Atoi(strings.TrimSpace(from))
+    if err != nil {
+        return 0, 0, fmt.Errorf("invalid levels '%s'", value)
+    }
+    if !isRange {
+        return minLevel, minLevel, nil
+    }
+    maxLevel, err := strconv.Atoi(strings.TrimSpace(to))
+    if err != nil || maxLevel < minLevel || minLevel < 1 {
+        return 0, 0, fmt.Errorf("invalid levels '%s'", value)
+    }
+    return minLevel, maxLevel, nil
+}
+
+func splitList(value string, separator string) []string {
+    var result []string
+    for _, part := range strings.Split(value, separator) {
+        part = strings.TrimSpace(part)
+        if part != "" {
+            result = append(result, part)
+        }
+    }
+    return result
+}
+
+// expandGroups turns "grey_rat*3" into three grey rats.
+func expandGroups(names []string) []string {
+    var result []string
+    for _, name := range names {
+        count := 1
+        if base, countPart, hasCount := strings.Cut(name, "*"); hasCount {
+            if parsed, err := strconv.Atoi(countPart); err == nil && parsed > 0 {
+                name, count = base, parsed
+            }
+        }
+        for i := 0; i < count; i++ {
+            result = append(result, name)
+        }
+    }
+    return result
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "slices"
+)
+
+func printReport(out io.Writer, level int, results []battleResult) {
+    var won, lost, undecided, fled, surrendered, opportunityAttacks, criticalHits, fumbles, companions int
+    var partyMisses, enemyMisses int
+    var rounds, roundsToKill, roundsToLose, partyHits, enemyHits []int
+    for _, result := range results {
+        if result.partyWon {
+            won++
+        } else if result.enemiesWon {
+            lost++
+        } else {
+            undecided++
+        }
+        fled += result.enemiesFled
+        surrendered += result.enemiesSurrendered
+        opportunityAttacks += result.opportunityAttacks
+        criticalHits += result.criticalHits
+        fumbles += result.fumbles
+        companions += result.companions
+        partyMisses += result.partyMisses
+        enemyMisses += result.enemyMisses
+        rounds = append(rounds, result.rounds)
+        roundsToKill = append(roundsToKill, result.roundsToKillEnemy...)
+        roundsToLose = append(roundsToLose, result.roundsToLoseMember...)
+        partyHits = append(partyHits, result.partyHits...)
+        enemyHits = append(enemyHits, result.enemyHits...)
+    }
+    count := len(results)
+
+    fmt.Fprintf(out, "== Party level %d ==\n", level)
+    fmt.Fprintf(out, "  won %5.1f%%   lost %5.1f%%   undecided %5.1f%%\n", percent(won, count), percent(lost, count), percent(undecided, count))
+    fmt.Fprintf(out, "  rounds             %s\n", describe(rounds))
+    fmt.Fprintf(out, "  round of kill      %s\n", describe(roundsToKill))
+    fmt.Fprintf(out, "  round of loss      %s\n", describe(roundsToLose))
+    fmt.Fprintf(out, "  party damage/hit   %s   hit rate %5.1f%%\n", describe(partyHits), percent(len(partyHits), len(partyHits)+partyMisses))
+    fmt.Fprintf(out, "  enemy damage/hit   %s   hit rate %5.1f%%\n", describe(enemyHits), percent(len(enemyHits), len(enemyHits)+enemyMisses))
+    fmt.Fprintf(out, "  enemies fled %.2f, surrendered %.2f per battle\n", float64(fled)/float64(max(1, count)), float64(surrendered)/float64(max(1, count)))
+    fmt.Fprintf(out, "  opportunity attacks %.2f per battle\n", float64(opportunityAttacks)/float64(max(1, count)))
+    fmt.Fprintf(out, "  critical hits %.2f, fumbles %.2f per battle\n", float64(criticalHits)/float64(max(1, count)), float64(fumbles)/float64(max(1, count)))
+    fmt.Fprintf(out, "  companions %.2f per battle\n\n", float64(companions)/float64(max(1, count)))
+}
+
+// describe gives the average and the distribution of the values.
+func describe(values []int) string {
+    if len(values) == 0 {
+        return "n/a"
+    }
+    sorted := slices.Clone(values)
+    slices.Sort(sorted)
+    sum := 0
+    for _, value := range sorted {
+        sum += value
+    }
+    average := float64(sum) / float64(len(sorted))
+    return fmt.Sprintf("avg %5.2f  min %3d  p10 %3d  p50 %3d  p90 %3d  max %3d", average, sorted[0], percentile(sorted, 10), percentile(sorted, 50), percentile(sorted, 90), sorted[len(sorted)-1])
+}
+
+func percentile(sorted []int, p int) int {
+    index := (len(sorted) - 1) * p / 100
+    return sorted[index]
+}
+
+func percent(part, total int) float64 {
+    if total == 0 {
+        return 0
+    }
+    return 100 * float64(part) / float64(total)
+}
@@ -0,0 +1,214 @@
+// Command dungenpreview runs a dungeon generator without starting the game and shows what it made.
+// The layout is printed as ASCII or rendered with the world tileset, followed by some statistics.
+//
+//  go run ./cmd/dungenpreview -generator caves -seed 23 -level 1 -size 40x30
+//  go run ./cmd/dungenpreview -generator maze -seeds 20 -format png -out maze.png
+//
+// With -level, the seed is combined with the level number like in the game, so the layout is the one the generator builds for !gen_<branch>_<seed>_level_<level>.
+// The game then makes room for prefabs, picks its ladders and digs more tunnels, so ladders and path lengths of the preview are only an estimate.
+package main
+
+import (
+    "Legacy/dungen"
+    "Legacy/geometry"
+    "flag"
+    "fmt"
+    "image"
+    "image/draw"
+    "image/png"
+    "math/rand"
+    "os"
+    "path"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+const tileSize = 16
+
+type previewConfig struct {
+    generator string
+    width     int
+    height    int
+    format    string
+    out       string
+    tileset   image.Image
+    entities  image.Image
+    wallIcon  int
+    floorIcon int
+}
+
+func main() {
+    generatorFlag := flag.String("generator", "accretion", "generator to run: accretion, caves, mines or maze")
+    seedFlag := flag.Int64("seed", 23, "seed of the first layout")
+    seedsFlag := flag.Int("seeds", 1, "number of layouts to generate, with consecutive seeds")
+    levelFlag := flag.Int("level", 0, "dungeon level, combined with the seed like in the game")
+    sizeFlag := flag.String("size", "32x32", "width and height of the layout")
+    formatFlag := flag.String("format", "ascii", "output format: ascii or png")
+    outFlag := flag.String("out", "", "file to write to, png files get the seed appended when there are several layouts")
+    assetsFlag := flag.String("assets", "assets", "path to the asset directory")
+    wallFlag := flag.Int("wall", 80, "world tile used for walls in png output")
+    floorFlag := flag.Int("floor", 36, "world tile used for floors in png output")
+    flag.Parse()
+
+    if !slices.Contains(dungen.GeneratorNames, *generatorFlag) {
+        fmt.Fprintf(os.Stderr, "unknown generator '%s', use one of: %s\n", *generatorFlag, strings.Join(dungen.GeneratorNames, ", "))
+        os.Exit(2)
+    }
+    width, height, err := parseSize(*sizeFlag)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(2)
+    }
+    if minimumSize := dungen.MinimumSize(*generatorFlag); width < minimumSize || height < minimumSize {
+        fmt.Fprintf(os.Stderr, "size '%s' is too small, the %s generator needs at least %dx%d\n", *sizeFlag, *generatorFlag, minimumSize, minimumSize)
+        os.Exit(2)
+    }
+    config := previewConfig{
+        generator: *generatorFlag,
+        width:     width,
+        height:    height,
+        format:    *formatFlag,
+        out:       *outFlag,
+        wallIcon:  *wallFlag,
+        floorIcon: *floorFlag,
+    }
+    switch config.format {
+    case "ascii":
+    case "png":
+        if config.out == "" {
+            config.out = config.generator + ".png"
+        }
+        config.tileset, err = loadPNG(path.Join(*assetsFlag, "world.png"))
+        if err == nil {
+            config.entities, err = loadPNG(path.Join(*assetsFlag, "entities.png"))
+        }
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+    default:
+        fmt.Fprintf(os.Stderr, "unknown format '%s'\n", config.format)
+        os.Exit(2)
+    }
+
+    asciiOut := os.Stdout
+    if config.format == "ascii" && config.out != "" {
+        asciiOut, err = os.Create(config.out)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        defer asciiOut.Close()
+    }
+
+    var allStats []layoutStats
+    for i := 0; i < *seedsFlag; i++ {
+        seed := *seedFlag + int64(i)
+        if *levelFlag > 0 {
+            // the same as levelSeed in generator.go
+            seed = seed*1000 + int64(*levelFlag)
+        }
+        layout := dungen.NewGeneratorByName(config.generator, seed).Generate(config.width, config.height)
+        stats := analyze(layout, seed)
+        allStats = append(allStats, stats)
+
+        switch config.format {
+        case "ascii":
+            fmt.Fprintf(asciiOut, "%s seed %d\n", config.generator, seed)
+            layout.Fprint(asciiOut, stats.ladderMarkers())
+            fmt.Fprintln(asciiOut)
+        case "png":
+            filename := config.out
+            if *seedsFlag > 1 {
+                filename = strings.TrimSuffix(filename, ".png") + "_" + strconv.FormatInt(seed, 10) + ".png"
+            }
+            if err := writePNG(filename, renderLayout(layout, stats, config)); err != nil {
+                fmt.Fprintln(os.Stderr, err)
+                os.Exit(1)
+            }
+        }
+        stats.print(os.Stdout)
+    }
+    if len(allStats) > 1 {
+        printSummary(os.Stdout, allStats)
+    }
+}
+
+func parseSize(size string) (int, int, error) {
+    parts := strings.Split(size, "x")
+    if len(parts) != 2 {
+        return 0, 0, fmt.Errorf("invalid size '%s', use eg. 32x32", size)
+    }
+    width, widthErr := strconv.Atoi(parts[0])
+    height, heightErr := strconv.Atoi(parts[1])
+    if widthErr != nil || heightErr != nil || width < 1 || height < 1 {
+        return 0, 0, fmt.Errorf("invalid size '%s', use eg. 32x32", size)
+    }
+    return width, height, nil
+}
+
+// renderLayout draws the layout with the tiles of the game. Doors and ladders use the same icons as generated levels.
+// Doors are objects, so they come from the entity tileset.
+func renderLayout(layout *dungen.DungeonMap, stats layoutStats, config previewConfig) image.Image {
+    img := image.NewRGBA(image.Rect(0, 0, layout.Width()*tileSize, layout.Height()*tileSize))
+    for y := 0; y < layout.Height(); y++ {
+        for x := 0; x < layout.Width(); x++ {
+            icon := config.floorIcon
+            if layout.GetTile(x, y) == dungen.Wall {
+                icon = config.wallIcon
+            }
+            pos := geometry.Point{X: x, Y: y}
+            if stats.hasLadders && pos == stats.ladderUp {
+                icon = ladderUpIcon
+            } else if stats.hasLadders && pos == stats.ladderDown {
+                icon = ladderDownIcon
+            }
+            drawTile(img, config.tileset, icon, x, y)
+            if layout.GetTile(x, y) == dungen.Door {
+                drawTile(img, config.entities, doorIcon, x, y)
+            }
+        }
+    }
+    return img
+}
+
+func drawTile(img draw.Image, tileset image.Image, icon int, x, y int) {
+    columns := tileset.Bounds().Dx() / tileSize
+    source := image.Pt((icon%columns)*tileSize, (icon/columns)*tileSize)
+    target := image.Rect(x*tileSize, y*tileSize, (x+1)*tileSize, (y+1)*tileSize)
+    draw.Draw(img, target, tileset, source, draw.Over)
+}
+
+func loadPNG(filename string) (image.Image, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return png.Decode(file)
+}
+
+func writePNG(filename string, img image.Image) error {
+    file, err := os.Create(filename)
+    if err != nil {
+        return err
+    }
+    if err := png.Encode(file, img); err != nil {
+        _ = file.Close()
+        return err
+    }
+    return file.Close()
+}
+
+// pickLadderRooms chooses the rooms for the ladders the way generator.go does.
+// The game draws from its level random before, so the rooms it picks usually differ.
+func pickLadderRooms(rooms []*dungen.DungeonRoom, seed int64) (*dungen.DungeonRoom, *dungen.DungeonRoom) {
+    random := rand.New(rand.NewSource(seed))
+    entryRoom := rooms[random.Intn(len(rooms))]
+    exitRoom := entryRoom
+    for exitRoom == entryRoom && len(rooms) > 1 {
+        exitRoom = rooms[random.Intn(len(rooms))]
+    }
+    return entryRoom, exitRoom
+}
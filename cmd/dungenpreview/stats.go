@@ -0,0 +1,121 @@
+package main
+
+import (
+    "Legacy/dungen"
+    "Legacy/geometry"
+    "fmt"
+    "io"
+)
+
+// icons as used for generated levels, the door is from the entity tileset
+const (
+    doorIcon       = 183
+    ladderUpIcon   = 20
+    ladderDownIcon = 21
+)
+
+type layoutStats struct {
+    seed        int64
+    rooms       int
+    floorTiles  int
+    doors       int
+    deadEnds    int
+    unreachable int
+    hasLadders  bool
+    ladderUp    geometry.Point
+    ladderDown  geometry.Point
+    pathLength  int
+}
+
+func analyze(layout *dungen.DungeonMap, seed int64) layoutStats {
+    stats := layoutStats{
+        seed:  seed,
+        rooms: len(layout.AllRooms()),
+    }
+    for y := 0; y < layout.Height(); y++ {
+        for x := 0; x < layout.Width(); x++ {
+            pos := geometry.Point{X: x, Y: y}
+            switch layout.GetTile(x, y) {
+            case dungen.Door:
+                stats.doors++
+            case dungen.Room, dungen.Corridor:
+                stats.floorTiles++
+            }
+            if _, isDeadEnd := layout.IsDeadEnd(pos); isDeadEnd {
+                stats.deadEnds++
+            }
+        }
+    }
+    if stats.rooms < 2 {
+        return stats
+    }
+    entryRoom, exitRoom := pickLadderRooms(layout.AllRooms(), seed)
+    stats.hasLadders = true
+    stats.ladderUp = entryRoom.Center()
+    stats.ladderDown = exitRoom.Center()
+    // the game digs tunnels to these, they are still worth knowing about
+    reached := reachableFrom(layout, stats.ladderUp)
+    stats.unreachable = stats.floorTiles + stats.doors - len(reached)
+    if path := layout.GetJPSPath(stats.ladderUp, stats.ladderDown); len(path) > 0 && path[len(path)-1] == stats.ladderDown {
+        stats.pathLength = len(path)
+    } else {
+        stats.pathLength = -1
+    }
+    return stats
+}
+
+func reachableFrom(layout *dungen.DungeonMap, start geometry.Point) map[geometry.Point]bool {
+    reached := map[geometry.Point]bool{start: true}
+    queue := []geometry.Point{start}
+    for len(queue) > 0 {
+        current := queue[0]
+        queue = queue[1:]
+        for _, direction := range []geometry.CompassDirection{geometry.North, geometry.South, geometry.East, geometry.West} {
+            next := current.Add(direction.ToPoint())
+            if !reached[next] && layout.IsWalkable(next) {
+                reached[next] = true
+                queue = append(queue, next)
+            }
+        }
+    }
+    return reached
+}
+
+func (s layoutStats) ladderMarkers() map[geometry.Point]rune {
+    if !s.hasLadders {
+        return nil
+    }
+    return map[geometry.Point]rune{s.ladderUp: '<', s.ladderDown: '>'}
+}
+
+func (s layoutStats) print(w io.Writer) {
+    fmt.Fprintf(w, "seed %d: %d rooms, %d floor tiles, %d doors, %d dead ends", s.seed, s.rooms, s.floorTiles, s.doors, s.deadEnds)
+    switch {
+    case !s.hasLadders:
+        fmt.Fprintf(w, ", too few rooms for the ladders\n")
+    case s.pathLength < 0:
+        fmt.Fprintf(w, ", ladders %v to %v are not connected, %d tiles unreachable\n", s.ladderUp, s.ladderDown, s.unreachable)
+    default:
+        fmt.Fprintf(w, ", path between ladders %d, %d tiles unreachable\n", s.pathLength, s.unreachable)
+    }
+}
+
+func printSummary(w io.Writer, allStats []layoutStats) {
+    var rooms, deadEnds, pathLength, connected, unusable int
+    for _, stats := range allStats {
+        rooms += stats.rooms
+        deadEnds += stats.deadEnds
+        if !stats.hasLadders {
+            unusable++
+        } else if stats.pathLength > 0 {
+            pathLength += stats.pathLength
+            connected++
+        }
+    }
+    count := float64(len(allStats))
+    fmt.Fprintf(w, "\n%d layouts: %.1f rooms, %.1f dead ends on average, %d without enough rooms", len(allStats), float64(rooms)/count, float64(deadEnds)/count, unusable)
+    if connected > 0 {
+        fmt.Fprintf(w, ", path between ladders %.1f on average (%d connected)", float64(pathLength)/float64(connected), connected)
+    }
+    fmt.Fprintln(w)
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-    "Legacy/ega"
     "Legacy/game"
     "Legacy/geometry"
     "Legacy/gocoro"
@@ -11,8 +10,6 @@ import (
     "fmt"
     "github.com/hajimehoshi/ebiten/v2"
     "image/color"
-    "math"
-    "slices"
     "time"
 )
 
@@ -30,12 +27,24 @@ type CombatState struct {
     isPlayerTurn         bool
     waitForTarget        func(targetPos geometry.Point)
     validTargets         map[geometry.Point]bool
+    previewTarget        func(target geometry.Point) targetPreview
+    currentPreview       targetPreview
+    targetCursor         geometry.Point
+    hasTargetCursor      bool
     isInCombat           bool
     didAlertNearbyActors bool
     iconGenericMissile   int32
     partyAutoAttacks     bool
     animator             *renderer.Animator
     stuckCounter         map[*game.Actor]int
+    turnQueue            []*game.Actor
+    activeActor          *game.Actor
+    hasDelayedTurn       map[*game.Actor]bool
+    tactics              *game.TacticalAI
+    isFleeing            map[*game.Actor]bool
+    companions           map[*game.Actor]bool
+    combatLog            *game.CombatLog
+    combat               *game.Combat
 }
 
 func (c *CombatState) OnMouseWheel(x int, y int, dy float64) bool {
@@ -51,6 +60,10 @@ func (c *CombatState) OnCommand(command ui.CommandType) bool {
     }
     switch command {
     case ui.PlayerCommandConfirm:
+        if c.IsTargeting() {
+            c.confirmTarget()
+            return true
+        }
         c.openCombatMenu(c.engine.GetAvatar())
     case ui.PlayerCommandUp:
         c.onDirectionalInput(geometry.Point{X: 0, Y: -1})
@@ -61,10 +74,8 @@ func (c *CombatState) OnCommand(command ui.CommandType) bool {
     case ui.PlayerCommandRight:
         c.onDirectionalInput(geometry.Point{X: 1, Y: 0})
     case ui.PlayerCommandCancel:
-        if c.waitForTarget != nil {
-            c.waitForTarget = nil
-            clear(c.validTargets)
-            c.engine.ClearOverlay()
+        if c.IsTargeting() {
+            c.endTargeting()
             c.checkForEndOfCombat()
             return true
         }
@@ -73,11 +84,15 @@ func (c *CombatState) OnCommand(command ui.CommandType) bool {
 }
 
 func NewCombatState(gridEngine *GridEngine) *CombatState {
-    return &CombatState{
+    combatState := &CombatState{
         opponents:            make(map[*game.Actor]bool),
         movesTakenThisTurn:   make(map[*game.Actor]int),
         hasUsedPrimaryAction: make(map[*game.Actor]bool),
         stuckCounter:         make(map[*game.Actor]int),
+        hasDelayedTurn:       make(map[*game.Actor]bool),
+        isFleeing:            make(map[*game.Actor]bool),
+        companions:           make(map[*game.Actor]bool),
+        combatLog:            game.NewCombatLog(),
         engine:               gridEngine,
         iconGenericMissile:   26,
         animator: renderer.NewAnimator(
@@ -90,50 +105,34 @@ func NewCombatState(gridEngine *GridEngine) *CombatState {
                 }
             }),
     }
-}
-
-type AttackActionType int
-
-const (
-    AttackActionTypeMelee AttackActionType = iota
-    AttackActionTypeRanged
-    AttackActionTypeSpell
-    AttackActionTypeActiveSkill
-    AttackActionTypeMove
-)
-
-type AttackAction struct {
-    attacker *game.Actor
-    target   *game.Actor
-    action   AttackActionType
+    combatState.tactics = game.NewTacticalAI(gridEngine, combatState)
+    combatState.combat = game.NewCombat(gridEngine, combatState)
+    return combatState
 }
 
 func (c *CombatState) Update() {
 
     c.animator.Update()
-    if c.animator.IsRunning() {
+    if c.animator.IsRunning() || c.engine.IsInConversation() {
         return
     }
 
+    if c.activeActor == nil || !c.IsParticipating(c.activeActor) || !c.canAct(c.activeActor) {
+        c.nextTurn()
+        if c.activeActor == nil {
+            return
+        }
+    }
+
     if c.isPlayerTurn {
-        if c.canPartyAct() {
-            if !c.canAct(c.engine.GetAvatar()) {
-                c.switchToNextPartyMember()
-            }
-            if c.partyAutoAttacks {
-                c.automaticBattleActionFor(c.engine.GetAvatar())
-            }
-        } else {
-            c.endPlayerTurn()
+        if c.engine.GetAvatar() != c.activeActor {
+            c.engine.SwitchAvatarTo(c.activeActor)
         }
-    } else {
-        // do ai
-        if c.canEnemyAct() {
-            // wait for input
-            c.takeEnemyTurn()
-        } else {
-            c.endEnemyTurn()
+        if c.partyAutoAttacks {
+            c.automaticBattleActionFor(c.activeActor)
         }
+    } else {
+        c.automaticBattleActionFor(c.activeActor)
     }
 }
 
@@ -163,29 +162,11 @@ func (c *CombatState) meleeHitOnLocation(attacker *game.Actor, pos geometry.Poin
     c.meleeHitOnActor(attacker, victim)
 }
 func (c *CombatState) meleeHitOnActor(attacker *game.Actor, npc *game.Actor) {
-    doesHit := c.engine.rules.DoesMeleeAttackHit(attacker, npc)
-    hitPos := npc.Pos()
-    icon := int32(194)
-    useAtlas := renderer.AtlasEntities
-    if doesHit {
-        icon = int32(104)
-        useAtlas = renderer.AtlasWorld
-    }
-    animation := &renderer.TileAnimation{
-        UseTiles:  useAtlas,
-        Positions: []geometry.Point{hitPos},
-        Frames:    []int32{icon},
-        TintColor: color.White,
-        WhenDone: func() {
-            c.hasUsedPrimaryAction[attacker] = true
-            if doesHit {
-                c.deliverMeleeDamage(attacker, npc)
-            }
-        },
-    }
-    animation.EndAfterTime(0.33)
-    c.animator.AddHitAnimation(animation)
+    c.combat.MeleeAttack(attacker, npc, func() {
+        c.hasUsedPrimaryAction[attacker] = true
+    })
 }
+
 func (c *CombatState) animateProjectile(attacker *game.Actor, target geometry.Point, icon int32, tint color.Color, onImpact func(dest geometry.Point, actor *game.Actor) func()) {
 
     currentMap := c.engine.currentMap
@@ -220,26 +201,10 @@ func (c *CombatState) animateProjectile(attacker *game.Actor, target geometry.Po
 }
 
 func (c *CombatState) getLineOfSight(source geometry.Point, destination geometry.Point, isActorBlocking func(actor *game.Actor) bool) []geometry.Point {
-    currentMap := c.engine.currentMap
-    los := geometry.BresenhamLoS(source, destination, func(x, y int) bool {
-        p := geometry.Point{X: x, Y: y}
-        if !currentMap.Contains(p) {
-            return false
-        }
-        if currentMap.IsActorAt(p) {
-            actorAt := currentMap.ActorAt(p)
-            if isActorBlocking(actorAt) {
-                return false
-            }
-        }
-        isWalkable := currentMap.IsWalkable(p)
-        return isWalkable || p == source
-    })
-    losWithoutSource := los[1:]
-    return losWithoutSource
+    return game.GetLineOfFire(c.engine.currentMap, source, destination, isActorBlocking)
 }
 
-func (c *CombatState) getMovesLeft(actor *game.Actor) int {
+func (c *CombatState) GetMovesLeft(actor *game.Actor) int {
     return actor.GetMovementAllowance() - c.movesTakenThisTurn[actor]
 }
 
@@ -253,13 +218,9 @@ func (c *CombatState) canAct(actor *game.Actor) bool {
     }
     return c.movesTakenThisTurn[actor] < actorMovementAllowance
 }
-func (c *CombatState) canPartyAct() bool {
-    for _, partyMember := range c.engine.GetPartyMembers() {
-        if c.canAct(partyMember) {
-            return true
-        }
-    }
-    return false
+
+func (c *CombatState) IsParticipating(actor *game.Actor) bool {
+    return c.IsOnPartySide(actor) || c.opponents[actor]
 }
 
 func (c *CombatState) OnMouseClicked(xGrid int, yGrid int) bool {
@@ -270,19 +231,8 @@ func (c *CombatState) OnMouseClicked(xGrid int, yGrid int) bool {
 func (c *CombatState) onDirectionalInput(direction geometry.Point) bool {
     avatar := c.engine.GetAvatar()
     curPos := avatar.Pos()
-    if c.waitForTarget != nil {
-        counter := 0
-        potentialTargetPos := curPos.Add(direction)
-        _, isValidPos := c.validTargets[potentialTargetPos]
-        for counter < 20 && !isValidPos {
-            counter++
-            potentialTargetPos = potentialTargetPos.Add(direction)
-            _, isValidPos = c.validTargets[potentialTargetPos]
-        }
-        if isValidPos {
-            c.waitForTarget(potentialTargetPos)
-            return true
-        }
+    if c.IsTargeting() {
+        c.moveTargetCursor(direction)
         return true
     }
 
@@ -294,6 +244,9 @@ func (c *CombatState) onDirectionalInput(direction geometry.Point) bool {
         c.engine.PlayerMovement(direction)
         if curPos != avatar.Pos() {
             c.movesTakenThisTurn[avatar]++
+            if !c.combat.ProvokeOpportunityAttacks(avatar, curPos) {
+                c.movesTakenThisTurn[avatar] = avatar.GetMovementAllowance()
+            }
             return true
         }
     }
@@ -323,6 +276,13 @@ func (c *CombatState) openCombatMenu(partyMember *game.Actor) {
                 c.engine.openActiveSkillsMenu(partyMember, partyMember.GetActiveSkills())
             },
         },
+        {
+            Text: "Items",
+            Action: func() {
+                c.engine.CloseAllModals()
+                c.engine.openActiveSkillsMenu(partyMember, c.engine.GetParty().GetConsumableActions())
+            },
+        },
         {
             Text: "Auto-Attack",
             Action: func() {
@@ -330,6 +290,13 @@ func (c *CombatState) openCombatMenu(partyMember *game.Actor) {
                 c.engine.CloseAllModals()
             },
         },
+        {
+            Text: "Wait",
+            Action: func() {
+                c.engine.CloseAllModals()
+                c.delayTurn(partyMember)
+            },
+        },
         {
             Text: "End turn",
             Action: func() {
@@ -340,83 +307,146 @@ func (c *CombatState) openCombatMenu(partyMember *game.Actor) {
     }
     c.engine.OpenMenu(combatOptions)
 }
-func (c *CombatState) endPlayerTurn() {
-    c.isPlayerTurn = false
-    clear(c.movesTakenThisTurn)
-    clear(c.hasUsedPrimaryAction)
+// nextTurn hands the turn to the next actor in the initiative queue.
+// A new round is started, when everyone had their turn.
+func (c *CombatState) nextTurn() {
+    c.activeActor = nil
     c.checkForEndOfCombat()
+    if !c.isInCombat {
+        return
+    }
+    for {
+        if len(c.turnQueue) == 0 {
+            c.startNewRound()
+            if len(c.turnQueue) == 0 {
+                return
+            }
+        }
+        nextActor := c.turnQueue[0]
+        c.turnQueue = c.turnQueue[1:]
+        if nextActor.IsStunned() {
+            // the stun wears off, but the turn is lost
+            nextActor.OnNewCombatTurn(c.engine)
+            c.engine.Print(fmt.Sprintf("'%s' is stunned", nextActor.Name()))
+            if !nextActor.IsAlive() {
+                c.OnActorDied(nextActor)
+            }
+            continue
+        }
+        if c.IsParticipating(nextActor) && c.canAct(nextActor) {
+            c.startTurnOf(nextActor)
+            return
+        }
+    }
 }
 
-func (c *CombatState) endEnemyTurn() {
-    c.isPlayerTurn = true
-    clear(c.movesTakenThisTurn)
-    clear(c.hasUsedPrimaryAction)
-    c.checkForEndOfCombat()
-    if c.isInCombat {
-        c.switchToNextPartyMember()
+func (c *CombatState) startNewRound() {
+    if c.combat.GetRound() > 0 {
+        clear(c.movesTakenThisTurn)
+        clear(c.hasUsedPrimaryAction)
     }
-}
-func (c *CombatState) ensureCombatInit(isPlayerTurn bool) {
-    if c.isInCombat {
-        return
+    clear(c.hasDelayedTurn)
+    c.combat.StartNewRound()
+    if c.combat.GetRound() == 1 {
+        c.deployParty()
     }
-    clear(c.movesTakenThisTurn)
-    clear(c.hasUsedPrimaryAction)
-    clear(c.opponents)
-    c.partyAutoAttacks = false
-    c.isInCombat = true
-    c.didAlertNearbyActors = false
-    c.isPlayerTurn = isPlayerTurn
-}
+    c.engine.tickEnvironment()
 
-func (c *CombatState) canEnemyAct() bool {
+    var participants []*game.Actor
+    for _, partyMember := range c.engine.GetPartyMembers() {
+        if partyMember.CanAct() {
+            participants = append(participants, partyMember)
+        }
+    }
+    for companion, _ := range c.companions {
+        if companion.CanAct() {
+            participants = append(participants, companion)
+        }
+    }
     for opponent, _ := range c.opponents {
-        if c.canAct(opponent) {
-            return true
+        if opponent.CanAct() {
+            participants = append(participants, opponent)
         }
     }
-    return false
+    c.turnQueue = c.combat.SortByInitiative(participants)
 }
 
-func (c *CombatState) actorDied(actor *game.Actor) {
-    if !c.engine.IsPlayerControlled(actor) {
-        c.removeOpponent(actor)
+func (c *CombatState) startTurnOf(actor *game.Actor) {
+    c.activeActor = actor
+    c.isPlayerTurn = c.engine.IsPlayerControlled(actor)
+    if !c.hasDelayedTurn[actor] {
+        if c.companions[actor] && actor.TickCompanionRound() {
+            c.dismissCompanion(actor, true)
+            c.nextTurn()
+            return
+        }
+        actor.OnNewCombatTurn(c.engine)
+        if !actor.IsAlive() {
+            // killed by poison or bleeding
+            c.OnActorDied(actor)
+            c.nextTurn()
+            return
+        }
+    }
+    if c.isPlayerTurn {
+        c.engine.SwitchAvatarTo(actor)
     }
-    c.engine.actorDied(actor)
 }
 
-func (c *CombatState) deliverMeleeDamage(attacker, victim *game.Actor) {
-    c.engine.DeliverMeleeDamage(attacker, victim)
-    if !victim.IsAlive() {
-        c.actorDied(victim)
+// delayTurn moves the actor to the end of the current round, once per round.
+func (c *CombatState) delayTurn(actor *game.Actor) {
+    if actor != c.activeActor {
+        return
     }
-}
-func (c *CombatState) deliverRangedDamage(attacker, victim *game.Actor) {
-    c.engine.DeliverRangedDamage(attacker, victim)
-    if !victim.IsAlive() {
-        c.actorDied(victim)
+    if c.hasDelayedTurn[actor] {
+        c.engine.Print(fmt.Sprintf("'%s' cannot wait any longer", actor.Name()))
+        return
     }
+    c.hasDelayedTurn[actor] = true
+    c.turnQueue = append(c.turnQueue, actor)
+    c.activeActor = nil
+    c.engine.Print(fmt.Sprintf("'%s' waits", actor.Name()))
 }
 
-func (c *CombatState) takeEnemyTurn() {
-    // does nothing for now
-    for opponent, _ := range c.opponents {
-        if c.canAct(opponent) {
-            //c.engine.Print(fmt.Sprintf("'%s' turn", opponent.Name()))
-            c.automaticBattleActionFor(opponent)
-            return
+// GetTurnOrder returns the active actor, followed by everyone who will still act this round.
+func (c *CombatState) GetTurnOrder() []*game.Actor {
+    var turnOrder []*game.Actor
+    if c.activeActor != nil {
+        turnOrder = append(turnOrder, c.activeActor)
+    }
+    for _, actor := range c.turnQueue {
+        if actor != c.activeActor && c.IsParticipating(actor) && actor.CanAct() {
+            turnOrder = append(turnOrder, actor)
         }
     }
+    return turnOrder
 }
 
-func (c *CombatState) switchToNextPartyMember() {
-    for _, partyMember := range c.engine.GetPartyMembers() {
-        if c.canAct(partyMember) {
-            c.engine.SwitchAvatarTo(partyMember)
-            //c.engine.Print(fmt.Sprintf("It's '%s' turn", partyMember.Name()))
-            return
-        }
+func (c *CombatState) ensureCombatInit(isPlayerTurn bool) {
+    if c.isInCombat {
+        return
+    }
+    clear(c.movesTakenThisTurn)
+    clear(c.hasUsedPrimaryAction)
+    clear(c.hasDelayedTurn)
+    clear(c.opponents)
+    c.tactics.Reset()
+    clear(c.isFleeing)
+    c.turnQueue = nil
+    c.activeActor = nil
+    c.combat.Start(isPlayerTurn)
+    c.partyAutoAttacks = false
+    c.isInCombat = true
+    c.didAlertNearbyActors = false
+    c.isPlayerTurn = isPlayerTurn
+}
+
+func (c *CombatState) OnActorDied(actor *game.Actor) {
+    c.combatLog.LogEffect(actor, "dies")
+    if !c.IsOnPartySide(actor) {
+        c.removeOpponent(actor)
     }
+    c.engine.actorDied(actor)
 }
 
 func (c *CombatState) isEnemyAt(dest geometry.Point) (bool, *game.Actor) {
@@ -428,100 +458,35 @@ func (c *CombatState) isEnemyAt(dest geometry.Point) (bool, *game.Actor) {
     return false, nil
 }
 
-type BattleAction struct {
-    MovementDestination  geometry.Point
-    ActionType           AttackActionType
-    ActiveAction         game.Action // skill / spell
-    ActionTargetLocation *geometry.Point
-}
-
-func (a BattleAction) IsValid(actorPos geometry.Point) bool {
-    return a.MovementDestination != actorPos || a.ActiveAction != nil || a.ActionTargetLocation != nil
-}
-
 func (c *CombatState) automaticBattleActionFor(ourActor *game.Actor) {
-    autoAction, reachablePositions := c.calculateBestAction(ourActor)
-    c.executeBattleAction(ourActor, autoAction, reachablePositions)
-}
-func (c *CombatState) calculateBestAction(ourActor *game.Actor) (BattleAction, map[geometry.Point]int) {
-    // let's start simple..
-    // find the nearest enemy, find a free cell position next to him
-    // move towards that position
-    // if we can reach it this turn: attack
-
-    gridmap := c.engine.GetGridMap()
-    reachablePositions := gridmap.GetDijkstraMap(ourActor.Pos(), c.getMovesLeft(ourActor), func(p geometry.Point) bool {
-        return gridmap.IsCurrentlyPassable(p)
-    })
-    reachablePositions[ourActor.Pos()] = 0
-
-    maxUtility := math.MinInt
-    bestAction := BattleAction{
-        MovementDestination: ourActor.Pos(),
-    }
-    for pos, _ := range reachablePositions {
-        utility, actionAt := c.getUtilityForPosition(ourActor, pos)
-        if utility > maxUtility {
-            maxUtility = utility
-            bestAction = actionAt
-        }
-    }
-
-    if maxUtility == 0 {
-        attackType := AttackActionTypeMelee
-        moveTo, attackTarget := c.closeIntoMeleeRange(ourActor, reachablePositions)
-        bestAction = BattleAction{
-            MovementDestination:  moveTo,
-            ActionType:           attackType,
-            ActionTargetLocation: attackTarget,
+    autoAction, reachablePositions := c.tactics.ChooseAction(ourActor)
+    switch autoAction.ActionType {
+    case game.AttackActionTypeSurrender:
+        c.offerSurrender(ourActor)
+    case game.AttackActionTypeEscape:
+        c.escape(ourActor)
+    case game.AttackActionTypeFlee:
+        if !c.isFleeing[ourActor] {
+            c.isFleeing[ourActor] = true
+            c.engine.Print(fmt.Sprintf("'%s' flees", ourActor.Name()))
         }
+        c.executeBattleAction(ourActor, autoAction, reachablePositions)
+    default:
+        c.executeBattleAction(ourActor, autoAction, reachablePositions)
     }
-
-    return bestAction, reachablePositions
 }
 
-func (c *CombatState) closeIntoMeleeRange(ourActor *game.Actor, reachablePositions map[geometry.Point]int) (geometry.Point, *geometry.Point) {
-    // find the nearest enemy
-    var nearestEnemy *game.Actor
-    nearestPos := ourActor.Pos()
-    var attackPos *geometry.Point
-    currentMap := c.engine.currentMap
-    for _, enemy := range c.getActiveOpponents(ourActor) {
-        if enemy.IsAlive() {
-            if enemy.IsRightNextTo(ourActor) {
-                enemyPos := enemy.Pos()
-                return ourActor.Pos(), &enemyPos
-            }
-            freeNeighbors := currentMap.NeighborsCardinal(enemy.Pos(), func(p geometry.Point) bool {
-                return currentMap.Contains(p) && currentMap.IsWalkableFor(p, ourActor)
-            })
-            if len(freeNeighbors) == 0 {
-                continue
-            }
-            for _, freeNeighbor := range freeNeighbors {
-                if _, ok := reachablePositions[freeNeighbor]; ok {
-                    distance := geometry.DistanceManhattan(ourActor.Pos(), freeNeighbor)
-                    if nearestEnemy == nil || distance < geometry.DistanceManhattan(ourActor.Pos(), nearestPos) {
-                        nearestEnemy = enemy
-                        nearestPos = freeNeighbor
-                    }
-                }
-            }
-        }
-    }
-    if nearestEnemy != nil {
-        enemyPos := nearestEnemy.Pos()
-        attackPos = &enemyPos
-    }
-    return nearestPos, attackPos
-}
 
-func (c *CombatState) executeBattleAction(actor *game.Actor, move BattleAction, reachablePositions map[geometry.Point]int) {
+func (c *CombatState) executeBattleAction(actor *game.Actor, move game.BattleAction, reachablePositions map[geometry.Point]int) {
     if !c.canAct(actor) {
         return
     }
     c.hasUsedPrimaryAction[actor] = true
 
+    if move.ActionTargetLocation != nil && c.engine.currentMap.IsActorAt(*move.ActionTargetLocation) {
+        c.tactics.SetFocusTarget(actor, c.engine.currentMap.ActorAt(*move.ActionTargetLocation))
+    }
+
     if !move.IsValid(actor.Pos()) {
         c.stuckCounter[actor]++
         if c.stuckCounter[actor] > 3 {
@@ -533,18 +498,23 @@ func (c *CombatState) executeBattleAction(actor *game.Actor, move BattleAction,
         c.stuckCounter[actor] = 0
     }
 
-    combatPathTo := c.getCombatPathTo(actor, reachablePositions, move.MovementDestination)
+    combatPathTo := game.GetCombatPath(c.engine.currentMap, actor, reachablePositions, move.MovementDestination)
 
     c.animator.RunAnimationScript(func(exe *gocoro.Execution) {
         for _, dest := range combatPathTo {
+            from := actor.Pos()
             c.engine.moveActorInCombat(actor, dest)
             c.movesTakenThisTurn[actor]++
             if !actor.IsAlive() {
-                c.actorDied(actor)
+                c.OnActorDied(actor)
+                return
+            }
+            if !c.combat.ProvokeOpportunityAttacks(actor, from) {
+                // killed or pinned down, the turn is over
                 return
             }
             if actor.IsSleeping() {
-                if !c.engine.IsPlayerControlled(actor) {
+                if !c.IsOnPartySide(actor) {
                     c.removeOpponent(actor)
                 }
                 return
@@ -558,11 +528,13 @@ func (c *CombatState) executeBattleAction(actor *game.Actor, move BattleAction,
             return
         }
 
-        if move.ActionType == AttackActionTypeMelee {
+        if move.ActionType == game.AttackActionTypeMelee {
             c.meleeHitOnLocation(actor, *move.ActionTargetLocation)
-        } else if move.ActionType == AttackActionTypeActiveSkill || move.ActionType == AttackActionTypeSpell {
+        } else if move.ActiveAction != nil && !move.ActiveAction.IsTargeted() {
+            c.UseUntargetedAction(actor, move.ActiveAction)
+        } else if move.ActionType == game.AttackActionTypeActiveSkill || move.ActionType == game.AttackActionTypeSpell {
             c.UseTargetedAction(actor, move.ActiveAction, *move.ActionTargetLocation)
-        } else if move.ActionType == AttackActionTypeRanged {
+        } else if move.ActionType == game.AttackActionTypeRanged {
             c.RangedAttack(actor, *move.ActionTargetLocation)
         }
     })
@@ -579,7 +551,7 @@ func (c *CombatState) findAlliesOfOpponent(attackedNPC *game.Actor) {
     // TODO: better filter for guards, allies, etc.
     currentMap := c.engine.currentMap
     nearbyNPCs := currentMap.FindAllNearbyActors(attackedNPC.Pos(), radius, func(actor *game.Actor) bool {
-        return !c.engine.IsPlayerControlled(actor) &&
+        return !c.IsOnPartySide(actor) &&
             actor.IsAlive() &&
             !actor.IsHidden() &&
             actor != attackedNPC &&
@@ -588,7 +560,7 @@ func (c *CombatState) findAlliesOfOpponent(attackedNPC *game.Actor) {
     avatar := c.engine.GetAvatar()
     for _, nearbyNPC := range nearbyNPCs {
         randomPosNearby := currentMap.GetRandomFreeNeighbor(avatar.Pos())
-        path := currentMap.GetJPSPath(nearbyNPC.Pos(), randomPosNearby, currentMap.IsCurrentlyPassable)
+        path := currentMap.GetJPSPath(nearbyNPC.Pos(), randomPosNearby, currentMap.IsCurrentlyPassableAndSafe)
         if len(path) > (nearbyNPC.GetMovementAllowance() * 4) {
             continue
         }
@@ -598,7 +570,7 @@ func (c *CombatState) findAlliesOfOpponent(attackedNPC *game.Actor) {
 }
 
 func (c *CombatState) removeOpponent(actor *game.Actor) {
-    if c.engine.IsPlayerControlled(actor) {
+    if c.IsOnPartySide(actor) {
         return
     }
     delete(c.opponents, actor)
@@ -614,9 +586,14 @@ func (c *CombatState) checkForEndOfCombat() {
 
 func (c *CombatState) endCombat() {
     c.isInCombat = false
+    c.combat.End()
+    c.dismissAllCompanions()
     c.engine.ForceJoinParty()
     clear(c.movesTakenThisTurn)
     clear(c.hasUsedPrimaryAction)
+    clear(c.hasDelayedTurn)
+    c.turnQueue = nil
+    c.activeActor = nil
 }
 
 func (c *CombatState) OnScreenMouseClicked(screenX, screenY int) bool {
@@ -627,18 +604,26 @@ func (c *CombatState) OnScreenMouseClicked(screenX, screenY int) bool {
     }
     return false
 }
-func (c *CombatState) OnMouseMoved(screenX, screenY int) (bool, ui.Tooltip) {
+func (c *CombatState) OnMouseMoved(xGrid, yGrid int) (bool, ui.Tooltip) {
+    if c.IsTargeting() {
+        x, y := ebiten.CursorPosition()
+        return c.onTargetHovered(x, y), ui.NoTooltip{}
+    }
     return false, ui.NoTooltip{}
 }
 
 func (c *CombatState) OnCombatAction(attacker *game.Actor, target *game.Actor) {
-    isAttackerPlayerControlled := c.engine.IsPlayerControlled(attacker)
+    isAttackerPlayerControlled := c.IsOnPartySide(attacker)
     c.ensureCombatInit(isAttackerPlayerControlled)
 
     npc := target
     if !isAttackerPlayerControlled {
         npc = attacker
     }
+    if c.IsOnPartySide(npc) {
+        // friendly fire
+        return
+    }
     c.findAlliesOfOpponent(npc)
 }
 func (c *CombatState) MeleeAttack(attacker *game.Actor, target *game.Actor) {
@@ -652,6 +637,7 @@ func (c *CombatState) RangedAttack(attacker *game.Actor, targetPos geometry.Poin
         return
     }
     c.hasUsedPrimaryAction[attacker] = true
+    attacker.OnAttackPerformed(c.engine)
     c.animateProjectile(attacker, targetPos, c.iconGenericMissile, color.White, func(pos geometry.Point, actorHit *game.Actor) func() {
         return func() {
             if actorHit != nil {
@@ -666,8 +652,12 @@ func (c *CombatState) RangedAttack(attacker *game.Actor, targetPos geometry.Poin
 func (c *CombatState) UseTargetedAction(attacker *game.Actor, action game.Action, targetPos geometry.Point) {
     c.hasUsedPrimaryAction[attacker] = true
     spellIcon := int32(28)
+    if thrownIcon, isThrown := action.GetProjectileIcon(); isThrown {
+        spellIcon = thrownIcon
+    }
     spellColor := action.GetColor()
     c.engine.Print(fmt.Sprintf("%s uses %s", attacker.Name(), action.Name()))
+    attacker.OnAttackPerformed(c.engine)
     c.animateProjectile(attacker, targetPos, spellIcon, spellColor, func(pos geometry.Point, actorHit *game.Actor) func() {
         return func() {
             c.onActionImpact(attacker, action, pos, actorHit)
@@ -678,6 +668,12 @@ func (c *CombatState) UseTargetedAction(attacker *game.Actor, action game.Action
         }
     })
 }
+func (c *CombatState) UseUntargetedAction(user *game.Actor, action game.Action) {
+    c.hasUsedPrimaryAction[user] = true
+    c.engine.Print(fmt.Sprintf("%s uses %s", user.Name(), action.Name()))
+    action.Execute(c.engine, user)
+}
+
 func (c *CombatState) onActionImpact(attacker *game.Actor, spell game.Action, finalDestination geometry.Point, actorHit *game.Actor) {
     if actorHit != nil {
         if actorHit.IsHidden() {
@@ -727,17 +723,10 @@ func (c *CombatState) PlayerUsesActiveSkill(user *game.Actor, skillUsed game.Act
 }
 func (c *CombatState) SelectActionTarget(attacker *game.Actor, action game.Action) {
     c.engine.CloseAllModals()
-    c.waitForTarget = func(targetPos geometry.Point) {
+    validTargets := action.GetValidTargets(c.engine, attacker, attacker.Pos())
+    c.beginTargeting(attacker, validTargets, c.previewTargetedAction(attacker, action), func(targetPos geometry.Point) {
         c.UseTargetedAction(attacker, action, targetPos)
-        c.waitForTarget = nil
-        c.validTargets = nil
-        c.engine.ClearOverlay()
-    }
-    c.setValidTargets(action.GetValidTargets(c.engine, attacker, attacker.Pos()))
-}
-func (c *CombatState) setValidTargets(validTargets map[geometry.Point]bool) {
-    c.validTargets = validTargets
-    c.engine.SetOverlay(toColorMap(c.validTargets, ega.BrightGreen))
+    })
 }
 func toColorMap(targets map[geometry.Point]bool, drawColor color.Color) map[geometry.Point]color.Color {
     colorMap := make(map[geometry.Point]color.Color)
@@ -749,41 +738,29 @@ func toColorMap(targets map[geometry.Point]bool, drawColor color.Color) map[geom
 
 func (c *CombatState) selectRangedTarget(attacker *game.Actor) {
     c.engine.CloseAllModals()
-    c.waitForTarget = func(targetPos geometry.Point) {
+    validTargets := c.getPositionsOfVisibleOpponentsOfActor(attacker)
+    c.beginTargeting(attacker, validTargets, c.previewRangedAttack(attacker), func(targetPos geometry.Point) {
         c.RangedAttack(attacker, targetPos)
-        c.waitForTarget = nil
-        c.validTargets = nil
-        c.engine.ClearOverlay()
-    }
-    c.setValidTargets(c.getPositionsOfVisibleOpponentsOfActor(attacker))
+    })
 }
 
 func (c *CombatState) selectOrchestratedRangedTarget() {
-    c.waitForTarget = func(targetPos geometry.Point) {
+    validTargets := c.getVisibleOpponentsOfParty()
+    c.beginTargeting(c.engine.GetAvatar(), validTargets, c.previewOrchestratedRangedAttack(), func(targetPos geometry.Point) {
         for _, partyMember := range c.engine.GetPartyMembers() {
             c.RangedAttack(partyMember, targetPos)
         }
-        c.waitForTarget = nil
-        c.validTargets = nil
-        c.engine.ClearOverlay()
-    }
-    c.setValidTargets(c.getVisibleOpponentsOfParty())
+    })
 }
 
 func (c *CombatState) onRangedImpact(attacker, actorHit *game.Actor) {
     if actorHit != nil {
-        if actorHit.IsHidden() {
-            actorHit.SetHidden(false)
-        }
-        doesHit := c.engine.rules.DoesRangedAttackHit(attacker, actorHit)
-        if doesHit {
-            c.deliverRangedDamage(attacker, actorHit)
-        }
+        c.combat.ResolveRangedHit(attacker, actorHit)
     }
 }
 
 func (c *CombatState) addOpponent(opponent *game.Actor) {
-    if c.engine.IsPlayerControlled(opponent) {
+    if c.IsOnPartySide(opponent) {
         return
     }
     if !c.didAlertNearbyActors {
@@ -804,7 +781,7 @@ func (c *CombatState) listOfEnemies() []*game.Actor {
 
 func (c *CombatState) removeDeadAndSleepingOpponents() {
     for opponent, _ := range c.opponents {
-        if !opponent.IsAlive() || opponent.IsSleeping() || c.engine.IsPlayerControlled(opponent) {
+        if !opponent.IsAlive() || opponent.IsSleeping() || c.IsOnPartySide(opponent) {
             delete(c.opponents, opponent)
         }
     }
@@ -815,156 +792,9 @@ func (c *CombatState) IsAITurn() bool {
 }
 
 func (c *CombatState) isActorBlockingSightForAttacker(attacker *game.Actor) func(actor *game.Actor) bool {
-    return func(actor *game.Actor) bool {
-        return !c.areAllies(attacker, actor)
-    }
+    return game.IsBlockingLineOfFireFor(attacker)
 }
 
-func (c *CombatState) getUtilityForPosition(ourActor *game.Actor, potentialNewLocation geometry.Point) (int, BattleAction) {
-    // what's our disposition? Stay near for melee, or stay far for ranged?
-    // if we can do ranged attacks, we will. If we can't, we'll try to close in.
-    // for ranged: positions were we can attack but cannot be attacked are favorable
-    gridMap := c.engine.GetGridMap()
-
-    utility := 0
-    bestAction := BattleAction{
-        MovementDestination: potentialNewLocation,
-        ActionType:          AttackActionTypeMelee,
-    }
-    allyPositions := make(map[geometry.Point]*game.Actor)
-    enemyPositions := make(map[geometry.Point]*game.Actor)
-    enemies := c.getActiveOpponents(ourActor)
-    allies := c.getActiveAllies(ourActor)
-    for _, enemy := range enemies {
-        enemyPositions[enemy.Pos()] = enemy
-    }
-    for _, ally := range allies {
-        allyPositions[ally.Pos()] = ally
-    }
-
-    activeSkills := ourActor.GetActiveSkills()
-    spells := ourActor.GetEquippedSpells()
-
-    /*
-       canMeleeAttack := true
-       canRangedAttack := ourActor.HasRangedWeaponEquipped()
-       canUseActiveSkill := len(activeSkills) > 0
-       canUseSpell := len(spells) > 0
-
-    */
-
-    // assign utility to this position first
-
-    var neighborsWithAllies, neighborsWithEnemies []geometry.Point
-    neighbors := gridMap.GetAllCardinalNeighbors(potentialNewLocation)
-    for _, neighbor := range neighbors {
-        if gridMap.IsActorAt(neighbor) {
-            actorAt := gridMap.ActorAt(neighbor)
-            if actorAt == ourActor {
-                continue
-            }
-            if actorAt.IsAlive() {
-                if c.areAllies(ourActor, actorAt) {
-                    neighborsWithAllies = append(neighborsWithAllies, neighbor)
-                } else {
-                    neighborsWithEnemies = append(neighborsWithEnemies, neighbor)
-                }
-            }
-        }
-    }
-
-    maxSkillUtility := 0
-    var bestSkill game.Action
-    var bestLocation geometry.Point
-    for _, skill := range append(activeSkills, spells...) {
-        if skill.IsTargeted() {
-            validTargetsOfSkill := skill.GetValidTargets(c.engine, ourActor, potentialNewLocation)
-            for target, _ := range validTargetsOfSkill {
-                combatUtility := skill.GetCombatUtilityForTargetedUseOnLocation(c.engine, ourActor, target, allyPositions, enemyPositions)
-                if combatUtility > maxSkillUtility {
-                    maxSkillUtility = combatUtility
-                    bestSkill = skill
-                    bestLocation = target
-                }
-            }
-        } else {
-            combatUtility := skill.GetCombatUtilityForUseAtLocation(c.engine, ourActor, potentialNewLocation, allyPositions, enemyPositions)
-            if combatUtility > maxSkillUtility {
-                maxSkillUtility = combatUtility
-                bestSkill = skill
-                bestLocation = potentialNewLocation
-            }
-        }
-    }
-    positionUtility := 0
-    if maxSkillUtility > 0 {
-        bestAction = BattleAction{
-            MovementDestination:  potentialNewLocation,
-            ActionType:           AttackActionTypeActiveSkill,
-            ActiveAction:         bestSkill,
-            ActionTargetLocation: &bestLocation,
-        }
-        positionUtility += c.getRangedUtility(neighborsWithAllies, neighborsWithEnemies)
-    } else {
-        positionUtility += c.getMeleeUtility(neighborsWithAllies, neighborsWithEnemies)
-    }
-
-    distance := geometry.DistanceManhattan(ourActor.Pos(), potentialNewLocation)
-    positionUtility -= distance
-
-    utility = positionUtility + maxSkillUtility
-    return utility, bestAction
-}
-
-func (c *CombatState) getRangedUtility(neighborsWithAllies, neighborsWithEnemies []geometry.Point) int {
-    rangedUtility := 0
-    if len(neighborsWithEnemies) == 0 {
-        rangedUtility += 30
-    } else if len(neighborsWithEnemies) > 1 {
-        rangedUtility -= 30
-    }
-
-    if len(neighborsWithAllies) == 0 { // by, default, leave a gap
-        rangedUtility += 10
-    }
-    return rangedUtility
-}
-func (c *CombatState) getMeleeUtility(neighborsWithAllies, neighborsWithEnemies []geometry.Point) int {
-    meleeUtility := 0
-    if len(neighborsWithEnemies) == 1 {
-        meleeUtility += 30
-    } else if len(neighborsWithEnemies) > 1 {
-        meleeUtility -= 10
-    }
-
-    if len(neighborsWithAllies) == 0 { // by, default, leave a gap
-        meleeUtility += 10
-    }
-    return meleeUtility
-}
-func (c *CombatState) getCombatPathTo(ourActor *game.Actor, dijkstraMap map[geometry.Point]int, dest geometry.Point) []geometry.Point {
-    if ourActor.Pos() == dest { // we're already there
-        return []geometry.Point{}
-    }
-    if _, ok := dijkstraMap[dest]; !ok { // we can't reach this position
-        return []geometry.Point{}
-    }
-    path := []geometry.Point{dest}
-    currentPos := dest
-    for currentPos != ourActor.Pos() {
-        currentDistInMap := dijkstraMap[currentPos] // roll down from here..
-        for _, neighbor := range c.engine.currentMap.GetAllCardinalNeighbors(currentPos) {
-            if distOfNeighbor, ok := dijkstraMap[neighbor]; ok && distOfNeighbor < currentDistInMap {
-                path = append(path, neighbor)
-                currentPos = neighbor
-                break
-            }
-        }
-    }
-    // reverse the path
-    slices.Reverse(path)
-    return path
-}
 func (c *CombatState) areAllies(one *game.Actor, two *game.Actor) bool {
     return one.GetCombatFaction() == two.GetCombatFaction()
 }
@@ -1001,10 +831,10 @@ func (c *CombatState) canSee(one, other *game.Actor) bool {
 }
 func (c *CombatState) getOpponents(actor *game.Actor, keep func(actor *game.Actor) bool) []*game.Actor {
     var listOfOpponents []*game.Actor
-    if c.engine.IsPlayerControlled(actor) {
+    if c.IsOnPartySide(actor) {
         listOfOpponents = toList(c.opponents)
     } else {
-        listOfOpponents = c.engine.GetPartyMembers()
+        listOfOpponents = c.getPartySide()
     }
 
     var opponents []*game.Actor
@@ -1016,12 +846,12 @@ func (c *CombatState) getOpponents(actor *game.Actor, keep func(actor *game.Acto
     return opponents
 }
 
-func (c *CombatState) getActiveOpponents(actor *game.Actor) []*game.Actor {
+func (c *CombatState) GetActiveOpponents(actor *game.Actor) []*game.Actor {
     var listOfOpponents []*game.Actor
-    if c.engine.IsPlayerControlled(actor) {
+    if c.IsOnPartySide(actor) {
         listOfOpponents = toList(c.opponents)
     } else {
-        listOfOpponents = c.engine.GetPartyMembers()
+        listOfOpponents = c.getPartySide()
     }
 
     var opponents []*game.Actor
@@ -1033,10 +863,10 @@ func (c *CombatState) getActiveOpponents(actor *game.Actor) []*game.Actor {
     return opponents
 }
 
-func (c *CombatState) getActiveAllies(actor *game.Actor) []*game.Actor {
+func (c *CombatState) GetActiveAllies(actor *game.Actor) []*game.Actor {
     var listOfAllies []*game.Actor
-    if c.engine.IsPlayerControlled(actor) {
-        listOfAllies = c.engine.GetPartyMembers()
+    if c.IsOnPartySide(actor) {
+        listOfAllies = c.getPartySide()
     } else {
         listOfAllies = toList(c.opponents)
     }
@@ -0,0 +1,59 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/util"
+    "fmt"
+)
+
+func (c *CombatState) offerSurrender(actor *game.Actor) {
+    c.hasUsedPrimaryAction[actor] = true
+    text := []string{
+        fmt.Sprintf("%s throws down their weapon.", actor.Name()),
+        "\"Mercy! I yield!\"",
+    }
+    choices := []util.MenuItem{
+        {
+            Text: "Accept the surrender",
+            Action: func() {
+                c.engine.CloseConversation()
+                c.acceptSurrender(actor)
+            },
+        },
+        {
+            Text: "Show no mercy",
+            Action: func() {
+                c.engine.CloseConversation()
+                c.engine.Print(fmt.Sprintf("'%s' has to fight on", actor.Name()))
+            },
+        },
+    }
+    c.engine.ShowMultipleChoiceDialogue(false, actor.Icon(0), c.engine.gridRenderer.AutolayoutArrayToIconPages(5, text), choices)
+}
+
+// acceptSurrender takes the actor out of the fight.
+// If they have something to say about it, the "_surrender" node of their dialogue is opened.
+func (c *CombatState) acceptSurrender(actor *game.Actor) {
+    actor.SetAggressive(false)
+    delete(c.isFleeing, actor)
+    c.removeOpponent(actor)
+    c.engine.Print(fmt.Sprintf("'%s' surrendered", actor.Name()))
+    dialogue := actor.GetDialogue()
+    if dialogue != nil && dialogue.HasSurrenderText() {
+        response := dialogue.GetResponseAndAddKnowledge(c.engine.GetAvatar(), c.engine.playerKnowledge, c.engine, "_surrender")
+        c.engine.handleDialogueChoice(dialogue, response, actor)
+    }
+}
+
+// escape lets the actor leave the fight. It won't come back.
+func (c *CombatState) escape(actor *game.Actor) {
+    c.hasUsedPrimaryAction[actor] = true
+    actor.SetAggressive(false)
+    delete(c.isFleeing, actor)
+    c.engine.Print(fmt.Sprintf("'%s' escaped", actor.Name()))
+    if c.companions[actor] {
+        c.dismissCompanion(actor, false)
+        return
+    }
+    c.removeOpponent(actor)
+}
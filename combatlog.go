@@ -0,0 +1,77 @@
+package main
+
+import (
+    "Legacy/util"
+    "fmt"
+    "os"
+)
+
+const combatLogFilename = "combatlog.txt"
+
+// openPrintLog shows the message log. Once there was a fight, the detailed combat log can be chosen as well.
+func (g *GridEngine) openPrintLog() {
+    combatLog := g.GetCombatLog()
+    if combatLog.IsEmpty() {
+        g.ShowFixedFormatText(g.printLog)
+        return
+    }
+    g.openMenuWithTitle("Logs", []util.MenuItem{
+        {
+            Text: "Messages",
+            Action: func() {
+                g.ShowFixedFormatText(g.printLog)
+            },
+        },
+        {
+            Text: "Combat log",
+            Action: func() {
+                g.ShowFixedFormatText(combatLog.GetLines(nil, true))
+            },
+        },
+        {
+            Text:   "Combat log by actor",
+            Action: g.openCombatLogFilterMenu,
+        },
+        {
+            Text:   "Save combat log",
+            Action: g.saveCombatLog,
+        },
+    })
+}
+
+func (g *GridEngine) openCombatLogFilterMenu() {
+    combatLog := g.GetCombatLog()
+    var menuItems []util.MenuItem
+    nameCount := make(map[string]int)
+    for _, actor := range combatLog.GetActors() {
+        logActor := actor
+        // actors sharing a name are numbered in order of appearance
+        nameCount[logActor.Name()]++
+        text := logActor.Name()
+        if nameCount[logActor.Name()] > 1 {
+            text = fmt.Sprintf("%s (%d)", logActor.Name(), nameCount[logActor.Name()])
+        }
+        menuItems = append(menuItems, util.MenuItem{
+            Text: text,
+            Action: func() {
+                g.ShowFixedFormatText(combatLog.GetLines(logActor, true))
+            },
+        })
+    }
+    g.openMenuWithTitle("Combat log of", menuItems)
+}
+
+// saveCombatLog writes the combat log to a text file in the working directory.
+func (g *GridEngine) saveCombatLog() {
+    file, err := os.Create(combatLogFilename)
+    if err != nil {
+        g.Print(fmt.Sprintf("Could not save the combat log: %s", err.Error()))
+        return
+    }
+    defer file.Close()
+    if _, err = g.GetCombatLog().WriteTo(file); err != nil {
+        g.Print(fmt.Sprintf("Could not save the combat log: %s", err.Error()))
+        return
+    }
+    g.Print(fmt.Sprintf("Combat log saved to %s", combatLogFilename))
+}
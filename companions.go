@@ -0,0 +1,82 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "fmt"
+    "path"
+    "slices"
+)
+
+// SummonMonsterAt only works in a fight. The creature is gone when the fight is over.
+func (g *GridEngine) SummonMonsterAt(caster *game.Actor, pos geometry.Point, monster game.SummonableMonster, rounds int) {
+    if !g.IsInCombat() || !g.combatManager.IsOnPartySide(caster) {
+        g.Print("Nothing answers the call")
+        return
+    }
+    if g.currentMap.IsActorAt(pos) {
+        return
+    }
+    summoned := game.NewActorFromFile(mustOpen(path.Join("assets", "npc", monster.NpcName+".txt")), monster.Icon, g.gridRenderer.AutolayoutArrayToIconPages)
+    summoned.SetInternalName("summoned_" + monster.NpcName)
+    g.rules.PrepareAsSummon(summoned, caster.GetLevel())
+    g.currentMap.AddActor(summoned, pos)
+    g.combatManager.addCompanion(summoned, game.CompanionKindSummoned, rounds)
+    g.GetCombatLog().LogEffect(summoned, fmt.Sprintf("is summoned for %d rounds", rounds))
+    g.Print(fmt.Sprintf("'%s' answers the call of '%s'", summoned.Name(), caster.Name()))
+}
+
+func (g *GridEngine) Charm(caster *game.Actor, victim *game.Actor, rounds int) {
+    if !g.IsInCombat() || !g.combatManager.opponents[victim] || !g.combatManager.IsOnPartySide(caster) {
+        g.Print(fmt.Sprintf("'%s' looks confused for a moment", victim.Name()))
+        return
+    }
+    g.combatManager.addCompanion(victim, game.CompanionKindCharmed, rounds)
+    g.GetCombatLog().LogEffect(victim, fmt.Sprintf("is charmed for %d rounds", rounds))
+    g.Print(fmt.Sprintf("'%s' is charmed by '%s'", victim.Name(), caster.Name()))
+    g.combatManager.checkForEndOfCombat()
+}
+
+func (c *CombatState) IsOnPartySide(actor *game.Actor) bool {
+    return c.engine.IsPlayerControlled(actor) || c.companions[actor]
+}
+
+// getPartySide returns the party members, followed by their companions.
+func (c *CombatState) getPartySide() []*game.Actor {
+    return append(slices.Clone(c.engine.GetPartyMembers()), toList(c.companions)...)
+}
+
+func (c *CombatState) addCompanion(actor *game.Actor, kind game.CompanionKind, rounds int) {
+    delete(c.opponents, actor)
+    delete(c.isFleeing, actor)
+    actor.BecomeCompanion(kind, rounds)
+    c.companions[actor] = true
+}
+
+// dismissCompanion is called when the time of the companion is up or the fight is over.
+// Summoned creatures vanish. Charmed ones go back to their old side, or leave peacefully after the fight.
+func (c *CombatState) dismissCompanion(actor *game.Actor, returnsToFight bool) {
+    isSummoned := actor.IsSummoned()
+    delete(c.companions, actor)
+    actor.ReleaseCompanion()
+    combatLog := c.combatLog
+    if isSummoned {
+        c.engine.currentMap.RemoveActor(actor)
+        combatLog.LogEffect(actor, "vanishes")
+        c.engine.Print(fmt.Sprintf("'%s' vanishes", actor.Name()))
+        return
+    }
+    combatLog.LogEffect(actor, "is no longer charmed")
+    c.engine.Print(fmt.Sprintf("'%s' is no longer charmed", actor.Name()))
+    if returnsToFight && actor.IsAlive() {
+        c.opponents[actor] = true
+        return
+    }
+    actor.SetAggressive(false)
+}
+
+func (c *CombatState) dismissAllCompanions() {
+    for companion, _ := range c.companions {
+        c.dismissCompanion(companion, false)
+    }
+}
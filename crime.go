@@ -0,0 +1,176 @@
+package main
+
+import (
+    "Legacy/ega"
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gocoro"
+    "Legacy/util"
+    "fmt"
+    "time"
+)
+
+// onCriminalOffense is called when a crime against an NPC was noticed.
+// The witnesses will raise a bounty and a guard will come for the party.
+func (g *GridEngine) onCriminalOffense(crime game.CrimeType, victim *game.Actor) {
+    if !g.reportCrime(crime, victim, g.GetAvatar().Pos()) {
+        return
+    }
+    // some maps replace the guard confrontation with a scripted event
+    if g.currentMap.CriminalOffenseEvent != "" {
+        g.TriggerEvent(g.currentMap.CriminalOffenseEvent)
+        return
+    }
+    guard := g.findNearestGuard(g.GetAvatar().Pos())
+    if guard != nil {
+        g.guardPursuesParty(guard)
+    } else if victim != nil && victim.IsAlive() {
+        g.EnemyStartsCombat(victim)
+    }
+}
+
+// reportCrime adds a bounty for the crime, if anyone was there to see it.
+func (g *GridEngine) reportCrime(crime game.CrimeType, victim *game.Actor, location geometry.Point) bool {
+    witnesses := g.findWitnesses(victim, location)
+    if len(witnesses) == 0 {
+        return false
+    }
+    mapName := g.currentMap.GetName()
+    g.bounties.AddBounty(mapName, g.rules.GetBountyForCrime(crime))
+    g.flags.IncrementFlag(fmt.Sprintf("crimes_%s", crime))
+    g.Print(fmt.Sprintf("Your %s was witnessed. Bounty: %d", crime, g.bounties.GetBounty(mapName)))
+    return true
+}
+
+func (g *GridEngine) findWitnesses(victim *game.Actor, location geometry.Point) []*game.Actor {
+    var witnesses []*game.Actor
+    if victim != nil && victim.IsAlive() && !victim.IsSleeping() {
+        witnesses = append(witnesses, victim)
+    }
+    radius := g.rules.GetWitnessRadius()
+    nearbyActors := g.currentMap.FindAllNearbyActors(location, radius, func(actor *game.Actor) bool {
+        return actor != victim &&
+            !g.IsPlayerControlled(actor) &&
+            actor.IsAlive() &&
+            !actor.IsSleeping() &&
+            !actor.IsAggressive() &&
+            g.currentMap.IsInLineOfSight(actor.Pos(), location)
+    })
+    return append(witnesses, nearbyActors...)
+}
+
+func (g *GridEngine) findNearestGuard(location geometry.Point) *game.Actor {
+    var nearestGuard *game.Actor
+    nearestDistance := 0
+    for _, actor := range g.currentMap.Actors() {
+        if !actor.IsGuard() || !actor.IsAlive() || actor.IsSleeping() || g.IsPlayerControlled(actor) {
+            continue
+        }
+        distance := geometry.DistanceManhattan(actor.Pos(), location)
+        if nearestGuard == nil || distance < nearestDistance {
+            nearestGuard = actor
+            nearestDistance = distance
+        }
+    }
+    return nearestGuard
+}
+
+// PickedLockAt is called whenever the party picked a lock.
+// Doing so in a private zone counts as trespassing.
+func (g *GridEngine) PickedLockAt(location geometry.Point) {
+    zone := g.currentMap.ZoneAt(location)
+    if zone == nil || (!zone.IsPrivate() && !zone.IsHighSecurity()) {
+        return
+    }
+    g.onCriminalOffense(game.CrimeTrespassing, nil)
+}
+
+// checkForGuardsOnPatrol lets guards that spot a wanted party come for them.
+func (g *GridEngine) checkForGuardsOnPatrol(location geometry.Point) {
+    if !g.bounties.HasBounty(g.currentMap.GetName()) || g.animationRoutine.Running() || g.IsInConversation() {
+        return
+    }
+    radius := g.rules.GetWitnessRadius()
+    guards := g.currentMap.FindAllNearbyActors(location, radius, func(actor *game.Actor) bool {
+        return actor.IsGuard() &&
+            actor.IsAlive() &&
+            !actor.IsSleeping() &&
+            !g.IsPlayerControlled(actor) &&
+            g.currentMap.IsInLineOfSight(actor.Pos(), location)
+    })
+    if len(guards) > 0 {
+        g.guardPursuesParty(guards[0])
+    }
+}
+
+func (g *GridEngine) guardPursuesParty(guard *game.Actor) {
+    err := g.RunAnimationScript(func(exe *gocoro.Execution) {
+        maxSteps := g.rules.GetWitnessRadius() * 4
+        for steps := 0; steps < maxSteps && !guard.IsNearTo(g.GetAvatar()); steps++ {
+            if !guard.IsAlive() || g.IsInCombat() {
+                return
+            }
+            path := g.currentMap.GetJPSPath(guard.Pos(), g.GetAvatar().Pos(), g.currentMap.IsCurrentlyPassableAndSafe)
+            if len(path) < 2 {
+                break
+            }
+            g.TryMoveNPCOnPath(guard, path[1])
+            _ = exe.YieldTime(300 * time.Millisecond)
+        }
+        if guard.IsAlive() && !g.IsInCombat() {
+            g.confrontWithGuard(guard)
+        }
+    })
+    if err != nil {
+        g.confrontWithGuard(guard)
+    }
+}
+
+func (g *GridEngine) confrontWithGuard(guard *game.Actor) {
+    mapName := g.currentMap.GetName()
+    bounty := g.bounties.GetBounty(mapName)
+    if bounty == 0 {
+        return
+    }
+    fine := g.rules.GetFineForBounty(bounty)
+    jailDays := g.rules.GetJailDaysForBounty(bounty)
+    text := []string{
+        fmt.Sprintf("\"Stop right there, criminal scum! There is a bounty of %d gold on your head.\"", bounty),
+        "\"Pay the fine or serve your time.\"",
+    }
+    var choices []util.MenuItem
+    if g.playerParty.GetGold() >= fine {
+        choices = append(choices, util.MenuItem{
+            Text: fmt.Sprintf("Pay the fine (%d gold)", fine),
+            Action: func() {
+                g.CloseConversation()
+                g.playerParty.RemoveGold(fine)
+                guard.AddGold(fine)
+                g.bounties.ClearBounty(mapName)
+                g.Print(fmt.Sprintf("You paid a fine of %d gold.", fine))
+            },
+        })
+    }
+    choices = append(choices, util.MenuItem{
+        Text: fmt.Sprintf("Go to jail (%d days)", jailDays),
+        Action: func() {
+            g.CloseConversation()
+            g.serveJailTime(jailDays)
+        },
+    })
+    choices = append(choices, util.MenuItem{
+        Text: "Resist (Combat)",
+        Action: func() {
+            g.CloseConversation()
+            g.EnemyStartsCombat(guard)
+        },
+    })
+    g.ShowMultipleChoiceDialogue(false, guard.Icon(0), g.gridRenderer.AutolayoutArrayToIconPages(5, text), choices)
+}
+
+func (g *GridEngine) serveJailTime(days int) {
+    g.bounties.ClearBounty(g.currentMap.GetName())
+    g.flags.IncrementFlagBy("days_in_jail", days)
+    g.AdvanceWorldTimeWithMessage(days, 0, 0)
+    g.ShowScrollableText([]string{fmt.Sprintf("You spent %d days in a cell.", days), "The guards let you go with a stern warning."}, ega.BrightWhite, true)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/util"
+    "fmt"
+    "math"
+)
+
+func (g *GridEngine) openFormationMenu() {
+    var menuItems []util.MenuItem
+    for _, f := range game.GetAllFormations() {
+        formation := f
+        label := formation.Name()
+        if formation == g.playerParty.GetFormation() {
+            label = "* " + label
+        }
+        menuItems = append(menuItems, util.MenuItem{
+            Text:        label,
+            TooltipText: formation.GetDescription(),
+            Action: func() {
+                g.playerParty.SetFormation(formation)
+                g.Print(fmt.Sprintf("Formation: %s", formation.Name()))
+            },
+        })
+    }
+    g.openMenuWithTitle("Formation", menuItems)
+}
+
+// deployParty moves the party into formation, before the first round of a fight.
+// The avatar stays where it is, everyone else takes the free tile closest to their place in the formation.
+// Walls, hazards, objects and triggers are avoided.
+func (c *CombatState) deployParty() {
+    party := c.engine.GetParty()
+    if party.GetFormation() == game.FormationLoose || !party.HasFollowers() || party.IsSplit() || len(c.opponents) == 0 {
+        return
+    }
+    avatar := c.engine.GetAvatar()
+    offsets := party.GetFormationOffsets(avatar, c.directionOfNearestOpponent(avatar))
+    gridMap := c.engine.currentMap
+    isFreeForDeployment := func(pos geometry.Point) bool {
+        if !gridMap.Contains(pos) || !gridMap.IsWalkable(pos) || gridMap.IsObviousHazardAt(pos) || gridMap.IsObjectAt(pos) {
+            return false
+        }
+        if _, isTrigger := gridMap.GetNamedTriggerAt(pos); isTrigger {
+            return false
+        }
+        return !gridMap.IsActorAt(pos) || party.IsMember(gridMap.ActorAt(pos))
+    }
+    candidates := gridMap.GetFreeCellsForDistribution(avatar.Pos(), len(party.GetMembers())*8, isFreeForDeployment)
+
+    isTaken := map[geometry.Point]bool{avatar.Pos(): true}
+    for _, member := range party.GetMembers() {
+        if member == avatar || !member.CanAct() {
+            isTaken[member.Pos()] = true
+        }
+    }
+    for _, member := range party.GetMembers() {
+        if member == avatar || !member.CanAct() {
+            continue
+        }
+        wantedPos := avatar.Pos().Add(offsets[member])
+        bestPos := member.Pos()
+        bestDistance := geometry.DistanceSquared(bestPos, wantedPos)
+        for _, pos := range candidates {
+            if distance := geometry.DistanceSquared(pos, wantedPos); !isTaken[pos] && distance < bestDistance {
+                bestPos = pos
+                bestDistance = distance
+            }
+        }
+        isTaken[bestPos] = true
+        if bestPos == member.Pos() {
+            continue
+        }
+        if gridMap.IsActorAt(bestPos) {
+            gridMap.SwapPositions(member, gridMap.ActorAt(bestPos))
+        } else {
+            gridMap.MoveActor(member, bestPos)
+        }
+    }
+    c.engine.Print(fmt.Sprintf("The party takes the %s formation", party.GetFormation().Name()))
+}
+
+// directionOfNearestOpponent is the cardinal direction the party should face.
+func (c *CombatState) directionOfNearestOpponent(actor *game.Actor) geometry.Point {
+    var nearest *game.Actor
+    nearestDistance := math.MaxInt
+    for opponent, _ := range c.opponents {
+        if distance := geometry.DistanceSquared(actor.Pos(), opponent.Pos()); distance < nearestDistance {
+            nearest = opponent
+            nearestDistance = distance
+        }
+    }
+    if nearest == nil {
+        return geometry.Point{X: 1, Y: 0}
+    }
+    delta := nearest.Pos().Sub(actor.Pos())
+    if geometry.Abs(delta.X) >= geometry.Abs(delta.Y) {
+        return geometry.Point{X: sign(delta.X), Y: 0}
+    }
+    return geometry.Point{X: 0, Y: sign(delta.Y)}
+}
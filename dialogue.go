@@ -230,8 +230,13 @@ func (g *GridEngine) handleDialogueEffect(npc *game.Actor, dialogue *game.Dialog
         return func() { g.AddToParty(npc) }, ConversationFlowEffectAfterLastPage
     case "sells":
         return func() { g.openVendorMenu(npc) }, ConversationFlowEffectOnLastPage
+    case "recharges":
+        return func() { g.openRechargeMenu(npc) }, ConversationFlowEffectOnLastPage
     case "combat":
         return func() { g.EnemyStartsCombat(npc) }, ConversationFlowEffectAfterLastPage
+    case "clearBounty":
+        g.bounties.ClearBounty(g.currentMap.GetName())
+        return nil, ConversationFlowContinue
     }
     effectPredicate := recfile.StrPredicate(effect)
     if effectPredicate != nil {
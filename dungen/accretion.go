@@ -5,26 +5,35 @@ import (
     "math/rand"
 )
 
+const (
+    maxFirstRooms     = 20
+    maxFirstRoomTries = 1000
+)
+
 type AccretionGenerator struct {
     random *rand.Rand
+    seed   int64
 }
 
 func NewAccretionGenerator() *AccretionGenerator {
+    return NewAccretionGeneratorWithSeed(23)
+}
+
+// NewAccretionGeneratorWithSeed creates a generator that always builds the same layout for the same seed.
+func NewAccretionGeneratorWithSeed(seed int64) *AccretionGenerator {
     return &AccretionGenerator{
-        random: rand.New(rand.NewSource(42)),
+        random: rand.New(rand.NewSource(seed)),
+        seed:   seed,
     }
 }
 func (g *AccretionGenerator) Generate(width, height int) *DungeonMap {
-    g.random.Seed(23)
+    g.random.Seed(g.seed)
 
     dMap := NewDungeonMap(width, height)
-    rect := g.randomRect(width/2, height/2)
-
-    firstRoom := NewDungeonRoomFromRect(g.random, rect)
-    firstRoom.SetPositionOffset(geometry.Point{X: 1 + g.random.Intn(width-rect.Size().X-2), Y: 1 + g.random.Intn(height-rect.Size().Y-2)})
-
-    for !dMap.CanPlaceRoom(firstRoom) {
-        firstRoom.SetPositionOffset(geometry.Point{X: 1 + g.random.Intn(width-rect.Size().X-2), Y: 1 + g.random.Intn(height-rect.Size().Y-2)})
+    firstRoom, isPlaced := g.placeFirstRoom(dMap, width, height)
+    if !isPlaced {
+        // the map is too small, an empty layout is all we can do
+        return dMap
     }
 
     dMap.AddRoomAndSetTiles(firstRoom)
@@ -49,6 +58,29 @@ func (g *AccretionGenerator) Generate(width, height int) *DungeonMap {
     return dMap
 }
 
+// placeFirstRoom moves a random room around, until it fits. Rooms that don't fit at all are replaced.
+func (g *AccretionGenerator) placeFirstRoom(dMap *DungeonMap, width, height int) (*DungeonRoom, bool) {
+    // the smallest room is 3x3, with a wall and at least one tile to move it around on every side
+    if width < 6 || height < 6 {
+        return nil, false
+    }
+    for room := 0; room < maxFirstRooms; room++ {
+        rect := g.randomRect(width/2, height/2)
+        freeWidth, freeHeight := width-rect.Size().X-2, height-rect.Size().Y-2
+        if freeWidth < 1 || freeHeight < 1 {
+            continue
+        }
+        firstRoom := NewDungeonRoomFromRect(g.random, rect)
+        for try := 0; try < maxFirstRoomTries; try++ {
+            firstRoom.SetPositionOffset(geometry.Point{X: 1 + g.random.Intn(freeWidth), Y: 1 + g.random.Intn(freeHeight)})
+            if dMap.CanPlaceRoom(firstRoom) {
+                return firstRoom, true
+            }
+        }
+    }
+    return nil, false
+}
+
 func (g *AccretionGenerator) randomRect(width int, height int) geometry.Rect {
     randWidth := max(3, g.random.Intn(width))
     randHeight := max(3, g.random.Intn(height))
@@ -59,7 +91,8 @@ func (g *AccretionGenerator) randomRect(width int, height int) geometry.Rect {
 func (g *AccretionGenerator) tryConnectTo(dMap *DungeonMap, newRoom *DungeonRoom, existingRoom *DungeonRoom) bool {
     freeDoors := existingRoom.GetFreeDoorsAsRelative()
 
-    for relativeDoorPos, direction := range freeDoors {
+    for _, relativeDoorPos := range sortedDoorPositions(freeDoors) {
+        direction := freeDoors[relativeDoorPos]
         if relativeNewDoorPos, ok := newRoom.HasFreeRelativeDoorInDirection(direction.Opposite()); ok {
             // both have free doors in opposite directions
             absoluteDoorPos := existingRoom.GetAbsoluteDoorPosition(relativeDoorPos)
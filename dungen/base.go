@@ -2,7 +2,11 @@ package dungen
 
 import (
     "Legacy/geometry"
+    "io"
     "math/rand"
+    "os"
+    "slices"
+    "strings"
 )
 
 type DungeonTile int
@@ -19,6 +23,7 @@ type DungeonMap struct {
     height     int
     tiles      []DungeonTile
     rooms      []*DungeonRoom
+    reserved   []geometry.Rect
     pathfinder *geometry.PathRange
 }
 
@@ -77,22 +82,160 @@ func (m *DungeonMap) AllRooms() []*DungeonRoom {
     return m.rooms
 }
 
+// AddRoomFromTiles registers an irregular area, like a cave chamber, so it can be used like any other room.
+func (m *DungeonMap) AddRoomFromTiles(tiles []geometry.Point) {
+    if len(tiles) == 0 {
+        return
+    }
+    room := &DungeonRoom{
+        floorTiles:         make(map[geometry.Point]bool),
+        availableDoorTiles: make(map[geometry.Point]geometry.CompassDirection),
+        connectedRooms:     make(map[geometry.Point]*DungeonRoom),
+    }
+    sum := geometry.Point{}
+    for _, tile := range tiles {
+        room.floorTiles[tile] = true
+        sum = sum.Add(tile)
+    }
+    average := geometry.Point{X: sum.X / len(tiles), Y: sum.Y / len(tiles)}
+    room.center = tiles[0]
+    for _, tile := range tiles {
+        if geometry.DistanceManhattan(tile, average) < geometry.DistanceManhattan(room.center, average) {
+            room.center = tile
+        }
+    }
+    m.rooms = append(m.rooms, room)
+}
+
+// SetRoomsFromSectors divides layouts without rooms into square sectors.
+// The open tiles of each sector become a room.
+func (m *DungeonMap) SetRoomsFromSectors(sectorSize int) {
+    for sectorY := 0; sectorY < m.height; sectorY += sectorSize {
+        for sectorX := 0; sectorX < m.width; sectorX += sectorSize {
+            var tiles []geometry.Point
+            for y := sectorY; y < min(sectorY+sectorSize, m.height); y++ {
+                for x := sectorX; x < min(sectorX+sectorSize, m.width); x++ {
+                    if m.IsEmptySpace(geometry.Point{X: x, Y: y}) {
+                        tiles = append(tiles, geometry.Point{X: x, Y: y})
+                    }
+                }
+            }
+            // too small to be worth visiting
+            if len(tiles) >= sectorSize {
+                m.AddRoomFromTiles(tiles)
+            }
+        }
+    }
+}
+
+// ReserveSpace finds a place for a hand-made prefab of the given size, preferring places with the least floor to cover.
+// The space is filled with walls, the prefab brings its own tiles. Rooms it cuts into are no longer used as rooms.
+func (m *DungeonMap) ReserveSpace(width, height int, random *rand.Rand) (geometry.Rect, bool) {
+    var candidates []geometry.Rect
+    leastCovered := -1
+    for y := 0; y+height <= m.height; y++ {
+        for x := 0; x+width <= m.width; x++ {
+            rect := geometry.NewRect(x, y, x+width, y+height)
+            covered, isUsable := m.countCoveredFloor(rect)
+            if !isUsable || (leastCovered >= 0 && covered > leastCovered) {
+                continue
+            }
+            if covered < leastCovered || leastCovered < 0 {
+                candidates = candidates[:0]
+                leastCovered = covered
+            }
+            candidates = append(candidates, rect)
+        }
+    }
+    if len(candidates) == 0 {
+        return geometry.Rect{}, false
+    }
+    chosen := candidates[random.Intn(len(candidates))]
+    var remainingRooms []*DungeonRoom
+    for _, room := range m.rooms {
+        if !room.IsOverlapping(chosen) {
+            remainingRooms = append(remainingRooms, room)
+        }
+    }
+    // the ladders need two rooms
+    if len(remainingRooms) < 2 {
+        return geometry.Rect{}, false
+    }
+    m.rooms = remainingRooms
+    for y := chosen.Min.Y; y < chosen.Max.Y; y++ {
+        for x := chosen.Min.X; x < chosen.Max.X; x++ {
+            m.SetWall(x, y)
+        }
+    }
+    m.reserved = append(m.reserved, chosen)
+    return chosen, true
+}
+
+func (m *DungeonMap) countCoveredFloor(rect geometry.Rect) (int, bool) {
+    covered := 0
+    for y := rect.Min.Y; y < rect.Max.Y; y++ {
+        for x := rect.Min.X; x < rect.Max.X; x++ {
+            pos := geometry.Point{X: x, Y: y}
+            if m.GetTileAt(pos) == Door || m.IsReserved(pos) {
+                return 0, false
+            }
+            if m.IsWalkable(pos) {
+                covered++
+            }
+        }
+    }
+    return covered, true
+}
+
+func (m *DungeonMap) IsReserved(pos geometry.Point) bool {
+    for _, rect := range m.reserved {
+        if rect.Contains(pos) {
+            return true
+        }
+    }
+    return false
+}
+
+// isOnBorder is true for the outermost tiles, they always stay walls.
+func (m *DungeonMap) isOnBorder(pos geometry.Point) bool {
+    return pos.X <= 0 || pos.Y <= 0 || pos.X >= m.width-1 || pos.Y >= m.height-1
+}
+
 func (m *DungeonMap) Print() {
+    m.Fprint(os.Stderr, nil)
+}
+
+// Fprint writes the layout as ASCII, markers are drawn on top of the tiles.
+func (m *DungeonMap) Fprint(w io.Writer, markers map[geometry.Point]rune) {
+    var sb strings.Builder
     for y := 0; y < m.height; y++ {
         for x := 0; x < m.width; x++ {
+            if marker, hasMarker := markers[geometry.Point{X: x, Y: y}]; hasMarker {
+                sb.WriteRune(marker)
+                continue
+            }
             switch m.GetTile(x, y) {
             case Wall:
-                print("#")
+                sb.WriteString("#")
             case Corridor:
-                print(".")
+                sb.WriteString(".")
             case Room:
-                print(".")
+                sb.WriteString(".")
             case Door:
-                print("+")
+                sb.WriteString("+")
             }
         }
-        println()
+        sb.WriteString("\n")
     }
+    _, _ = io.WriteString(w, sb.String())
+}
+
+func (m *DungeonMap) Width() int {
+    return m.width
+}
+
+func (m *DungeonMap) Height() int {
+    return m.height
 }
 
 func (m *DungeonMap) SetDoor(x int, y int) {
@@ -259,8 +402,8 @@ func (r *DungeonRoom) GetFreeDoorsAsRelative() map[geometry.Point]geometry.Compa
 }
 
 func (r *DungeonRoom) HasFreeRelativeDoorInDirection(direction geometry.CompassDirection) (geometry.Point, bool) {
-    for pos, dir := range r.availableDoorTiles {
-        if dir == direction {
+    for _, pos := range sortedDoorPositions(r.availableDoorTiles) {
+        if r.availableDoorTiles[pos] == direction {
             if _, ok := r.connectedRooms[pos]; !ok {
                 return pos, true
             } else {
@@ -284,6 +427,7 @@ func (r *DungeonRoom) GetAbsoluteFloorTiles() []geometry.Point {
     for point, _ := range r.floorTiles {
         result = append(result, r.roomPositionOffset.Add(point))
     }
+    slices.SortFunc(result, comparePoints)
     return result
 }
 
@@ -339,6 +483,15 @@ func (r *DungeonRoom) Contains(absolutePosition geometry.Point) bool {
     return false
 }
 
+func (r *DungeonRoom) IsOverlapping(rect geometry.Rect) bool {
+    for _, tile := range r.GetAbsoluteFloorTiles() {
+        if rect.Contains(tile) {
+            return true
+        }
+    }
+    return false
+}
+
 func NewDungeonRoomFromRect(random *rand.Rand, bounds geometry.Rect) *DungeonRoom {
     rectRoom := &DungeonRoom{
         center:         bounds.Center(),
@@ -367,6 +520,23 @@ func NewDungeonRoomFromRect(random *rand.Rand, bounds geometry.Rect) *DungeonRoo
     return rectRoom
 }
 
+// sortedDoorPositions gives the doors a fixed order, so the same seed always leads to the same dungeon.
+func sortedDoorPositions(doors map[geometry.Point]geometry.CompassDirection) []geometry.Point {
+    positions := make([]geometry.Point, 0, len(doors))
+    for pos := range doors {
+        positions = append(positions, pos)
+    }
+    slices.SortFunc(positions, comparePoints)
+    return positions
+}
+
+func comparePoints(a, b geometry.Point) int {
+    if a.Y != b.Y {
+        return a.Y - b.Y
+    }
+    return a.X - b.X
+}
+
 func roomTilesFromRect(bounds geometry.Rect) map[geometry.Point]bool {
     result := make(map[geometry.Point]bool)
     for x := bounds.Min.X; x < bounds.Max.X; x++ {
@@ -0,0 +1,64 @@
+package dungen
+
+import (
+    "Legacy/geometry"
+    "math/rand"
+)
+
+// CaveGenerator grows natural caves with a cellular automaton.
+// Random noise is smoothed until only round chambers and winding passages remain.
+type CaveGenerator struct {
+    random      *rand.Rand
+    seed        int64
+    wallChance  float64
+    smoothSteps int
+}
+
+func NewCaveGenerator(seed int64) *CaveGenerator {
+    return &CaveGenerator{
+        random:      rand.New(rand.NewSource(seed)),
+        seed:        seed,
+        wallChance:  0.45,
+        smoothSteps: 4,
+    }
+}
+
+func (g *CaveGenerator) Generate(width, height int) *DungeonMap {
+    g.random.Seed(g.seed)
+    dMap := NewDungeonMap(width, height)
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            pos := geometry.Point{X: x, Y: y}
+            if !dMap.isOnBorder(pos) && g.random.Float64() >= g.wallChance {
+                dMap.SetRoom(x, y)
+            }
+        }
+    }
+    for i := 0; i < g.smoothSteps; i++ {
+        g.smooth(dMap)
+    }
+    dMap.SetRoomsFromSectors(8)
+    return dMap
+}
+
+// smooth turns every tile into a wall, if most of its neighbors are walls.
+func (g *CaveGenerator) smooth(dMap *DungeonMap) {
+    nb := geometry.Neighbors{}
+    nextTiles := make([]DungeonTile, len(dMap.tiles))
+    for y := 0; y < dMap.height; y++ {
+        for x := 0; x < dMap.width; x++ {
+            pos := geometry.Point{X: x, Y: y}
+            wallCount := len(nb.All(pos, func(p geometry.Point) bool {
+                return !dMap.Contains(p) || dMap.GetTileAt(p) == Wall
+            }))
+            if dMap.isOnBorder(pos) || wallCount >= 5 {
+                nextTiles[x+y*dMap.width] = Wall
+            } else if wallCount <= 3 {
+                nextTiles[x+y*dMap.width] = Room
+            } else {
+                nextTiles[x+y*dMap.width] = dMap.GetTileAt(pos)
+            }
+        }
+    }
+    dMap.tiles = nextTiles
+}
@@ -0,0 +1,28 @@
+package dungen
+
+// GeneratorNames are the names NewGeneratorByName knows about.
+var GeneratorNames = []string{"accretion", "caves", "mines", "maze"}
+
+// MinimumSize is the smallest width and height a generator is meant for.
+// Smaller maps give empty layouts or fewer than the two rooms needed for the ladders.
+func MinimumSize(name string) int {
+    switch name {
+    case "caves", "maze":
+        return 18
+    }
+    return 13
+}
+
+// NewGeneratorByName returns the generator for the names used in the dungeon configs.
+// Unknown names fall back to the accretion generator.
+func NewGeneratorByName(name string, seed int64) DungeonGenerator {
+    switch name {
+    case "caves":
+        return NewCaveGenerator(seed)
+    case "mines":
+        return NewMineGenerator(seed)
+    case "maze":
+        return NewMazeGenerator(seed)
+    }
+    return NewAccretionGeneratorWithSeed(seed)
+}
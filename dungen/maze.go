@@ -0,0 +1,153 @@
+package dungen
+
+import (
+    "Legacy/geometry"
+    "math/rand"
+)
+
+// MazeGenerator places rectangular rooms and fills the space between them with a winding maze.
+// Every room gets a door or two into the maze, dead ends are filled in afterward.
+type MazeGenerator struct {
+    random       *rand.Rand
+    seed         int64
+    roomAttempts int
+}
+
+func NewMazeGenerator(seed int64) *MazeGenerator {
+    return &MazeGenerator{
+        random:       rand.New(rand.NewSource(seed)),
+        seed:         seed,
+        roomAttempts: 30,
+    }
+}
+
+func (g *MazeGenerator) Generate(width, height int) *DungeonMap {
+    g.random.Seed(g.seed)
+    dMap := NewDungeonMap(width, height)
+
+    // rooms and maze cells sit on odd coordinates, so there is always a wall in between
+    var roomRects []geometry.Rect
+    for i := 0; i < g.roomAttempts; i++ {
+        roomWidth := 3 + 2*g.random.Intn(3)
+        roomHeight := 3 + 2*g.random.Intn(3)
+        if roomWidth+2 >= width || roomHeight+2 >= height {
+            continue
+        }
+        x := 1 + 2*g.random.Intn((width-roomWidth-1)/2)
+        y := 1 + 2*g.random.Intn((height-roomHeight-1)/2)
+        rect := geometry.NewRect(x, y, x+roomWidth, y+roomHeight)
+        if g.overlapsAny(rect, roomRects) {
+            continue
+        }
+        roomRects = append(roomRects, rect)
+        var tiles []geometry.Point
+        for ry := rect.Min.Y; ry < rect.Max.Y; ry++ {
+            for rx := rect.Min.X; rx < rect.Max.X; rx++ {
+                dMap.SetRoom(rx, ry)
+                tiles = append(tiles, geometry.Point{X: rx, Y: ry})
+            }
+        }
+        dMap.AddRoomFromTiles(tiles)
+    }
+
+    for y := 1; y < height-1; y += 2 {
+        for x := 1; x < width-1; x += 2 {
+            if dMap.GetTile(x, y) == Wall {
+                g.carveMaze(dMap, geometry.Point{X: x, Y: y})
+            }
+        }
+    }
+
+    for _, rect := range roomRects {
+        g.addDoors(dMap, rect)
+    }
+    g.removeDeadEnds(dMap)
+    return dMap
+}
+
+func (g *MazeGenerator) overlapsAny(rect geometry.Rect, others []geometry.Rect) bool {
+    grown := geometry.NewRect(rect.Min.X-1, rect.Min.Y-1, rect.Max.X+1, rect.Max.Y+1)
+    for _, other := range others {
+        if grown.Overlaps(other) {
+            return true
+        }
+    }
+    return false
+}
+
+// carveMaze is a randomized depth first search over the odd cells.
+func (g *MazeGenerator) carveMaze(dMap *DungeonMap, start geometry.Point) {
+    directions := []geometry.CompassDirection{geometry.North, geometry.South, geometry.East, geometry.West}
+    dMap.SetCorridor(start.X, start.Y)
+    stack := []geometry.Point{start}
+    for len(stack) > 0 {
+        current := stack[len(stack)-1]
+        var open []geometry.CompassDirection
+        for _, direction := range directions {
+            next := current.Add(direction.ToPoint().Mul(2))
+            if dMap.Contains(next) && !dMap.isOnBorder(next) && dMap.GetTileAt(next) == Wall {
+                open = append(open, direction)
+            }
+        }
+        if len(open) == 0 {
+            stack = stack[:len(stack)-1]
+            continue
+        }
+        direction := open[g.random.Intn(len(open))]
+        between := current.Add(direction.ToPoint())
+        next := current.Add(direction.ToPoint().Mul(2))
+        dMap.SetCorridor(between.X, between.Y)
+        dMap.SetCorridor(next.X, next.Y)
+        stack = append(stack, next)
+    }
+}
+
+// addDoors opens one or two of the walls around the room, where a corridor is right behind.
+func (g *MazeGenerator) addDoors(dMap *DungeonMap, rect geometry.Rect) {
+    var candidates []geometry.Point
+    for x := rect.Min.X; x < rect.Max.X; x++ {
+        candidates = append(candidates, geometry.Point{X: x, Y: rect.Min.Y - 1}, geometry.Point{X: x, Y: rect.Max.Y})
+    }
+    for y := rect.Min.Y; y < rect.Max.Y; y++ {
+        candidates = append(candidates, geometry.Point{X: rect.Min.X - 1, Y: y}, geometry.Point{X: rect.Max.X, Y: y})
+    }
+    var connectors []geometry.Point
+    for _, pos := range candidates {
+        if _, couldBeDoor := dMap.CouldBeADoor(pos); couldBeDoor && !dMap.isOnBorder(pos) {
+            connectors = append(connectors, pos)
+        }
+    }
+    doorCount := min(len(connectors), 1+g.random.Intn(2))
+    g.random.Shuffle(len(connectors), func(i, j int) {
+        connectors[i], connectors[j] = connectors[j], connectors[i]
+    })
+    for _, pos := range connectors[:doorCount] {
+        dMap.SetDoor(pos.X, pos.Y)
+    }
+}
+
+// removeDeadEnds fills corridors that lead nowhere, until only the loops and the ways between rooms are left.
+func (g *MazeGenerator) removeDeadEnds(dMap *DungeonMap) {
+    directions := []geometry.CompassDirection{geometry.North, geometry.South, geometry.East, geometry.West}
+    for removedSome := true; removedSome; {
+        removedSome = false
+        for y := 0; y < dMap.height; y++ {
+            for x := 0; x < dMap.width; x++ {
+                tile := dMap.GetTile(x, y)
+                if tile != Corridor && tile != Door {
+                    continue
+                }
+                openNeighbors := 0
+                for _, direction := range directions {
+                    if dMap.IsWalkable(geometry.Point{X: x, Y: y}.Add(direction.ToPoint())) {
+                        openNeighbors++
+                    }
+                }
+                if openNeighbors <= 1 {
+                    dMap.SetWall(x, y)
+                    removedSome = true
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,67 @@
+package dungen
+
+import (
+    "Legacy/geometry"
+    "math/rand"
+)
+
+// MineGenerator digs like a drunkard: a random walk through the rock, until enough of it is hollowed out.
+// Now and then the diggers widen a tunnel into a small chamber.
+type MineGenerator struct {
+    random        *rand.Rand
+    seed          int64
+    openFraction  float64
+    chamberChance float64
+}
+
+func NewMineGenerator(seed int64) *MineGenerator {
+    return &MineGenerator{
+        random:        rand.New(rand.NewSource(seed)),
+        seed:          seed,
+        openFraction:  0.35,
+        chamberChance: 0.02,
+    }
+}
+
+func (g *MineGenerator) Generate(width, height int) *DungeonMap {
+    g.random.Seed(g.seed)
+    dMap := NewDungeonMap(width, height)
+    directions := []geometry.CompassDirection{geometry.North, geometry.South, geometry.East, geometry.West}
+
+    openTilesNeeded := int(float64(width*height) * g.openFraction)
+    openTiles := 0
+    dig := func(pos geometry.Point, tile DungeonTile) {
+        if dMap.isOnBorder(pos) || dMap.GetTileAt(pos) != Wall {
+            return
+        }
+        dMap.tiles[pos.X+pos.Y*width] = tile
+        openTiles++
+    }
+
+    digger := geometry.Point{X: width / 2, Y: height / 2}
+    direction := directions[g.random.Intn(len(directions))]
+    dig(digger, Corridor)
+    // the step limit keeps tiny maps from digging forever
+    for steps := 0; openTiles < openTilesNeeded && steps < width*height*20; steps++ {
+        // tunnels tend to go straight
+        if g.random.Float64() < 0.3 {
+            direction = directions[g.random.Intn(len(directions))]
+        }
+        next := digger.Add(direction.ToPoint())
+        if dMap.isOnBorder(next) {
+            direction = direction.Opposite()
+            continue
+        }
+        digger = next
+        dig(digger, Corridor)
+        if g.random.Float64() < g.chamberChance {
+            for y := -1; y <= 1; y++ {
+                for x := -1; x <= 1; x++ {
+                    dig(digger.Add(geometry.Point{X: x, Y: y}), Room)
+                }
+            }
+        }
+    }
+    dMap.SetRoomsFromSectors(8)
+    return dMap
+}
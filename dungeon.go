@@ -0,0 +1,148 @@
+package main
+
+import (
+    "Legacy/dungen"
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "math/rand"
+    "path"
+    "slices"
+)
+
+const (
+    secretDoorChance     = 0.15
+    roomLightChance      = 0.3
+    roomMonsterChance    = 0.6
+    roomChestChance      = 0.25
+    prefabChance         = 0.5
+    nonHumanoidDeathIcon = int32(224)
+)
+
+var dungeonChestLoot = [][]game.Loot{
+    {game.LootGold},
+    {game.LootPotions, game.LootFood},
+    {game.LootWeapon},
+    {game.LootArmor},
+    {game.LootCommon, game.LootGold},
+    {game.LootScrolls},
+}
+
+// populateDungeon fills the rooms of a generated level. The entry room is left alone, so the party can arrive safely.
+// Everything is decided by the random source of the level, so the same seed gives the same dungeon.
+func (g *GridEngine) populateDungeon(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], random *rand.Rand, level int, rooms []*dungen.DungeonRoom, entryRoom, exitRoom *dungen.DungeonRoom) {
+    boss, hasBoss := game.GetBossForDepth(level)
+    if hasBoss {
+        g.placeBoss(dungeonMap, boss, level, exitRoom)
+    }
+    monsters := game.GetMonstersForDepth(level)
+    var contentRooms []*dungen.DungeonRoom
+    for _, room := range rooms {
+        if room == entryRoom {
+            continue
+        }
+        contentRooms = append(contentRooms, room)
+        floor := room.GetAbsoluteFloorTiles()
+        if random.Float64() < roomLightChance {
+            if pos, found := randomFreeFloor(dungeonMap, random, floor); found {
+                dungeonMap.AddItem(game.NewCandle(true), pos)
+            }
+        }
+        if random.Float64() < roomChestChance {
+            if pos, found := randomFreeFloor(dungeonMap, random, floor); found {
+                lootType := dungeonChestLoot[random.Intn(len(dungeonChestLoot))]
+                chest := game.NewChest(level, lootType)
+                // the luck of the party changes how often loot is rolled, so it gets its own source
+                lootRandom := rand.New(rand.NewSource(random.Int63()))
+                chest.SetFixedLoot(g.createLoot(lootRandom, level, lootType))
+                dungeonMap.AddObject(chest, pos)
+            }
+        }
+        if len(monsters) > 0 && (room != exitRoom || !hasBoss) && random.Float64() < roomMonsterChance {
+            monsterCount := 1 + random.Intn(g.rules.GetMaxMonstersPerRoom(level))
+            for i := 0; i < monsterCount; i++ {
+                if pos, found := randomFreeFloor(dungeonMap, random, floor); found {
+                    g.spawnMonster(dungeonMap, monsters[random.Intn(len(monsters))], g.rules.GetMonsterLevelForDepth(level), pos)
+                }
+            }
+        }
+    }
+    if len(contentRooms) == 0 {
+        return
+    }
+    hiddenRoom := contentRooms[random.Intn(len(contentRooms))]
+    if pos, found := randomFreeFloor(dungeonMap, random, hiddenRoom.GetAbsoluteFloorTiles()); found {
+        gold := game.NewPseudoItemFromTypeAndAmount(game.PseudoItemTypeGold, 10*level+random.Intn(10*level+1))
+        gold.SetHidden(true)
+        dungeonMap.AddItem(gold, pos)
+    }
+    g.placeTraps(dungeonMap, random, level, contentRooms)
+}
+
+// placeBoss puts the boss right next to the ladder down and its minions around it.
+func (g *GridEngine) placeBoss(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], boss game.DungeonBoss, level int, exitRoom *dungen.DungeonRoom) {
+    exitPos := exitRoom.Center()
+    floor := slices.Clone(exitRoom.GetAbsoluteFloorTiles())
+    slices.SortStableFunc(floor, func(a, b geometry.Point) int {
+        return geometry.DistanceManhattan(a, exitPos) - geometry.DistanceManhattan(b, exitPos)
+    })
+    var freeFloor []geometry.Point
+    for _, pos := range floor {
+        if isFreeDungeonFloor(dungeonMap, pos) {
+            freeFloor = append(freeFloor, pos)
+        }
+    }
+    if len(freeFloor) == 0 {
+        return
+    }
+    g.spawnMonster(dungeonMap, boss.Boss, g.rules.GetBossLevelForDepth(level), freeFloor[0])
+    for i := 1; i <= boss.MinionCount && i < len(freeFloor); i++ {
+        g.spawnMonster(dungeonMap, boss.Minion, g.rules.GetMonsterLevelForDepth(level), freeFloor[i])
+    }
+}
+
+func (g *GridEngine) placeTraps(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], random *rand.Rand, level int, rooms []*dungen.DungeonRoom) {
+    trapCount := g.rules.GetTrapCountForDepth(level)
+    for i := 0; i < trapCount; i++ {
+        room := rooms[random.Intn(len(rooms))]
+        if pos, found := randomFreeFloor(dungeonMap, random, room.GetAbsoluteFloorTiles()); found {
+            trapTypes := game.AllTrapTypes()
+            trapType := trapTypes[random.Intn(len(trapTypes))]
+            dungeonMap.AddObject(game.NewTrap(trapType, g.rules.GetTrapDamageForDepth(level), g.rules.GetTrapDifficultyForDepth(level)), pos)
+        }
+    }
+}
+
+func (g *GridEngine) spawnMonster(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], entry game.BestiaryEntry, level int, pos geometry.Point) {
+    monster := game.NewActorFromFile(mustOpen(path.Join("assets", "npc", entry.NpcName+".txt")), entry.Icon, g.gridRenderer.AutolayoutArrayToIconPages)
+    monster.SetInternalName("dungeon_" + entry.NpcName)
+    if monster.GetCreatureType() != game.CreatureTypeHuman {
+        monster.SetDeathIcon(nonHumanoidDeathIcon)
+    }
+    g.rules.PrepareAsMonster(monster, level)
+    monster.SetHomePosition(pos)
+    dungeonMap.AddActor(monster, pos)
+    g.onNPCMovedOrTeleported(dungeonMap, monster, pos)
+}
+
+func randomFreeFloor(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], random *rand.Rand, positions []geometry.Point) (geometry.Point, bool) {
+    var freeFloor []geometry.Point
+    for _, pos := range positions {
+        if isFreeDungeonFloor(dungeonMap, pos) {
+            freeFloor = append(freeFloor, pos)
+        }
+    }
+    if len(freeFloor) == 0 {
+        return geometry.Point{}, false
+    }
+    return freeFloor[random.Intn(len(freeFloor))], true
+}
+
+// isFreeDungeonFloor keeps the ladders and doorways clear.
+func isFreeDungeonFloor(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], pos geometry.Point) bool {
+    return dungeonMap.IsTileWalkable(pos) &&
+        !dungeonMap.IsObjectAt(pos) &&
+        !dungeonMap.IsItemAt(pos) &&
+        !dungeonMap.IsActorAt(pos) &&
+        !dungeonMap.IsNamedLocationAt(pos)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "Legacy/recfile"
+    "fmt"
+    "path"
+    "strconv"
+)
+
+const defaultDungeonBranch = "dungeon"
+
+// dungeonBranch describes a series of generated levels, as configured in assets/dungeons.txt.
+// Their maps are named after the branch, like !gen_mines_level_2.
+type dungeonBranch struct {
+    name              string
+    displayName       string
+    generator         string
+    width             int
+    height            int
+    firstLevel        int
+    maxLightIntensity float64
+    entryMap          string
+    entryLocation     string
+    wallIcon          int32
+    floorIcon         int32
+    prefabs           []string
+}
+
+func newDefaultDungeonBranch() dungeonBranch {
+    return dungeonBranch{
+        name:              defaultDungeonBranch,
+        displayName:       "Dungeon Level",
+        generator:         "accretion",
+        width:             32,
+        height:            32,
+        firstLevel:        1,
+        maxLightIntensity: 0.1,
+        entryMap:          "Edge_Town",
+        entryLocation:     "dungeon_entrance",
+        wallIcon:          80,
+        floorIcon:         36,
+    }
+}
+
+// entranceName is the named location on the entry map, where the party arrives when leaving the branch.
+func (b dungeonBranch) entranceName() string {
+    if _, err := geometry.NewPointFromEncodedString(b.entryLocation); err == nil {
+        return b.name + "_entrance"
+    }
+    return b.entryLocation
+}
+
+func loadDungeonBranches() map[string]dungeonBranch {
+    branches := map[string]dungeonBranch{defaultDungeonBranch: newDefaultDungeonBranch()}
+    filename := path.Join("assets", "dungeons.txt")
+    if !doesFileExist(filename) {
+        return branches
+    }
+    file := mustOpen(filename)
+    records := recfile.Read(file)
+    _ = file.Close()
+    for _, record := range records {
+        branch := newDefaultDungeonBranch()
+        for _, field := range record {
+            switch field.Name {
+            case "Name":
+                branch.name = field.Value
+            case "DisplayName":
+                branch.displayName = field.Value
+            case "Generator":
+                branch.generator = field.Value
+            case "Width":
+                branch.width = field.AsInt()
+            case "Height":
+                branch.height = field.AsInt()
+            case "FirstLevel":
+                branch.firstLevel = field.AsInt()
+            case "MaxLightIntensity":
+                branch.maxLightIntensity, _ = strconv.ParseFloat(field.Value, 64)
+            case "EntryMap":
+                branch.entryMap = field.Value
+            case "EntryLocation":
+                branch.entryLocation = field.Value
+            case "WallIcon":
+                branch.wallIcon = field.AsInt32()
+            case "FloorIcon":
+                branch.floorIcon = field.AsInt32()
+            case "Prefab":
+                branch.prefabs = append(branch.prefabs, field.Value)
+            }
+        }
+        branches[branch.name] = branch
+    }
+    return branches
+}
+
+func (g *GridEngine) getDungeonBranch(name string) dungeonBranch {
+    if branch, isKnown := g.dungeonBranches[name]; isKnown {
+        return branch
+    }
+    println(fmt.Sprintf("ERROR: unknown dungeon branch '%s'", name))
+    return newDefaultDungeonBranch()
+}
+
+// addDungeonEntrances puts the ladders down into the generated branches.
+// An entry location given as position gets a new ladder, named locations are used as they are.
+func (g *GridEngine) addDungeonEntrances(loadedMap *gridmap.GridMap[*game.Actor, game.Item, game.Object]) {
+    for _, branch := range g.dungeonBranches {
+        if branch.entryMap != loadedMap.GetName() {
+            continue
+        }
+        entrancePos, isPosition := loadedMap.NamedLocations[branch.entryLocation], false
+        if pos, err := geometry.NewPointFromEncodedString(branch.entryLocation); err == nil {
+            entrancePos, isPosition = pos, true
+        } else if !loadedMap.IsNamedLocationAt(entrancePos) {
+            println(fmt.Sprintf("ERROR: no location '%s' for the entrance to '%s'", branch.entryLocation, branch.name))
+            continue
+        }
+        if isPosition {
+            loadedMap.SetTile(entrancePos, ladderDownTile)
+            loadedMap.AddNamedLocation(branch.entranceName(), entrancePos)
+        }
+        loadedMap.AddTransitionAt(entrancePos, gridmap.Transition{
+            TargetMap:      levelName(branch.name, defaultDungeonSeed, branch.firstLevel),
+            TargetLocation: "ladder_up",
+        })
+    }
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "fmt"
+)
+
+const (
+    fireIcon        = int32(113)
+    smokeIcon       = int32(95)
+    burntGroundIcon = int32(106)
+)
+
+// IgniteAt sets the tile on fire, if there is something to burn.
+// Anyone standing there catches fire either way.
+func (g *GridEngine) IgniteAt(pos geometry.Point) {
+    if g.currentMap.Ignite(pos) {
+        g.flags.IncrementFlag("fires_started")
+    }
+    if g.currentMap.IsActorAt(pos) {
+        g.setOnFire(g.currentMap.ActorAt(pos))
+    }
+}
+
+func (g *GridEngine) ExtinguishAt(pos geometry.Point) {
+    g.currentMap.Extinguish(pos)
+    if g.currentMap.IsActorAt(pos) {
+        g.currentMap.ActorAt(pos).RemoveStatusEffect(g, game.StatusEffectNameBurning)
+    }
+}
+
+func (g *GridEngine) setOnFire(actor *game.Actor) {
+    if !actor.IsAlive() || g.currentMap.GetCell(actor.Pos()).TileType.IsWater() {
+        return
+    }
+    g.AddStatusEffect(actor, game.StatusBurning(), 1)
+}
+
+// tickEnvironment lets fire spread and smoke clear.
+// Called once per combat round and once per minute of world time outside of combat.
+func (g *GridEngine) tickEnvironment() {
+    for _, actor := range g.currentMap.Actors() {
+        if actor.HasStatusEffectWithName(game.StatusEffectNameBurning) && g.currentMap.GetCell(actor.Pos()).TileType.IsWater() {
+            actor.RemoveStatusEffect(g, game.StatusEffectNameBurning)
+            g.Print(fmt.Sprintf("The water puts out the flames on '%s'", actor.Name()))
+        }
+    }
+    if !g.currentMap.HasActiveEnvironment() {
+        return
+    }
+    changes := g.currentMap.TickEnvironment()
+    for _, pos := range changes.BurnedOut {
+        g.burnOutAt(pos)
+    }
+    for _, pos := range changes.Burning {
+        if g.currentMap.IsActorAt(pos) {
+            g.setOnFire(g.currentMap.ActorAt(pos))
+        }
+    }
+    g.updateLighting()
+    g.currentMap.UpdateFieldOfView(g.playerParty.GetFoV(), g.GetAvatar().Pos())
+}
+
+// burnOutAt leaves burnt ground behind. Doors burn down.
+func (g *GridEngine) burnOutAt(pos geometry.Point) {
+    if g.currentMap.IsObjectAt(pos) {
+        g.RemoveDoorAt(pos)
+    }
+    currentTile := g.currentMap.GetCell(pos).TileType
+    if currentTile.IsForest() {
+        burntTile := currentTile.WithIcon(burntGroundIcon).
+            WithIsWalkable(true).
+            WithIsTransparent(true).
+            WithSpecial(gridmap.SpecialTileNone)
+        g.currentMap.SetTile(pos, burntTile)
+    }
+}
@@ -8,7 +8,6 @@ import (
     "Legacy/util"
     "fmt"
     "image/color"
-    "math/rand"
     "sort"
     "strconv"
 )
@@ -52,6 +51,12 @@ func (g *GridEngine) openPartyMenu() {
                 g.openActiveSkillsMenu(g.GetAvatar(), g.GetAvatar().GetActiveSkills())
             },
         },
+        {
+            Text: "Quick Slots",
+            Action: func() {
+                g.openQuickSlotMenu(g.GetAvatar())
+            },
+        },
         {
             Text:   "Rest",
             Action: g.TryRestParty,
@@ -72,6 +77,11 @@ func (g *GridEngine) openPartyMenu() {
         }
         //insert at index 3
         partyOptions = append(partyOptions[:3], append([]util.MenuItem{partyRanged}, partyOptions[3:]...)...)
+        partyOptions = append(partyOptions, util.MenuItem{
+            Text:        "Formation",
+            Action:      g.openFormationMenu,
+            TooltipText: []string{"How the party lines up, when a fight starts."},
+        })
         partyOptions = append(partyOptions, util.MenuItem{
             Text: "Split",
             Action: func() {
@@ -371,6 +381,11 @@ func (g *GridEngine) ForceJoinParty() {
     g.onViewedActorMoved(g.GetAvatar().Pos())
 }
 func (g *GridEngine) openVendorMenu(npc *game.Actor) { // TODO: replace with usage of the dialogue system
+    if !npc.IsOpenForBusiness(g.worldTime) {
+        g.conversationModal.SetText(oneLine(fmt.Sprintf("We are closed. Come back between %s.", npc.GetSchedule().GetOpeningHours())))
+        g.conversationModal.SetVendorOptions(nil)
+        return
+    }
     itemsToSell := npc.GetItemsToSell()
     if len(itemsToSell) == 0 {
         g.conversationModal.SetText(oneLine("I have nothing to sell."))
@@ -448,6 +463,46 @@ func (g *GridEngine) openTrainerMenu(npc *game.Actor, maxLevel int) {
     g.conversationModal.OnMouseMoved(g.lastMousePosX, g.lastMousePosY)
 }
 
+func (g *GridEngine) openRechargeMenu(npc *game.Actor) {
+    var wands []*game.Wand
+    for _, item := range g.playerParty.GetFlatInventory() {
+        if wand, isWand := item.(*game.Wand); isWand && wand.NeedsRecharge() {
+            wands = append(wands, wand)
+        }
+    }
+
+    if len(wands) == 0 {
+        g.conversationModal.SetText(oneLine("Your wands are all fully charged."))
+        g.conversationModal.SetVendorOptions(nil)
+        return
+    }
+
+    var menuItems []util.MenuItem
+    for _, w := range wands {
+        wand := w
+        price := wand.GetRechargePrice()
+        itemLine := fmt.Sprintf("%s (%d/%d) %dg", wand.Name(), wand.GetCharges(), wand.GetMaxCharges(), price)
+        menuItems = append(menuItems, util.MenuItem{
+            Text:      itemLine,
+            CharIcon:  wand.InventoryIcon(),
+            TextColor: wand.TintColor(),
+            Action: func() {
+                if g.playerParty.HasGold(price) {
+                    g.playerParty.RemoveGold(price)
+                    npc.AddGold(price)
+                    wand.Recharge()
+                    g.openRechargeMenu(npc)
+                } else {
+                    g.conversationModal.SetText(oneLine("You don't have enough gold."))
+                    g.conversationModal.SetVendorOptions(nil)
+                }
+            },
+        })
+    }
+    g.conversationModal.SetVendorOptions(menuItems)
+    g.conversationModal.OnMouseMoved(g.lastMousePosX, g.lastMousePosY)
+}
+
 func getLineLengthInfoItems(sell []game.SalesOffer) (int, int) {
     var longestItemNameLength, longestPriceLength int
     for _, i := range sell {
@@ -621,10 +676,16 @@ func (g *GridEngine) GetPartySize() int {
 }
 
 func (g *GridEngine) CreateLootForContainer(level int, lootType []game.Loot) []game.Item {
+    return g.createLoot(game.GlobalRandom, level, lootType)
+}
+
+// createLoot rolls the contents of a container. Generated levels pass their own random source.
+func (g *GridEngine) createLoot(random game.Random, level int, lootType []game.Loot) []game.Item {
     var lootFound []game.Item
+    luck := g.playerParty.GetBestLuck()
     for _, loot := range lootType {
         var lootItems []game.Item
-        randFloat := rand.Float64()
+        randFloat := g.rules.RollLootQuality(random, luck)
         switch loot {
         case game.LootLockpicks:
             lockpickAmount := max(level, int(float64(level)*3*randFloat))
@@ -640,10 +701,15 @@ func (g *GridEngine) CreateLootForContainer(level int, lootType []game.Loot) []g
             lootItems = g.createPotions(potionAmount)
         case game.LootArmor:
             armorAmount := max(1, int(float64(level)*randFloat))
-            lootItems = g.createArmorForLoot(level, armorAmount)
+            lootItems = g.createArmorForLoot(random, level+g.rules.GetLootLevelBonus(random, luck), armorAmount)
         case game.LootWeapon:
             weaponAmount := max(1, int(float64(level)*randFloat))
-            lootItems = g.createWeaponsForLoot(level, weaponAmount)
+            lootItems = g.createWeaponsForLoot(random, level+g.rules.GetLootLevelBonus(random, luck), weaponAmount)
+        case game.LootCommon:
+            throwableAmount := max(1, int(float64(level)*randFloat))
+            lootItems = g.createThrowablesForLoot(random, throwableAmount)
+        case game.LootScrolls:
+            lootItems = []game.Item{game.NewRandomWand(random, level)}
         }
         lootFound = append(lootFound, lootItems...)
     }
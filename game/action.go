@@ -31,7 +31,10 @@ type BaseAction struct {
     canPayCost        func(engine Engine, user *Actor) bool
     payCost           func(engine Engine, user *Actor)
     getValidPositions func(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool
+    affectedPositions func(engine Engine, user *Actor, target geometry.Point) []geometry.Point
+    hitPreview        func(engine Engine, user *Actor, victim *Actor) (float64, int)
     description       []string
+    projectileIcon    int32
 
     // AI stuff
     targetedCombatUtility func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int
@@ -76,6 +79,41 @@ func (s *BaseAction) ExecuteOnTarget(engine Engine, caster *Actor, pos geometry.
 func (s *BaseAction) GetValidTargets(engine Engine, caster *Actor, usePosition geometry.Point) map[geometry.Point]bool {
     return s.getValidPositions(engine, caster, usePosition)
 }
+
+// GetAffectedPositions returns all positions that will be affected, when the action is used on the target.
+func (s *BaseAction) GetAffectedPositions(engine Engine, caster *Actor, target geometry.Point) []geometry.Point {
+    if s.affectedPositions == nil {
+        return []geometry.Point{target}
+    }
+    return s.affectedPositions(engine, caster, target)
+}
+
+// GetHitPreview returns the chance to hit the victim and the damage dealt on a hit.
+func (s *BaseAction) GetHitPreview(engine Engine, caster *Actor, victim *Actor) (float64, int, bool) {
+    if s.hitPreview == nil {
+        return 0, 0, false
+    }
+    chance, damage := s.hitPreview(engine, caster, victim)
+    return chance, damage, true
+}
+
+func (s *BaseAction) SetAffectedPositions(affected func(engine Engine, user *Actor, target geometry.Point) []geometry.Point) {
+    s.affectedPositions = affected
+}
+
+func (s *BaseAction) SetHitPreview(preview func(engine Engine, user *Actor, victim *Actor) (float64, int)) {
+    s.hitPreview = preview
+}
+
+func (s *BaseAction) SetProjectileIcon(icon int32) {
+    s.projectileIcon = icon
+}
+
+// GetProjectileIcon returns the icon that flies to the target, if the action throws something.
+func (s *BaseAction) GetProjectileIcon() (int32, bool) {
+    return s.projectileIcon, s.projectileIcon != 0
+}
+
 func (s *BaseAction) IsTargeted() bool {
     return s.targetedEffect != nil && s.effect == nil
 }
@@ -137,6 +175,9 @@ type Action interface {
     Execute(engine Engine, caster *Actor)
     ExecuteOnTarget(engine Engine, caster *Actor, pos geometry.Point)
     GetValidTargets(engine Engine, caster *Actor, usePosition geometry.Point) map[geometry.Point]bool
+    GetAffectedPositions(engine Engine, caster *Actor, target geometry.Point) []geometry.Point
+    GetHitPreview(engine Engine, caster *Actor, victim *Actor) (float64, int, bool)
+    GetProjectileIcon() (int32, bool)
     CanPayCost(engine Engine, member *Actor) bool
     IsTargeted() bool
     GetValue() int
@@ -135,6 +135,8 @@ type Actor struct {
     // magic slots
     equippedScrolls []*Scroll
 
+    quickSlots [QuickSlotCount]QuickSlot
+
     internalName string
     isHuman      bool
 
@@ -150,16 +152,31 @@ type Actor struct {
     attributes   AttributeHolder
     skillset     SkillSet
     innateSkills []*BaseAction
+    knownSpells  []*Spell
 
     color                 color.Color
     isTinted              bool
     combatFaction         string
     originalCombatFaction string
     isAggressive          bool
+    isGuard               bool
+    combatBehavior        CombatBehavior
+    baseMorale            int
+    canSurrender          bool
+    creatureType          CreatureType
+    isStatusTinted        bool
     engagementRange       int
     statusEffects         map[StatusEffectName]StatusEffect
     deathIcon             int32
     zoneOfEngagement      map[geometry.Point]int
+
+    companionKind          CompanionKind
+    companionRoundsLeft    int
+    companionFormerFaction string
+
+    schedule     *Schedule
+    homePosition geometry.Point
+    patrol       *Patrol
 }
 
 func NewActor(name string, icon int32) *Actor {
@@ -191,6 +208,7 @@ func NewActorFromFile(file io.ReadCloser, icon int32, toPages func(height int, i
     conversation := NewDialogueFromRecords(actorData["Conversation"], toPages)
 
     health, _ := coreRecord.GetInt("Health")
+    mana, _ := coreRecord.GetInt("Mana")
     description := coreRecord["Description"]
 
     newActor := &Actor{
@@ -198,6 +216,7 @@ func NewActorFromFile(file io.ReadCloser, icon int32, toPages func(height int, i
         icon:                icon,
         health:              health,
         maxHealth:           health,
+        mana:                mana,
         description:         description,
         dialogue:            conversation,
         isHuman:             true,
@@ -221,16 +240,46 @@ func NewActorFromFile(file io.ReadCloser, icon int32, toPages func(height int, i
         newActor.equippedRightHand = NewWeaponFromPredicate(recfile.StrPredicate(weaponString))
     }
 
+    if weaponString, hasWeapon := coreRecord["Ranged"]; hasWeapon {
+        newActor.equippedRanged = NewWeaponFromPredicate(recfile.StrPredicate(weaponString))
+    }
+
+    if behavior, hasBehavior := coreRecord["Behavior"]; hasBehavior {
+        newActor.combatBehavior = CombatBehaviorFromString(behavior)
+    }
+
+    if morale, err := coreRecord.GetInt("Morale"); err == nil {
+        newActor.baseMorale = morale
+    }
+
+    if surrenders, hasSurrender := coreRecord["Surrenders"]; hasSurrender {
+        newActor.canSurrender = recfile.StrBool(surrenders)
+    }
+
+    if creatureType, hasCreatureType := coreRecord["CreatureType"]; hasCreatureType {
+        newActor.creatureType = CreatureTypeFromString(creatureType)
+    }
+
     if recordsForInventory, hasInventory := actorData["Inventory"]; hasInventory && len(recordsForInventory) > 0 {
         inventory := recordsForInventory[0].ToValueList()
         newActor.inventory = toInventory(newActor, itemsFromStrings(inventory))
     }
 
+    if recordsForSchedule, hasSchedule := actorData["Schedule"]; hasSchedule && len(recordsForSchedule) > 0 {
+        newActor.schedule = NewScheduleFromRecords(recordsForSchedule)
+    }
+
+    if recordsForPatrol, hasPatrol := actorData["Patrol"]; hasPatrol && len(recordsForPatrol) > 0 {
+        newActor.patrol = NewPatrolFromRecord(recordsForPatrol[0])
+    }
+
     if recordForSkills, hasSkills := actorData["Skills"]; hasSkills && len(recordForSkills) > 0 {
         skillRecord := recordForSkills[0]
         for _, field := range skillRecord {
             if field.Name == "ActiveSkill" {
                 newActor.AddInnateSkill(NewActiveSkillFromName(SkillName(field.Value)))
+            } else if field.Name == "Spell" {
+                newActor.LearnSpell(NewSpellFromName(field.Value))
             }
         }
     }
@@ -260,6 +309,11 @@ func NewActorFromRecord(record recfile.Record) *Actor {
         deathIcon:           24,
         //dialogue:        NewDialogueFromRecords(actorData["Conversation"]),
     }
+    var encodedPatrol string
+    var patrolWaypoints []geometry.Point
+    hasSchedule, scheduleActive := false, -1
+    var scheduleEntries []ScheduleEntry
+    var scheduleWaypoints []geometry.Point
     for _, field := range record {
         switch field.Name {
         case "name":
@@ -274,6 +328,16 @@ func NewActorFromRecord(record recfile.Record) *Actor {
             a.iconFrameCount = field.AsInt()
         case "isHuman":
             a.isHuman = field.AsBool()
+        case "isGuard":
+            a.isGuard = field.AsBool()
+        case "behavior":
+            a.combatBehavior = CombatBehaviorFromString(field.Value)
+        case "morale":
+            a.baseMorale = field.AsInt()
+        case "surrenders":
+            a.canSurrender = field.AsBool()
+        case "creatureType":
+            a.creatureType = CreatureTypeFromString(field.Value)
         case "health":
             a.health = field.AsInt()
         case "maxhealth":
@@ -284,8 +348,32 @@ func NewActorFromRecord(record recfile.Record) *Actor {
             a.mana = field.AsInt()
         case "description":
             a.description = field.Value
+        case "quickSlot":
+            if index, slot, ok := decodeQuickSlot(field.Value); ok {
+                a.quickSlots[index] = slot
+            }
+        case "patrol":
+            encodedPatrol = field.Value
+        case "patrolWaypoint":
+            patrolWaypoints = append(patrolWaypoints, geometry.MustDecodePoint(field.Value))
+        case "homePosition":
+            a.homePosition = geometry.MustDecodePoint(field.Value)
+        case "scheduleActive":
+            hasSchedule, scheduleActive = true, field.AsInt()
+        case "scheduleEntry":
+            if entry, ok := decodeScheduleEntry(field.Value); ok {
+                scheduleEntries = append(scheduleEntries, entry)
+            }
+        case "scheduleWaypoint":
+            scheduleWaypoints = append(scheduleWaypoints, geometry.MustDecodePoint(field.Value))
         }
     }
+    if encodedPatrol != "" {
+        a.patrol = newPatrolFromEncoded(encodedPatrol, patrolWaypoints)
+    }
+    if hasSchedule {
+        a.schedule = newScheduleFromEncoded(scheduleActive, scheduleEntries, scheduleWaypoints)
+    }
     return a
 }
 
@@ -298,6 +386,11 @@ func (a *Actor) ToRecord() recfile.Record {
         recfile.Field{Name: "iconFrames", Value: strconv.Itoa(a.GetIconFrameCount())},
 
         recfile.Field{Name: "isHuman", Value: recfile.BoolStr(a.IsHuman())},
+        recfile.Field{Name: "isGuard", Value: recfile.BoolStr(a.IsGuard())},
+        recfile.Field{Name: "behavior", Value: string(a.GetCombatBehavior())},
+        recfile.Field{Name: "morale", Value: strconv.Itoa(a.baseMorale)},
+        recfile.Field{Name: "surrenders", Value: recfile.BoolStr(a.CanSurrender())},
+        recfile.Field{Name: "creatureType", Value: string(a.GetCreatureType())},
 
         recfile.Field{Name: "health", Value: strconv.Itoa(a.GetHealth())},
         recfile.Field{Name: "maxhealth", Value: strconv.Itoa(a.GetMaxHealth())},
@@ -306,6 +399,7 @@ func (a *Actor) ToRecord() recfile.Record {
         recfile.Field{Name: "mana", Value: strconv.Itoa(a.GetMana())},
 
         recfile.Field{Name: "description", Value: a.description},
+        recfile.Field{Name: "homePosition", Value: a.homePosition.Encode()},
         // TODO: dialogue state, attributes & skills, equipment
 
     }
@@ -317,6 +411,13 @@ func (a *Actor) ToRecord() recfile.Record {
             actorRecord = append(actorRecord, recfile.Field{Name: "d_prev", Value: keyword})
         }
     }
+    actorRecord = append(actorRecord, a.quickSlotsToRecord()...)
+    if a.HasPatrol() {
+        actorRecord = append(actorRecord, a.patrol.toRecord()...)
+    }
+    if a.HasSchedule() {
+        actorRecord = append(actorRecord, a.schedule.toRecord()...)
+    }
     return actorRecord
 }
 
@@ -363,7 +464,7 @@ func (a *Actor) Unequip(item Item) {
 
 func (a *Actor) OnAddedToParty(party *Party) {
     a.party = party
-    a.SetCombatFaction("_player_party")
+    a.SetCombatFaction(PartyCombatFaction)
 }
 
 func (a *Actor) OnRemovedFromParty() {
@@ -859,6 +960,35 @@ func (a *Actor) GetMeleeDamage() int {
     return baseDamage
 }
 
+func (a *Actor) GetMeleeDamageType() DamageType {
+    for _, handheld := range []Handheld{a.equippedRightHand, a.equippedLeftHand} {
+        if weapon, ok := handheld.(*Weapon); ok {
+            return weapon.GetDamageType()
+        }
+    }
+    return DamageTypeBlunt
+}
+
+func (a *Actor) GetRangedDamageType() DamageType {
+    if a.equippedRanged != nil {
+        return a.equippedRanged.GetDamageType()
+    }
+    return DamageTypePierce
+}
+
+// GetResistances sums up the resistances of the creature type and the equipped armor.
+func (a *Actor) GetResistances() Resistances {
+    resistances := Resistances{}
+    resistances.Add(a.GetCreatureType().GetResistances())
+    for _, armor := range a.equippedArmor {
+        resistances.Add(armor.GetResistances())
+    }
+    for _, accessory := range a.equippedAccessories {
+        resistances.Add(accessory.GetResistances())
+    }
+    return resistances
+}
+
 func (a *Actor) GetRangedDamage() int {
     baseDamage := a.attributes.GetBaseRangedDamage()
     if a.equippedRanged != nil {
@@ -880,6 +1010,10 @@ func (a *Actor) DropInventory() []Item {
     return dropped
 }
 
+func (a *Actor) GetInitiative() int {
+    return a.attributes.GetInitiative()
+}
+
 func (a *Actor) GetMovementAllowance() int {
     encumberance := a.GetTotalEncumbrance()
     return a.attributes.GetMovementAllowance(encumberance)
@@ -889,6 +1023,11 @@ func (a *Actor) SetHealth(health int) {
     a.health = health
 }
 
+// Heal restores health, up to the maximum.
+func (a *Actor) Heal(amount int) {
+    a.health = min(a.health+amount, a.maxHealth)
+}
+
 func (a *Actor) SetMana(mana int) {
     a.mana = mana
 }
@@ -1048,10 +1187,57 @@ func (a *Actor) GetEquippedSpells() []Action {
     for _, scroll := range a.equippedScrolls {
         spells = append(spells, scroll.spell)
     }
+    for _, spell := range a.knownSpells {
+        spells = append(spells, spell)
+    }
     return spells
 
 }
 
+// GetConsumableActions returns an action for each potion, wand and throwable the actor carries.
+func (a *Actor) GetConsumableActions() []Action {
+    return consumableActionsOf(a.inventory)
+}
+
+// consumableActionsOf returns one action for every kind of consumable in the items.
+// Equipped wands are left out, their spells are already part of the active skills.
+func consumableActionsOf(items []Item) []Action {
+    var actions []Action
+    var kindsSeen []Item
+    for _, item := range items {
+        if isStackableWithAny(kindsSeen, item) {
+            continue
+        }
+        switch consumable := item.(type) {
+        case *Potion:
+            if consumable.IsEmpty() {
+                continue
+            }
+            actions = append(actions, consumable.NewDrinkAction())
+        case *Wand:
+            if consumable.IsEquipped() {
+                continue
+            }
+            actions = append(actions, consumable.GetEmbeddedActions()...)
+        case *Throwable:
+            actions = append(actions, consumable.NewThrowAction())
+        default:
+            continue
+        }
+        kindsSeen = append(kindsSeen, item)
+    }
+    return actions
+}
+
+func isStackableWithAny(items []Item, item Item) bool {
+    for _, other := range items {
+        if other.CanStackWith(item) {
+            return true
+        }
+    }
+    return false
+}
+
 func (a *Actor) GetActiveSkills() []Action {
     var skills []Action
     // todo: add skills from items, etc.
@@ -1066,6 +1252,9 @@ func (a *Actor) GetActiveSkills() []Action {
     if rightItem != nil {
         skills = append(skills, rightItem.GetEmbeddedActions()...)
     }
+    if a.equippedRanged != nil {
+        skills = append(skills, a.equippedRanged.GetEmbeddedActions()...)
+    }
     return skills
 }
 
@@ -1147,6 +1336,10 @@ func (a *Actor) GetCombatFaction() string {
     return a.combatFaction
 }
 
+func (a *Actor) IsAllyOf(other *Actor) bool {
+    return a.combatFaction == other.combatFaction
+}
+
 func (a *Actor) RestoreOriginalCombatFaction() {
     if a.originalCombatFaction != "" {
         a.combatFaction = a.originalCombatFaction
@@ -1185,6 +1378,64 @@ func (a *Actor) IsAggressive() bool {
     return a.isAggressive
 }
 
+func (a *Actor) SetGuard(isGuard bool) {
+    a.isGuard = isGuard
+}
+
+func (a *Actor) IsGuard() bool {
+    return a.isGuard
+}
+
+func (a *Actor) GetCombatBehavior() CombatBehavior {
+    if a.combatBehavior == "" {
+        return CombatBehaviorSoldier
+    }
+    return a.combatBehavior
+}
+
+func (a *Actor) SetCombatBehavior(behavior CombatBehavior) {
+    a.combatBehavior = behavior
+}
+
+// GetBaseMorale returns the morale of this actor at full health.
+func (a *Actor) GetBaseMorale() int {
+    if a.baseMorale > 0 {
+        return a.baseMorale
+    }
+    return a.GetCombatBehavior().BaseMorale()
+}
+
+func (a *Actor) CanSurrender() bool {
+    return a.canSurrender
+}
+
+func (a *Actor) SetCanSurrender(canSurrender bool) {
+    a.canSurrender = canSurrender
+}
+
+// GetCreatureType defaults to human for humans and to other for everything else.
+// Anyone raised from the dead is undead.
+func (a *Actor) GetCreatureType() CreatureType {
+    if a.HasStatusEffectWithName(StatusEffectNameUndead) {
+        return CreatureTypeUndead
+    }
+    if a.creatureType == "" {
+        if a.isHuman {
+            return CreatureTypeHuman
+        }
+        return CreatureTypeOther
+    }
+    return a.creatureType
+}
+
+func (a *Actor) SetCreatureType(creatureType CreatureType) {
+    a.creatureType = creatureType
+}
+
+func (a *Actor) IsImmuneTo(effectName StatusEffectName) bool {
+    return a.GetCreatureType().IsImmuneTo(effectName)
+}
+
 func (a *Actor) GetNPCEngagementRange() int {
     return a.engagementRange
 }
@@ -1207,6 +1458,43 @@ func (a *Actor) OnRangedHitPerformed(engine Engine, victim *Actor) {
     }
 }
 
+func (a *Actor) OnNewCombatTurn(engine Engine) {
+    for effectName, effect := range a.statusEffects {
+        if combatEffect, ok := effect.(CombatEffect); ok {
+            combatEffect.OnNewTurn(engine, a)
+            if effect.IsExpired() {
+                a.RemoveStatusEffect(engine, effectName)
+            }
+        }
+    }
+}
+
+// OnWorldTimePassed ticks the status effects once for every minute that passed outside of combat.
+func (a *Actor) OnWorldTimePassed(engine Engine, minutes int) {
+    for i := 0; i < minutes && len(a.statusEffects) > 0; i++ {
+        for effectName, effect := range a.statusEffects {
+            if worldTimeEffect, ok := effect.(WorldTimeEffect); ok {
+                worldTimeEffect.OnMinutePassed(engine, a)
+                if effect.IsExpired() {
+                    a.RemoveStatusEffect(engine, effectName)
+                }
+            }
+        }
+    }
+}
+
+// OnAttackPerformed is called whenever the actor attacks, hit or miss.
+func (a *Actor) OnAttackPerformed(engine Engine) {
+    for effectName, effect := range a.statusEffects {
+        if onAttackEffect, ok := effect.(OnAttackEffect); ok {
+            onAttackEffect.OnAttack(engine, a)
+            if effect.IsExpired() {
+                a.RemoveStatusEffect(engine, effectName)
+            }
+        }
+    }
+}
+
 func (a *Actor) OnDamageReceived(engine Engine, amount int) {
     if amount <= 0 {
         return
@@ -1249,6 +1537,15 @@ func (a *Actor) GetMeleeWeapon() *Weapon {
     return nil
 }
 
+func (a *Actor) HasShieldEquipped() bool {
+    for _, item := range []Handheld{a.equippedLeftHand, a.equippedRightHand} {
+        if weapon, ok := item.(*Weapon); ok && weapon.IsShield() {
+            return true
+        }
+    }
+    return false
+}
+
 func (a *Actor) GetRangedWeapon() *Weapon {
     if a.equippedRanged != nil {
         return a.equippedRanged
@@ -1257,6 +1554,9 @@ func (a *Actor) GetRangedWeapon() *Weapon {
 }
 
 func (a *Actor) AddStatusEffect(engine Engine, statusEffect StatusEffect) {
+    if a.IsImmuneTo(statusEffect.Name()) {
+        return
+    }
     if a.HasStatusEffectWithName(statusEffect.Name()) {
         a.statusEffects[statusEffect.Name()].OnReapply(engine, a)
         return
@@ -1285,13 +1585,68 @@ func (a *Actor) AddStatusEffect(engine Engine, statusEffect StatusEffect) {
             )
         }
     }
+    a.updateStatusTint()
+}
+
+// updateStatusTint colors the actor like the first status effect that has a tint.
+func (a *Actor) updateStatusTint() {
+    for _, effectName := range a.getSortedStatusEffectNames() {
+        if tintingEffect, isTinting := a.statusEffects[effectName].(TintingEffect); isTinting {
+            a.SetTintColor(tintingEffect.TintColor())
+            a.SetTinted(true)
+            a.isStatusTinted = true
+            return
+        }
+    }
+    if a.isStatusTinted {
+        a.SetTintColor(color.White)
+        a.SetTinted(false)
+        a.isStatusTinted = false
+    }
+}
+
+func (a *Actor) getSortedStatusEffectNames() []StatusEffectName {
+    var nameOrder []StatusEffectName
+    for effectName, _ := range a.statusEffects {
+        nameOrder = append(nameOrder, effectName)
+    }
+    sort.SliceStable(nameOrder, func(i, j int) bool {
+        return nameOrder[i] < nameOrder[j]
+    })
+    return nameOrder
+}
 
+// GetStatusIndicator returns the character and color for the status bar, if any status effect wants to be shown.
+func (a *Actor) GetStatusIndicator() (rune, color.Color, bool) {
+    for _, effectName := range a.getSortedStatusEffectNames() {
+        if indicatorEffect, hasIndicator := a.statusEffects[effectName].(IndicatorEffect); hasIndicator {
+            char, charColor := indicatorEffect.Indicator()
+            return char, charColor, true
+        }
+    }
+    return 0, nil, false
 }
 
 func (a *Actor) IsSleeping() bool {
     return a.HasStatusEffectWithName(StatusEffectNameSleeping)
 }
 
+func (a *Actor) IsStunned() bool {
+    return a.HasStatusEffectWithName(StatusEffectNameStunned)
+}
+
+func (a *Actor) IsFrightened() bool {
+    return a.HasStatusEffectWithName(StatusEffectNameFrightened)
+}
+
+func (a *Actor) IsBlinded() bool {
+    return a.HasStatusEffectWithName(StatusEffectNameBlinded)
+}
+
+func (a *Actor) IsInvisible() bool {
+    return a.HasStatusEffectWithName(StatusEffectNameInvisible)
+}
+
 func (a *Actor) HasStatusEffectWithName(statusEffectName StatusEffectName) bool {
     _, hasEffect := a.statusEffects[statusEffectName]
     return hasEffect
@@ -1318,6 +1673,7 @@ func (a *Actor) RemoveStatusEffect(engine Engine, effectName StatusEffectName) {
     if existingEffect, ok := a.statusEffects[effectName]; ok {
         existingEffect.OnRemove(engine, a)
         delete(a.statusEffects, effectName)
+        a.updateStatusTint()
     }
 }
 
@@ -1370,9 +1726,14 @@ func (a *Actor) GetDebugInfos() []string {
         fmt.Sprintf("Armor: %d", a.GetTotalArmor()),
         fmt.Sprintf("Melee: %d", a.GetMeleeDamage()),
         fmt.Sprintf("Ranged: %d", a.GetRangedDamage()),
+        fmt.Sprintf("CreatureType: %s", a.GetCreatureType()),
         fmt.Sprintf("CombatFaction: %s", a.combatFaction),
         fmt.Sprintf("IsAggressive: %t", a.isAggressive),
     }
+    if resistanceRows := a.GetResistances().TooltipRows(); len(resistanceRows) > 0 {
+        infos = append(infos, "Resistances:")
+        infos = append(infos, util.TableLayout(resistanceRows)...)
+    }
     if len(a.innateSkills) > 0 {
         infos = append(infos, "Skills:")
         for _, skill := range a.innateSkills {
@@ -1392,14 +1753,7 @@ func (a *Actor) HasStatusEffects() bool {
 
 func (a *Actor) GetStatusEffectsTable() []string {
     var table []string
-    var nameOrder []StatusEffectName
-    for effectName, _ := range a.statusEffects {
-        nameOrder = append(nameOrder, effectName)
-    }
-    sort.SliceStable(nameOrder, func(i, j int) bool {
-        return nameOrder[i] < nameOrder[j]
-    })
-    for index, effectName := range nameOrder {
+    for index, effectName := range a.getSortedStatusEffectNames() {
         effect := a.statusEffects[effectName]
         if index != 0 {
             table = append(table, "")
@@ -1414,6 +1768,14 @@ func (a *Actor) AddInnateSkill(skill *BaseAction) {
     a.innateSkills = append(a.innateSkills, skill)
 }
 
+// LearnSpell lets the actor cast the spell without having the scroll equipped.
+func (a *Actor) LearnSpell(spell *Spell) {
+    if spell == nil {
+        return
+    }
+    a.knownSpells = append(a.knownSpells, spell)
+}
+
 func (a *Actor) CanAct() bool {
     return a.IsAlive() && !a.IsSleeping()
 }
@@ -6,7 +6,6 @@ import (
     "Legacy/util"
     "fmt"
     "image/color"
-    "math/rand"
 )
 
 type ItemTier string
@@ -103,6 +102,24 @@ func (m ArmorModifier) GetEncumbrance() int {
     return 0
 }
 
+// GetResistances of a single piece of armor made from this material.
+func (m ArmorModifier) GetResistances() Resistances {
+    switch m {
+    case ArmorMaterialCloth:
+        return Resistances{DamageTypeCold: 5}
+    case ArmorMaterialLeather:
+        return Resistances{DamageTypeSlash: 5, DamageTypeCold: 5}
+    case ArmorMaterialChain:
+        // the rings stop a blade, but not a point
+        return Resistances{DamageTypeSlash: 10, DamageTypePierce: -5}
+    case ArmorMaterialPlate:
+        return Resistances{DamageTypeSlash: 10, DamageTypePierce: 10, DamageTypeBlunt: -5, DamageTypeFire: -5}
+    case ArmorMaterialMagical:
+        return Resistances{DamageTypeFire: 10, DamageTypeCold: 10, DamageTypePoison: 10}
+    }
+    return Resistances{}
+}
+
 const (
     ArmorMaterialCloth   ArmorModifier = "cloth"
     ArmorMaterialLeather ArmorModifier = "leather"
@@ -139,6 +156,11 @@ func (a *Armor) GetTooltipLines() []string {
         {Label: "Value", Columns: []string{fmt.Sprintf("%dg", a.GetValue())},
         },
     }
+    if resistanceRows := a.GetResistances().TooltipRows(); len(resistanceRows) > 0 {
+        rows = append(rows, util.TableRow{})
+        rows = append(rows, util.TableRow{Label: "Resists", Columns: []string{}})
+        rows = append(rows, resistanceRows...)
+    }
     return util.TableLayout(rows)
 }
 
@@ -256,16 +278,16 @@ func NewArmor(level ItemTier, slot ArmorSlot, material ArmorModifier) *Armor {
         level:    level,
     }
 }
-func NewRandomArmor(lootLevel int) *Armor {
-    slot := randomSlot()
-    material := materialFromLootLevel(lootLevel)
-    randomTier := tierFromLootLevel(lootLevel)
+func NewRandomArmor(random Random, lootLevel int) *Armor {
+    slot := randomSlot(random)
+    material := materialFromLootLevel(random, lootLevel)
+    randomTier := tierFromLootLevel(random, lootLevel)
     return NewArmor(randomTier, slot, material)
 }
 
 func NewRandomArmorForVendor(lootLevel int) *Armor {
-    slot := randomSlot()
-    material := materialFromLootLevel(lootLevel)
+    slot := randomSlot(GlobalRandom)
+    material := materialFromLootLevel(GlobalRandom, lootLevel)
     randomTier := "common"
     if lootLevel > 1 {
         randomTier = "uncommon"
@@ -273,11 +295,11 @@ func NewRandomArmorForVendor(lootLevel int) *Armor {
     return NewArmor(ItemTier(randomTier), slot, material)
 }
 
-func tierFromLootLevel(lootLevel int) ItemTier {
+func tierFromLootLevel(random Random, lootLevel int) ItemTier {
     chanceForLegendary := 0.01
     chanceForRare := 0.04
     chanceForUncommon := 0.25
-    randFloat := rand.Float64() - (float64(lootLevel) * 0.05)
+    randFloat := random.Float64() - (float64(lootLevel) * 0.05)
     if randFloat < chanceForLegendary {
         return ItemTierLegendary
     }
@@ -332,8 +354,8 @@ func baseProtectionForArmor(slot ArmorSlot, level ItemTier) int {
     return 0
 }
 
-func randomSlot() ArmorSlot {
-    randomInt := rand.Intn(8)
+func randomSlot(random Random) ArmorSlot {
+    randomInt := random.Intn(8)
     switch randomInt {
     case 0:
         return ArmorSlotHelmet
@@ -482,8 +504,8 @@ func helmetName(material ArmorModifier) string {
     return fmt.Sprintf("%s helmet", material)
 }
 
-func materialFromLootLevel(level int) ArmorModifier {
-    randLevel := min(rand.Intn(level)+1, 5)
+func materialFromLootLevel(random Random, level int) ArmorModifier {
+    randLevel := min(random.Intn(level)+1, 5)
     switch randLevel {
     case 1:
         return ArmorMaterialCloth
@@ -528,6 +550,14 @@ func (a *Armor) GetProtection() int {
     return protectionForArmor(a.slot, a.material, a.level)
 }
 
+// GetResistances of the armor. Jewelry only protects, if it is magical.
+func (a *Armor) GetResistances() Resistances {
+    if a.IsAccessory() && a.material != ArmorMaterialMagical {
+        return Resistances{}
+    }
+    return a.material.GetResistances()
+}
+
 func (a *Armor) GetEncumbrance() int {
     materialEncumbrance := a.material.GetEncumbrance()
     switch a.slot {
@@ -0,0 +1,49 @@
+package game
+
+import "strings"
+
+// CombatBehavior decides how an NPC fights, see the "Behavior" field in the NPC files.
+type CombatBehavior string
+
+const (
+    // CombatBehaviorSoldier closes into melee and flees, when things look grim.
+    CombatBehaviorSoldier CombatBehavior = "soldier"
+    // CombatBehaviorBerserker fights to the death.
+    CombatBehaviorBerserker CombatBehavior = "berserker"
+    // CombatBehaviorCoward breaks early and runs.
+    CombatBehaviorCoward CombatBehavior = "coward"
+    // CombatBehaviorSkirmisher keeps its distance and prefers ranged attacks.
+    CombatBehaviorSkirmisher CombatBehavior = "skirmisher"
+)
+
+func CombatBehaviorFromString(value string) CombatBehavior {
+    switch CombatBehavior(strings.ToLower(strings.TrimSpace(value))) {
+    case CombatBehaviorBerserker:
+        return CombatBehaviorBerserker
+    case CombatBehaviorCoward:
+        return CombatBehaviorCoward
+    case CombatBehaviorSkirmisher:
+        return CombatBehaviorSkirmisher
+    }
+    return CombatBehaviorSoldier
+}
+
+func (b CombatBehavior) BaseMorale() int {
+    switch b {
+    case CombatBehaviorBerserker:
+        return 10
+    case CombatBehaviorCoward:
+        return 3
+    case CombatBehaviorSkirmisher:
+        return 5
+    }
+    return 6
+}
+
+func (b CombatBehavior) CanFlee() bool {
+    return b != CombatBehaviorBerserker
+}
+
+func (b CombatBehavior) KeepsDistance() bool {
+    return b == CombatBehaviorSkirmisher
+}
@@ -0,0 +1,95 @@
+package game
+
+// DungeonMonsterFaction is shared by everything living in the generated dungeons.
+const DungeonMonsterFaction = "dungeon_monsters"
+
+// BestiaryEntry is a creature that can be found in the generated dungeons, starting at MinDepth.
+// A MaxDepth of zero means there is no limit.
+type BestiaryEntry struct {
+    NpcName  string
+    Icon     int32
+    MinDepth int
+    MaxDepth int
+}
+
+func (e BestiaryEntry) LivesAt(depth int) bool {
+    return depth >= e.MinDepth && (e.MaxDepth == 0 || depth <= e.MaxDepth)
+}
+
+// DungeonBoss waits next to the ladder down, with some of its minions.
+type DungeonBoss struct {
+    Boss        BestiaryEntry
+    Minion      BestiaryEntry
+    MinionCount int
+}
+
+var bestiary = []BestiaryEntry{
+    {NpcName: "grey_rat", Icon: 57, MinDepth: 1, MaxDepth: 4},
+    {NpcName: "hungry_caterpillar", Icon: 117, MinDepth: 1, MaxDepth: 5},
+    {NpcName: "slime", Icon: 73, MinDepth: 2},
+    {NpcName: "cave_spider", Icon: 65, MinDepth: 3},
+    {NpcName: "dungeon_orc", Icon: 77, MinDepth: 5},
+}
+
+var dungeonBosses = []DungeonBoss{
+    {Boss: BestiaryEntry{NpcName: "rat_king", Icon: 200}, Minion: bestiary[0], MinionCount: 3},
+    {Boss: BestiaryEntry{NpcName: "spider_queen", Icon: 65}, Minion: bestiary[3], MinionCount: 2},
+    {Boss: BestiaryEntry{NpcName: "orc_warlord", Icon: 77}, Minion: bestiary[4], MinionCount: 2},
+}
+
+// dungeonBossInterval is the number of levels between two bosses.
+const dungeonBossInterval = 3
+
+func GetMonstersForDepth(depth int) []BestiaryEntry {
+    var monsters []BestiaryEntry
+    for _, entry := range bestiary {
+        if entry.LivesAt(depth) {
+            monsters = append(monsters, entry)
+        }
+    }
+    return monsters
+}
+
+// GetBossForDepth returns a boss for every third level. The bosses take turns.
+func GetBossForDepth(depth int) (DungeonBoss, bool) {
+    if depth <= 0 || depth%dungeonBossInterval != 0 {
+        return DungeonBoss{}, false
+    }
+    return dungeonBosses[(depth/dungeonBossInterval-1)%len(dungeonBosses)], true
+}
+
+func (r *Rules) GetMonsterLevelForDepth(depth int) int {
+    return max(1, depth)
+}
+
+func (r *Rules) GetBossLevelForDepth(depth int) int {
+    return depth + 2
+}
+
+// GetMaxMonstersPerRoom grows slowly with the depth.
+func (r *Rules) GetMaxMonstersPerRoom(depth int) int {
+    return 1 + depth/3
+}
+
+func (r *Rules) GetTrapCountForDepth(depth int) int {
+    return 1 + depth/2
+}
+
+func (r *Rules) GetTrapDamageForDepth(depth int) int {
+    return 2 + depth*2
+}
+
+// GetTrapDifficultyForDepth is used for finding and for disarming the traps.
+func (r *Rules) GetTrapDifficultyForDepth(depth int) DifficultyLevel {
+    return DifficultyLevelFromInt(depth / 2)
+}
+
+// PrepareAsMonster makes a creature from the bestiary hostile and as strong as the dungeon level demands.
+func (r *Rules) PrepareAsMonster(monster *Actor, level int) {
+    monster.SetCombatFaction(DungeonMonsterFaction)
+    monster.SetAggressive(true)
+    monster.SetNPCEngagementRange(5)
+    for monster.GetLevel() < level {
+        r.LevelUp(monster)
+    }
+}
@@ -229,6 +229,7 @@ func (s *Chest) GetContextActions(engine Engine) []util.MenuItem {
             Action: func() {
                 if engine.SkillCheckAvatar(skill, difficulty) {
                     s.isLocked = false
+                    engine.PickedLockAt(s.Pos())
                     s.spawnLoot(engine)
                     engine.ShowContainer(s)
                 } else {
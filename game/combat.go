@@ -0,0 +1,285 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "Legacy/renderer/atlas"
+    "cmp"
+    "fmt"
+    "image/color"
+    "slices"
+)
+
+// CombatSides tells the combat rules who fights on which side and what happens to the fallen.
+type CombatSides interface {
+    IsOnPartySide(actor *Actor) bool
+    IsParticipating(actor *Actor) bool
+    OnActorDied(actor *Actor)
+}
+
+// CombatObserver is told about attacks that did no damage. The combat simulator uses it for its statistics.
+type CombatObserver interface {
+    OnMiss(attacker *Actor)
+    OnFumble(attacker *Actor)
+    OnOpportunityAttack(attacker *Actor)
+}
+
+// Combat resolves attacks and the turn order of a fight.
+// The game and the combat simulator share it, each with their own engine.
+type Combat struct {
+    engine          Engine
+    sides           CombatSides
+    observer        CombatObserver
+    round           int
+    partyStarted    bool
+    hasUsedReaction map[*Actor]bool
+}
+
+func NewCombat(engine Engine, sides CombatSides) *Combat {
+    return &Combat{
+        engine:          engine,
+        sides:           sides,
+        hasUsedReaction: make(map[*Actor]bool),
+    }
+}
+
+func (c *Combat) SetObserver(observer CombatObserver) {
+    c.observer = observer
+}
+
+// Start begins a new fight. Whoever started it, acts first in the first round.
+func (c *Combat) Start(partyStarted bool) {
+    c.round = 0
+    c.partyStarted = partyStarted
+    clear(c.hasUsedReaction)
+    c.engine.GetCombatLog().StartCombat()
+}
+
+func (c *Combat) End() {
+    c.engine.GetCombatLog().EndCombat()
+}
+
+func (c *Combat) GetRound() int {
+    return c.round
+}
+
+// StartNewRound gives everyone their reaction back.
+func (c *Combat) StartNewRound() {
+    clear(c.hasUsedReaction)
+    c.round++
+    c.engine.GetCombatLog().SetRound(c.round)
+}
+
+// SortByInitiative returns the participants in the order they will act this round.
+// Initiative decides, the party wins ties.
+func (c *Combat) SortByInitiative(participants []*Actor) []*Actor {
+    isFirstRound := c.round == 1
+    turnOrder := slices.Clone(participants)
+    slices.SortStableFunc(turnOrder, func(one, two *Actor) int {
+        isOnePartyMember := c.sides.IsOnPartySide(one)
+        isTwoPartyMember := c.sides.IsOnPartySide(two)
+        if isFirstRound && isOnePartyMember != isTwoPartyMember {
+            // whoever started the fight, gets to act first
+            if isOnePartyMember == c.partyStarted {
+                return -1
+            }
+            return 1
+        }
+        if one.GetInitiative() != two.GetInitiative() {
+            return two.GetInitiative() - one.GetInitiative()
+        }
+        if isOnePartyMember != isTwoPartyMember {
+            if isOnePartyMember {
+                return -1
+            }
+            return 1
+        }
+        return cmp.Compare(one.Name(), two.Name())
+    })
+    return turnOrder
+}
+
+// MeleeAttack rolls right away, but the outcome is applied once the hit animation is done.
+// onResolve is called before any damage is dealt.
+func (c *Combat) MeleeAttack(attacker *Actor, victim *Actor, onResolve func()) {
+    rules := c.engine.GetRules()
+    combatLog := c.engine.GetCombatLog()
+    attacker.OnAttackPerformed(c.engine)
+    attackRoll := rules.RollMeleeAttack(combatLog, attacker, victim)
+    doesHit := attackRoll.IsHit()
+    isBlocked := doesHit && rules.DoesShieldBlock(victim)
+    if isBlocked {
+        combatLog.LogEffect(victim, "blocks with the shield")
+        doesHit = false
+    }
+    strikes := 1
+    if doesHit && attacker.CanBackstab(victim) && c.isStrikeFromBehind(attacker, victim) {
+        strikes = 2
+    }
+    icon, useAtlas := missIcon()
+    if doesHit {
+        icon, useAtlas = hitIcon()
+    }
+    c.engine.CombatHitAnimation(victim.Pos(), useAtlas, icon, color.White, func() {
+        if onResolve != nil {
+            onResolve()
+        }
+        if attackRoll.IsFumble() {
+            c.Fumble(attacker)
+        }
+        if isBlocked {
+            c.engine.Print(fmt.Sprintf("'%s' blocks the attack", victim.Name()))
+        }
+        if !doesHit {
+            c.onMiss(attacker)
+            return
+        }
+        if strikes > 1 {
+            c.engine.Print(fmt.Sprintf("'%s' strikes twice from behind", attacker.Name()))
+        }
+        for i := 0; i < strikes && victim.IsAlive(); i++ {
+            c.deliverMeleeDamage(attacker, victim, attackRoll.IsCritical() && i == 0)
+        }
+    })
+}
+
+// ProvokeOpportunityAttacks lets the enemies that threatened the old position strike, once per round each.
+// Returns false, if the mover was killed or pinned down by a shield bearer.
+func (c *Combat) ProvokeOpportunityAttacks(mover *Actor, from geometry.Point) bool {
+    isPinned := false
+    for _, attacker := range GetOpportunityAttackers(c.engine.GetGridMap(), mover, from) {
+        if !mover.IsAlive() {
+            break
+        }
+        if c.hasUsedReaction[attacker] || !c.sides.IsParticipating(attacker) {
+            continue
+        }
+        c.hasUsedReaction[attacker] = true
+        if c.opportunityAttack(attacker, mover) && attacker.PinsOnOpportunityAttack() && mover.IsAlive() {
+            c.engine.Print(fmt.Sprintf("'%s' is pinned down", mover.Name()))
+            isPinned = true
+        }
+    }
+    return mover.IsAlive() && !isPinned
+}
+
+// opportunityAttack is a free melee strike, it doesn't use up the turn of the attacker and never strikes twice.
+// Returns true on a hit.
+func (c *Combat) opportunityAttack(attacker *Actor, victim *Actor) bool {
+    rules := c.engine.GetRules()
+    combatLog := c.engine.GetCombatLog()
+    c.engine.Print(fmt.Sprintf("'%s' strikes at '%s' passing by", attacker.Name(), victim.Name()))
+    if c.observer != nil {
+        c.observer.OnOpportunityAttack(attacker)
+    }
+    attacker.OnAttackPerformed(c.engine)
+    attackRoll := rules.RollMeleeAttack(combatLog, attacker, victim)
+    doesHit := attackRoll.IsHit()
+    isBlocked := doesHit && rules.DoesShieldBlock(victim)
+    if isBlocked {
+        combatLog.LogEffect(victim, "blocks with the shield")
+    }
+    icon, useAtlas := missIcon()
+    if doesHit && !isBlocked {
+        icon, useAtlas = hitIcon()
+    }
+    c.engine.CombatHitAnimation(victim.Pos(), useAtlas, icon, color.White, nil)
+    if attackRoll.IsFumble() {
+        c.Fumble(attacker)
+    }
+    if isBlocked {
+        c.engine.Print(fmt.Sprintf("'%s' blocks the attack", victim.Name()))
+    }
+    if !doesHit || isBlocked {
+        c.onMiss(attacker)
+        return false
+    }
+    c.deliverMeleeDamage(attacker, victim, attackRoll.IsCritical())
+    return true
+}
+
+// Fumble is the price of a botched melee attack. The attacker may hit a nearby ally or drop the weapon.
+func (c *Combat) Fumble(attacker *Actor) {
+    combatLog := c.engine.GetCombatLog()
+    if c.observer != nil {
+        c.observer.OnFumble(attacker)
+    }
+    fumbleKind, ally := c.engine.GetRules().RollFumble(attacker, c.neighbouringAllies(attacker))
+    switch fumbleKind {
+    case FumbleKindHitAlly:
+        combatLog.LogEffect(attacker, fmt.Sprintf("fumbles and hits '%s'", ally.Name()))
+        c.engine.Print(fmt.Sprintf("'%s' fumbles and hits '%s'", attacker.Name(), ally.Name()))
+        c.deliverMeleeDamage(attacker, ally, false)
+    case FumbleKindDropWeapon:
+        weapon := attacker.DropMeleeWeapon()
+        combatLog.LogEffect(attacker, fmt.Sprintf("fumbles and drops the %s", weapon.Name()))
+        c.engine.Print(fmt.Sprintf("'%s' fumbles and drops the %s", attacker.Name(), weapon.Name()))
+    default:
+        combatLog.LogEffect(attacker, "fumbles and stumbles")
+        c.engine.Print(fmt.Sprintf("'%s' fumbles and stumbles", attacker.Name()))
+    }
+}
+
+// ResolveRangedHit rolls for a projectile that reached the actor.
+func (c *Combat) ResolveRangedHit(attacker *Actor, actorHit *Actor) {
+    if actorHit.IsHidden() {
+        actorHit.SetHidden(false)
+    }
+    attackRoll := c.engine.GetRules().RollRangedAttack(c.engine.GetCombatLog(), attacker, actorHit)
+    if !attackRoll.IsHit() {
+        c.onMiss(attacker)
+        return
+    }
+    c.engine.DeliverRangedDamage(attacker, actorHit, attackRoll.IsCritical())
+    if !actorHit.IsAlive() {
+        c.sides.OnActorDied(actorHit)
+    }
+}
+
+// isStrikeFromBehind is true, if the victim is helpless or flanked by an ally of the attacker.
+func (c *Combat) isStrikeFromBehind(attacker *Actor, victim *Actor) bool {
+    if victim.IsSleeping() || victim.IsStunned() {
+        return true
+    }
+    behindVictim := victim.Pos().Add(victim.Pos().Sub(attacker.Pos()))
+    gridMap := c.engine.GetGridMap()
+    if !gridMap.Contains(behindVictim) || !gridMap.IsActorAt(behindVictim) {
+        return false
+    }
+    flanker := gridMap.ActorAt(behindVictim)
+    return flanker.IsAlive() && attacker.IsAllyOf(flanker)
+}
+
+func (c *Combat) neighbouringAllies(actor *Actor) []*Actor {
+    var allies []*Actor
+    gridMap := c.engine.GetGridMap()
+    for _, neighbor := range gridMap.GetAllCardinalNeighbors(actor.Pos()) {
+        if gridMap.IsActorAt(neighbor) {
+            actorAt := gridMap.ActorAt(neighbor)
+            if actorAt != actor && actorAt.IsAlive() && actor.IsAllyOf(actorAt) {
+                allies = append(allies, actorAt)
+            }
+        }
+    }
+    return allies
+}
+
+func (c *Combat) deliverMeleeDamage(attacker, victim *Actor, isCritical bool) {
+    c.engine.DeliverMeleeDamage(attacker, victim, isCritical)
+    if !victim.IsAlive() {
+        c.sides.OnActorDied(victim)
+    }
+}
+
+func (c *Combat) onMiss(attacker *Actor) {
+    if c.observer != nil {
+        c.observer.OnMiss(attacker)
+    }
+}
+
+func hitIcon() (int32, atlas.Name) {
+    return 104, atlas.World
+}
+
+func missIcon() (int32, atlas.Name) {
+    return 194, atlas.Entities
+}
@@ -0,0 +1,161 @@
+package game
+
+import (
+    "fmt"
+    "io"
+    "slices"
+    "strings"
+)
+
+const maxCombatLogEntries = 1000
+
+// CombatLogEntry is a single attack or event, with the numbers behind it.
+type CombatLogEntry struct {
+    Round   int
+    Actors  []*Actor
+    Summary string
+    Details []string
+}
+
+func (e *CombatLogEntry) Involves(actor *Actor) bool {
+    return slices.Contains(e.Actors, actor)
+}
+
+func (e *CombatLogEntry) Lines() []string {
+    lines := []string{fmt.Sprintf("R%d %s", e.Round, e.Summary)}
+    for _, detail := range e.Details {
+        lines = append(lines, "   "+detail)
+    }
+    return lines
+}
+
+// CombatLog records what happened in fights in more detail than the message log.
+// Attack rolls are recorded by the rules, damage and status changes by the engine.
+// Nothing is recorded between fights.
+type CombatLog struct {
+    entries  []*CombatLogEntry
+    round    int
+    isActive bool
+}
+
+func NewCombatLog() *CombatLog {
+    return &CombatLog{}
+}
+
+func (l *CombatLog) StartCombat() {
+    l.round = 0
+    l.isActive = true
+    l.add(&CombatLogEntry{Summary: "=== Combat started ==="})
+}
+
+func (l *CombatLog) EndCombat() {
+    l.isActive = false
+}
+
+func (l *CombatLog) SetRound(round int) {
+    l.round = round
+}
+
+func (l *CombatLog) IsEmpty() bool {
+    return len(l.entries) == 0
+}
+
+func (l *CombatLog) LogAttack(attacker, victim *Actor, kind string, hitChance, roll float64, outcome AttackRoll, followUpChance float64) {
+    if !l.isActive {
+        return
+    }
+    details := fmt.Sprintf("rolled %.0f vs. hit chance %.0f%%", roll*100, hitChance*100)
+    switch outcome {
+    case AttackRollCritical, AttackRollHit:
+        details += fmt.Sprintf(", crit chance %.0f%%", followUpChance*100)
+    case AttackRollFumble, AttackRollMiss:
+        if followUpChance > 0 {
+            details += fmt.Sprintf(", fumble chance %.0f%%", followUpChance*100)
+        }
+    }
+    l.add(&CombatLogEntry{
+        Round:   l.round,
+        Actors:  []*Actor{attacker, victim},
+        Summary: fmt.Sprintf("'%s' %s '%s': %s", attacker.Name(), kind, victim.Name(), outcome.String()),
+        Details: []string{details},
+    })
+}
+
+// LogDamage shows how the damage was calculated. The attacker may be nil.
+func (l *CombatLog) LogDamage(attacker, victim *Actor, breakdown DamageBreakdown) {
+    l.logEvent(attacker, victim, fmt.Sprintf("damage to '%s': %s", victim.Name(), breakdown.String()))
+}
+
+func (l *CombatLog) LogEffect(actor *Actor, text string) {
+    l.logEvent(nil, actor, fmt.Sprintf("'%s' %s", actor.Name(), text))
+}
+
+// logEvent adds the text to the last entry, if it is about the same actor in the same round.
+// Otherwise it starts a new entry.
+func (l *CombatLog) logEvent(source, actor *Actor, text string) {
+    if !l.isActive {
+        return
+    }
+    if len(l.entries) > 0 {
+        lastEntry := l.entries[len(l.entries)-1]
+        if lastEntry.Round == l.round && lastEntry.Involves(actor) {
+            lastEntry.Details = append(lastEntry.Details, text)
+            return
+        }
+    }
+    actors := []*Actor{actor}
+    if source != nil && source != actor {
+        actors = append(actors, source)
+    }
+    l.add(&CombatLogEntry{
+        Round:   l.round,
+        Actors:  actors,
+        Summary: text,
+    })
+}
+
+func (l *CombatLog) add(entry *CombatLogEntry) {
+    if len(l.entries) >= maxCombatLogEntries {
+        l.entries = l.entries[1:]
+    }
+    l.entries = append(l.entries, entry)
+}
+
+// GetActors returns everyone who appears in the log, sorted by name.
+func (l *CombatLog) GetActors() []*Actor {
+    var actors []*Actor
+    for _, entry := range l.entries {
+        for _, actor := range entry.Actors {
+            if !slices.Contains(actors, actor) {
+                actors = append(actors, actor)
+            }
+        }
+    }
+    slices.SortStableFunc(actors, func(one, two *Actor) int {
+        return strings.Compare(one.Name(), two.Name())
+    })
+    return actors
+}
+
+// GetLines returns the entries involving the actor, or all entries for a nil actor.
+func (l *CombatLog) GetLines(actor *Actor, newestFirst bool) []string {
+    var lines []string
+    for i := range l.entries {
+        entry := l.entries[i]
+        if newestFirst {
+            entry = l.entries[len(l.entries)-1-i]
+        }
+        if actor != nil && !entry.Involves(actor) {
+            continue
+        }
+        lines = append(lines, entry.Lines()...)
+    }
+    return lines
+}
+
+// WriteTo dumps the whole log as plain text, oldest entries first.
+func (l *CombatLog) WriteTo(w io.Writer) (int64, error) {
+    text := strings.Join(l.GetLines(nil, false), "\n") + "\n"
+    written, err := io.WriteString(w, text)
+    return int64(written), err
+}
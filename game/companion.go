@@ -0,0 +1,134 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "math"
+)
+
+// PartyCombatFaction is shared by the party and everyone fighting on its side.
+const PartyCombatFaction = "_player_party"
+
+// Companions fight on the side of the party for a number of combat rounds, but they are controlled by the AI.
+// Summoned creatures vanish when their time is up, charmed ones return to their old side.
+type CompanionKind int
+
+const (
+    CompanionKindNone CompanionKind = iota
+    CompanionKindSummoned
+    CompanionKindCharmed
+)
+
+// SummonableMonster is what answers the call of a caster of the given level.
+type SummonableMonster struct {
+    NpcName string
+    Icon    int32
+}
+
+func GetSummonableMonster(casterLevel int) SummonableMonster {
+    if casterLevel >= 5 {
+        return SummonableMonster{NpcName: "rat_king", Icon: 200}
+    }
+    if casterLevel >= 3 {
+        return SummonableMonster{NpcName: "slime", Icon: 73}
+    }
+    return SummonableMonster{NpcName: "grey_rat", Icon: 57}
+}
+
+// BecomeCompanion puts the actor on the side of the party.
+func (a *Actor) BecomeCompanion(kind CompanionKind, rounds int) {
+    if a.companionKind == CompanionKindNone {
+        a.companionFormerFaction = a.combatFaction
+    }
+    a.companionKind = kind
+    a.companionRoundsLeft = rounds
+    a.combatFaction = PartyCombatFaction
+}
+
+// ReleaseCompanion returns the actor to the faction it had before.
+func (a *Actor) ReleaseCompanion() {
+    if a.companionKind == CompanionKindNone {
+        return
+    }
+    a.combatFaction = a.companionFormerFaction
+    a.companionKind = CompanionKindNone
+    a.companionRoundsLeft = 0
+}
+
+func (a *Actor) IsCompanion() bool {
+    return a.companionKind != CompanionKindNone
+}
+
+func (a *Actor) IsSummoned() bool {
+    return a.companionKind == CompanionKindSummoned
+}
+
+func (a *Actor) IsCharmed() bool {
+    return a.companionKind == CompanionKindCharmed
+}
+
+func (a *Actor) GetCompanionRoundsLeft() int {
+    return a.companionRoundsLeft
+}
+
+// TickCompanionRound is called at the start of each turn of the companion.
+// Returns true, if the time is up.
+func (a *Actor) TickCompanionRound() bool {
+    if a.companionKind == CompanionKindNone {
+        return false
+    }
+    a.companionRoundsLeft--
+    return a.companionRoundsLeft < 0
+}
+
+// PrepareAsSummon takes away everything the creature carries and brings it up to the level of the caster.
+// Summoned creatures leave nothing behind.
+func (r *Rules) PrepareAsSummon(summoned *Actor, casterLevel int) {
+    summoned.inventory = []Item{}
+    for summoned.GetLevel() < casterLevel {
+        r.LevelUp(summoned)
+    }
+}
+
+// GetSummonDuration is the number of rounds a summoned creature stays.
+func (r *Rules) GetSummonDuration(casterLevel int) int {
+    return 4 + casterLevel/2
+}
+
+// GetCharmChance compares the minds of caster and victim.
+func (r *Rules) GetCharmChance(caster *Actor, victim *Actor) float64 {
+    difference := caster.GetAttributes().GetAttribute(Intelligence) - victim.GetAttributes().GetAttribute(Intelligence)
+    return max(0.1, min(0.9, 0.5+0.1*float64(difference)))
+}
+
+// CanBeCharmed is false for creatures without a mind to bend.
+func (c CreatureType) CanBeCharmed() bool {
+    return c != CreatureTypeUndead && c != CreatureTypeNonIntelligent
+}
+
+// GetXPShare splits the experience for a kill between the party and its companions.
+// Each party member receives their share, the share of the companions is lost.
+func (r *Rules) GetXPShare(xp int, partySize int, companionCount int) int {
+    if companionCount <= 0 {
+        return xp
+    }
+    return xp * partySize / (partySize + companionCount)
+}
+
+// freeTilesForSummoning returns the visible tiles in range, where a creature could appear.
+func freeTilesForSummoning(radius int) func(engine Engine, caster *Actor, usePos geometry.Point) map[geometry.Point]bool {
+    return allVisibleTilesInRadiusWith(radius, func(engine Engine, pos geometry.Point) bool {
+        return engine.GetGridMap().IsCurrentlyPassable(pos)
+    })
+}
+
+// summonUtility prefers tiles close to the enemy, so the creature can fight right away.
+func summonUtility(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+    if len(enemyPositions) == 0 {
+        return -10
+    }
+    nearestEnemy := math.MaxInt
+    for pos, _ := range enemyPositions {
+        nearestEnemy = min(nearestEnemy, geometry.DistanceManhattan(pos, target))
+    }
+    return 30 - nearestEnemy*2
+}
@@ -0,0 +1,94 @@
+package game
+
+import (
+    "Legacy/recfile"
+    "fmt"
+    "sort"
+)
+
+type CrimeType string
+
+const (
+    CrimeTheft       CrimeType = "theft"
+    CrimePlanting    CrimeType = "planting"
+    CrimeTrespassing CrimeType = "trespassing"
+    CrimeAssault     CrimeType = "assault"
+    CrimeMurder      CrimeType = "murder"
+)
+
+// Bounties keeps track of the bounty on the party's head, per map.
+// Bounties decay over time, see Rules.GetBountyDecayPerDay.
+type Bounties struct {
+    perMap            map[string]int
+    minutesSinceDecay int
+}
+
+func NewBounties() *Bounties {
+    return &Bounties{
+        perMap: make(map[string]int),
+    }
+}
+
+func (b *Bounties) AddBounty(mapName string, amount int) {
+    b.perMap[mapName] += amount
+}
+
+func (b *Bounties) GetBounty(mapName string) int {
+    return b.perMap[mapName]
+}
+
+func (b *Bounties) HasBounty(mapName string) bool {
+    return b.perMap[mapName] > 0
+}
+
+func (b *Bounties) ClearBounty(mapName string) {
+    delete(b.perMap, mapName)
+}
+
+func (b *Bounties) OnTimePassed(minutes int, decayPerDay int) {
+    b.minutesSinceDecay += minutes
+    for b.minutesSinceDecay >= MinutesPerDay {
+        b.minutesSinceDecay -= MinutesPerDay
+        for mapName, bounty := range b.perMap {
+            bounty -= decayPerDay
+            if bounty <= 0 {
+                delete(b.perMap, mapName)
+            } else {
+                b.perMap[mapName] = bounty
+            }
+        }
+    }
+}
+
+func (b *Bounties) GetDebugInfo() []string {
+    result := make([]string, 0)
+    for mapName, bounty := range b.perMap {
+        result = append(result, fmt.Sprintf("%s: %d", mapName, bounty))
+    }
+    sort.Strings(result)
+    return result
+}
+
+func (b *Bounties) ToRecord() recfile.Record {
+    record := recfile.Record{
+        {Name: "minutesSinceDecay", Value: recfile.IntStr(b.minutesSinceDecay)},
+    }
+    for mapName, bounty := range b.perMap {
+        record = append(record, recfile.Field{Name: "bounty", Value: recfile.ToPredicate("bounty", mapName, recfile.IntStr(bounty))})
+    }
+    return record
+}
+
+func NewBountiesFromRecord(record recfile.Record) *Bounties {
+    bounties := NewBounties()
+    for _, field := range record {
+        switch field.Name {
+        case "minutesSinceDecay":
+            bounties.minutesSinceDecay = field.AsInt()
+        case "bounty":
+            predicate := recfile.StrPredicate(field.Value)
+            bounties.perMap[predicate.GetString(0)] = predicate.GetInt(1)
+        }
+    }
+    return bounties
+}
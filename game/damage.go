@@ -0,0 +1,74 @@
+package game
+
+import (
+    "Legacy/util"
+    "fmt"
+)
+
+// DamageType decides which resistances apply to a hit.
+type DamageType string
+
+const (
+    DamageTypeSlash  DamageType = "slash"
+    DamageTypePierce DamageType = "pierce"
+    DamageTypeBlunt  DamageType = "blunt"
+    DamageTypeFire   DamageType = "fire"
+    DamageTypeCold   DamageType = "cold"
+    DamageTypeHoly   DamageType = "holy"
+    DamageTypePoison DamageType = "poison"
+)
+
+func GetAllDamageTypes() []DamageType {
+    return []DamageType{
+        DamageTypeSlash,
+        DamageTypePierce,
+        DamageTypeBlunt,
+        DamageTypeFire,
+        DamageTypeCold,
+        DamageTypeHoly,
+        DamageTypePoison,
+    }
+}
+
+func (t DamageType) IsPhysical() bool {
+    return t == DamageTypeSlash || t == DamageTypePierce || t == DamageTypeBlunt
+}
+
+// Resistances are the percentages of damage that are not taken, by damage type.
+// Negative values are vulnerabilities, 100 is immunity.
+type Resistances map[DamageType]int
+
+func (r Resistances) Add(other Resistances) {
+    for damageType, value := range other {
+        r[damageType] += value
+    }
+}
+
+func (r Resistances) Get(damageType DamageType) int {
+    return max(-100, min(100, r[damageType]))
+}
+
+// Apply reduces the amount of damage. Like armor, it never reduces a hit to zero, unless the victim is immune.
+func (r Resistances) Apply(amount int, damageType DamageType) int {
+    resistance := r.Get(damageType)
+    if amount <= 0 || resistance == 0 {
+        return amount
+    }
+    if resistance >= 100 {
+        return 0
+    }
+    return max(1, amount*(100-resistance)/100)
+}
+
+// TooltipRows lists all resistances and vulnerabilities, in the order of GetAllDamageTypes.
+func (r Resistances) TooltipRows() []util.TableRow {
+    var rows []util.TableRow
+    for _, damageType := range GetAllDamageTypes() {
+        value := r.Get(damageType)
+        if value == 0 {
+            continue
+        }
+        rows = append(rows, util.TableRow{Label: " " + string(damageType), Columns: []string{fmt.Sprintf("%+d%%", value)}})
+    }
+    return rows
+}
@@ -272,6 +272,13 @@ func (d *Dialogue) HasFirstTimeText() bool {
     return false
 }
 
+func (d *Dialogue) HasSurrenderText() bool {
+    if _, exists := d.triggers["_surrender"]; exists {
+        return true
+    }
+    return false
+}
+
 func (d *Dialogue) HasOpening() bool {
     if _, exists := d.triggers["_opening"]; exists {
         return true
@@ -168,6 +168,11 @@ func (d *Door) IsTransparent() bool {
     return !d.isLocked
 }
 
+// IsFlammable is false for doors held shut by magic.
+func (d *Door) IsFlammable() bool {
+    return !d.isMagicallyLocked
+}
+
 func (d *Door) GetContextActions(engine Engine) []util.MenuItem {
     actions := d.BaseObject.GetContextActions(engine, d)
     party := engine.GetParty()
@@ -292,6 +297,7 @@ func (d *Door) GetContextActions(engine Engine) []util.MenuItem {
                         if engine.SkillCheckAvatar(skillForPick, difficultyForPick) {
                             d.isLocked = false
                             engine.Print("You picked the lock.")
+                            engine.PickedLockAt(d.Pos())
                         } else {
                             // broke
                             engine.RemoveLockpick()
@@ -3,7 +3,7 @@ package game
 import (
     "Legacy/geometry"
     "Legacy/gridmap"
-    "Legacy/renderer"
+    "Legacy/renderer/atlas"
     "Legacy/util"
     "image/color"
 )
@@ -34,8 +34,10 @@ type Engine interface {
     GetPartySize() int
     RemoveLockpick()
     ShowDrinkPotionMenu(potion *Potion)
+    DrinkPotion(potion *Potion, drinker *Actor)
     ManaSpent(caster *Actor, cost int)
     DamageAvatar(amount int)
+    SpringTrap(trap *Trap, victim *Actor)
     TriggerEvent(event string)
     GetMapName() string
     CurrentTick() uint64
@@ -48,10 +50,13 @@ type Engine interface {
     PlayerTriesBackstab(opponent *Actor)
     PlayerStartsOffensiveSpell(caster *Actor, spell *Spell)
     GetAoECircle(pos geometry.Point, radius int) []geometry.Point
-    CombatHitAnimation(pos geometry.Point, atlasName renderer.AtlasName, icon int32, tintColor color.Color, whenDone func())
-    FixedDamageAt(caster *Actor, pos geometry.Point, amount int)
+    CombatHitAnimation(pos geometry.Point, atlasName atlas.Name, icon int32, tintColor color.Color, whenDone func())
+    FixedDamageAt(caster *Actor, pos geometry.Point, amount int, damageType DamageType)
+    IgniteAt(pos geometry.Point)
+    ExtinguishAt(pos geometry.Point)
     GetPartyEquipment() []Item
     GetRules() *Rules
+    GetCombatLog() *CombatLog
     CanLevelUp(member *Actor) (bool, int)
     FreezeActorAt(pos geometry.Point, turns int)
     ProdActor(prodder *Actor, victim *Actor)
@@ -67,6 +72,8 @@ type Engine interface {
     GetRegion(regionName string) geometry.Rect
     DrawCharInWorld(charToDraw rune, pos geometry.Point)
     RaiseAsUndeadAt(caster *Actor, pos geometry.Point)
+    SummonMonsterAt(caster *Actor, pos geometry.Point, monster SummonableMonster, rounds int)
+    Charm(caster *Actor, victim *Actor, rounds int)
     GetActorByInternalName(internalName string) *Actor
     GetDialogueFromFile(conversationId string) *Dialogue
     GetVisibleMap() geometry.Rect
@@ -92,5 +99,11 @@ type Engine interface {
     UnlockDoorsByKeyName(keyName string)
     TryMoveNPCOnPath(actor *Actor, dest geometry.Point)
     IsSneaking() bool
+    PickedLockAt(location geometry.Point)
     AddStatusEffect(victim *Actor, statusEffect StatusEffect, stacks int)
+    WeaponDamageAt(attacker *Actor, weapon *Weapon, pos geometry.Point, damagePercent int) *Actor
+    DeliverMeleeDamage(attacker *Actor, victim *Actor, isCritical bool)
+    DeliverRangedDamage(attacker *Actor, victim *Actor, isCritical bool)
+    KnockBack(attacker *Actor, victim *Actor) bool
+    Reposition(actor *Actor, dest geometry.Point)
 }
@@ -3,7 +3,7 @@ package game
 import (
     "Legacy/geometry"
     "Legacy/gridmap"
-    "github.com/hajimehoshi/ebiten/v2"
+    "Legacy/util"
     "image/color"
     "math/rand"
 )
@@ -125,7 +125,7 @@ type EncounterPickedUpNomDePlume struct {
 
 func (e *EncounterPickedUpNomDePlume) Update() {
     e.tickCounter++
-    if e.tickCounter >= uint64(ebiten.ActualTPS()) {
+    if e.tickCounter >= uint64(util.TicksPerSecond()) {
         e.tickCounter = 0
         if len(e.availableIndices) > 0 {
             e.drawChar()
@@ -182,7 +182,7 @@ func (e *EncounterEarlyExit) start() {
 
 func (e *EncounterEarlyExit) Update() {
     e.ticksAlive++
-    if e.ticksAlive > uint64(ebiten.ActualTPS()*1) && !e.isOver {
+    if e.ticksAlive > uint64(util.TicksPerSecond()*1) && !e.isOver {
         e.engine.ShowScrollableText([]string{"That's definitely not how physics worked yesterday.", "Is this a bad dream?"}, color.White, true)
         e.isOver = true
     }
@@ -213,7 +213,7 @@ func (e *FlashWordEvent) Update() {
         return
     }
     e.tickCounter++
-    if e.tickCounter >= uint64(ebiten.ActualTPS()/2.0) {
+    if e.tickCounter >= uint64(util.TicksPerSecond()/2.0) {
         e.tickCounter = 0
         e.restore()
         e.isOver = true
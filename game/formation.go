@@ -0,0 +1,133 @@
+package game
+
+import "Legacy/geometry"
+
+// Formation decides where the party members stand, when a fight starts.
+type Formation string
+
+const (
+    FormationLoose  Formation = "loose"
+    FormationLines  Formation = "lines"
+    FormationWedge  Formation = "wedge"
+    FormationColumn Formation = "column"
+)
+
+func GetAllFormations() []Formation {
+    return []Formation{FormationLoose, FormationLines, FormationWedge, FormationColumn}
+}
+
+func FormationFromString(value string) Formation {
+    for _, formation := range GetAllFormations() {
+        if string(formation) == value {
+            return formation
+        }
+    }
+    return FormationLoose
+}
+
+func (f Formation) Name() string {
+    switch f {
+    case FormationLines:
+        return "Front and back line"
+    case FormationWedge:
+        return "Wedge"
+    case FormationColumn:
+        return "Column"
+    }
+    return "Loose"
+}
+
+func (f Formation) GetDescription() []string {
+    switch f {
+    case FormationLines:
+        return []string{
+            "Fighters form a line facing the enemy.",
+            "Archers and casters stay behind them.",
+        }
+    case FormationWedge:
+        return []string{
+            "The leader takes the point,",
+            "the others cover the flanks.",
+        }
+    case FormationColumn:
+        return []string{
+            "Everyone lines up behind the leader.",
+            "Good for narrow corridors.",
+        }
+    }
+    return []string{
+        "Everyone fights from where they stand.",
+    }
+}
+
+// slots are given as X = steps towards the enemy, Y = steps to the right.
+// The lines formation has a separate row for the back line.
+func (f Formation) slots() (front []geometry.Point, back []geometry.Point) {
+    switch f {
+    case FormationLines:
+        return []geometry.Point{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: -1}, {X: 0, Y: 2}},
+            []geometry.Point{{X: -1, Y: 0}, {X: -1, Y: 1}, {X: -1, Y: -1}, {X: -1, Y: 2}}
+    case FormationWedge:
+        return []geometry.Point{{X: 0, Y: 0}, {X: -1, Y: -1}, {X: -1, Y: 1}, {X: -2, Y: 0}}, nil
+    case FormationColumn:
+        return []geometry.Point{{X: 0, Y: 0}, {X: -1, Y: 0}, {X: -2, Y: 0}, {X: -3, Y: 0}}, nil
+    }
+    return nil, nil
+}
+
+// PrefersBackLine is true for members that would rather not be in melee.
+func (a *Actor) PrefersBackLine() bool {
+    return a.HasRangedWeaponEquipped() || a.GetCombatBehavior().KeepsDistance()
+}
+
+func (p *Party) GetFormation() Formation {
+    return p.formation
+}
+
+func (p *Party) SetFormation(formation Formation) {
+    p.formation = formation
+}
+
+// GetFormationOffsets returns where each member should stand, relative to the anchor.
+// Facing is the cardinal direction towards the enemy. Fighters take the front slots.
+func (p *Party) GetFormationOffsets(anchor *Actor, facing geometry.Point) map[*Actor]geometry.Point {
+    front, back := p.formation.slots()
+    if len(front) == 0 {
+        return nil
+    }
+    var frontLiners, backLiners []*Actor
+    for _, member := range p.members {
+        if member.PrefersBackLine() {
+            backLiners = append(backLiners, member)
+        } else {
+            frontLiners = append(frontLiners, member)
+        }
+    }
+    localSlots := make(map[*Actor]geometry.Point)
+    if len(back) == 0 {
+        for i, member := range append(frontLiners, backLiners...) {
+            localSlots[member] = front[i%len(front)]
+        }
+    } else {
+        for i, member := range frontLiners {
+            localSlots[member] = front[i%len(front)]
+        }
+        for i, member := range backLiners {
+            localSlots[member] = back[i%len(back)]
+        }
+    }
+
+    right := geometry.Point{X: -facing.Y, Y: facing.X}
+    toWorld := func(local geometry.Point) geometry.Point {
+        return geometry.Point{
+            X: facing.X*local.X + right.X*local.Y,
+            Y: facing.Y*local.X + right.Y*local.Y,
+        }
+    }
+    anchorSlot := localSlots[anchor]
+    offsets := make(map[*Actor]geometry.Point)
+    for member, slot := range localSlots {
+        offsets[member] = toWorld(slot.Sub(anchorSlot))
+    }
+    return offsets
+}
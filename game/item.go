@@ -161,7 +161,7 @@ func NewItemFromString(encoded string) Item {
     case "key":
         return NewKeyFromPredicate(predicate)
     case "potion":
-        return NewPotion()
+        return NewPotionFromPredicate(predicate)
     case "candle":
         return NewCandle(false)
     case "scroll":
@@ -188,6 +188,13 @@ func NewItemFromString(encoded string) Item {
         return NewNamedWeapon(predicate.GetString(0))
     case "tool":
         return NewToolFromPredicate(predicate)
+    case "wand":
+        // a nil *Wand would be a non-nil Item, unknown spells are treated like unknown items
+        if wand := NewWandFromPredicate(predicate); wand != nil {
+            return wand
+        }
+    case "throwable":
+        return NewThrowableFromPredicate(predicate)
     }
     println(fmt.Sprintf("Unknown item type and params: %s", encoded))
     return NewKeyFromImportance("unknown item", "unknown item", 1)
@@ -0,0 +1,35 @@
+package game
+
+// GetCarriedLightRadius returns the radius of the brightest lit light source the actor carries.
+func (a *Actor) GetCarriedLightRadius() int {
+    radius := 0
+    for _, item := range a.inventory {
+        if lightSource, isLightSource := item.(*LightSource); isLightSource {
+            radius = max(radius, lightSource.GetLightRadius())
+        }
+    }
+    return radius
+}
+
+// GetCarriedLightRadius returns the radius of the brightest lit light source in the party inventory.
+func (p *Party) GetCarriedLightRadius() int {
+    radius := 0
+    for _, item := range p.GetFlatInventory() {
+        if lightSource, isLightSource := item.(*LightSource); isLightSource {
+            radius = max(radius, lightSource.GetLightRadius())
+        }
+    }
+    return radius
+}
+
+// GetSneakBonusForLight makes sneaking easier in dim light and even more so in darkness.
+// The bonus lowers the difficulty of the sneak check by that many levels.
+func (r *Rules) GetSneakBonusForLight(lightLevel float64) int {
+    if lightLevel < 0.25 {
+        return 2
+    }
+    if lightLevel < 0.5 {
+        return 1
+    }
+    return 0
+}
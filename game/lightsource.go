@@ -16,6 +16,7 @@ type LightSource struct {
     litAttachedFrames []int32
     unlitAttachedIcon int32
     randomOffset      uint64
+    lightRadius       int
 }
 
 func (b *LightSource) GetTooltipLines() []string {
@@ -70,6 +71,18 @@ func (b *LightSource) GetContextActions(engine Engine) []util.MenuItem {
     return actions
 }
 
+func (b *LightSource) IsLit() bool {
+    return b.isLit
+}
+
+// GetLightRadius is the number of tiles lit, zero when the light is out.
+func (b *LightSource) GetLightRadius() int {
+    if !b.isLit {
+        return 0
+    }
+    return b.lightRadius
+}
+
 func (b *LightSource) Icon(tick uint64) int32 {
     if b.isAttached {
         return b.attachedIcon(tick)
@@ -93,9 +106,10 @@ func NewCandle(isLit bool) *LightSource {
         BaseItem: BaseItem{
             name: "candle",
         },
-        unlitIcon: 189,
-        litIcon:   190,
-        isLit:     isLit,
+        unlitIcon:   189,
+        litIcon:     190,
+        isLit:       isLit,
+        lightRadius: 3,
     }
 }
 
@@ -111,6 +125,7 @@ func NewLeftTorch(isLit bool) *LightSource {
         litIcon:           213,
         isAttached:        true,
         randomOffset:      uint64(rand.Intn(15)),
+        lightRadius:       5,
     }
 }
 
@@ -126,5 +141,6 @@ func NewRightTorch(isLit bool) *LightSource {
         litIcon:           213,
         isAttached:        true,
         randomOffset:      uint64(rand.Intn(15)),
+        lightRadius:       5,
     }
 }
@@ -0,0 +1,213 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "fmt"
+    "math/rand"
+)
+
+// Luck is neutral at 5. Every point above or below shifts the odds of crits, fumbles, loot and close skill checks.
+const neutralLuck = 5
+
+// AttackRoll is the outcome of an attack roll.
+type AttackRoll int
+
+const (
+    AttackRollFumble AttackRoll = iota
+    AttackRollMiss
+    AttackRollHit
+    AttackRollCritical
+)
+
+func (r AttackRoll) IsHit() bool {
+    return r == AttackRollHit || r == AttackRollCritical
+}
+
+func (r AttackRoll) IsCritical() bool {
+    return r == AttackRollCritical
+}
+
+func (r AttackRoll) IsFumble() bool {
+    return r == AttackRollFumble
+}
+
+func (r AttackRoll) String() string {
+    switch r {
+    case AttackRollFumble:
+        return "fumble"
+    case AttackRollMiss:
+        return "miss"
+    case AttackRollHit:
+        return "hit"
+    case AttackRollCritical:
+        return "critical hit"
+    }
+    return "unknown"
+}
+
+type FumbleKind int
+
+const (
+    FumbleKindStumble FumbleKind = iota
+    FumbleKindDropWeapon
+    FumbleKindHitAlly
+)
+
+func (a *Actor) GetLuckModifier() int {
+    return a.attributes.GetAttribute(Luck) - neutralLuck
+}
+
+// GetCriticalChance is the chance that a hit becomes a critical hit.
+func (r *Rules) GetCriticalChance(attacker *Actor) float64 {
+    return max(0.01, min(0.25, 0.05+0.01*float64(attacker.GetLuckModifier())))
+}
+
+// GetFumbleChance is the chance that a miss becomes a fumble.
+func (r *Rules) GetFumbleChance(attacker *Actor) float64 {
+    return max(0, min(0.2, 0.05-0.01*float64(attacker.GetLuckModifier())))
+}
+
+func (r *Rules) RollMeleeAttack(combatLog *CombatLog, attacker *Actor, defender *Actor) AttackRoll {
+    return r.rollAttack(combatLog, attacker, defender, "strikes", r.GetMeleeHitChance(attacker, defender), r.GetFumbleChance(attacker))
+}
+
+// RollRangedAttack never fumbles, a missed shot just flies past.
+func (r *Rules) RollRangedAttack(combatLog *CombatLog, attacker *Actor, defender *Actor) AttackRoll {
+    return r.rollAttack(combatLog, attacker, defender, "shoots at", r.GetRangedHitChance(attacker, defender), 0)
+}
+
+// rollAttack records every roll in the combat log.
+func (r *Rules) rollAttack(combatLog *CombatLog, attacker, defender *Actor, kind string, hitChance float64, fumbleChance float64) AttackRoll {
+    roll := rand.Float64()
+    if roll >= hitChance {
+        outcome := AttackRollMiss
+        if RollChance(fumbleChance) {
+            outcome = AttackRollFumble
+        }
+        combatLog.LogAttack(attacker, defender, kind, hitChance, roll, outcome, fumbleChance)
+        return outcome
+    }
+    criticalChance := r.GetCriticalChance(attacker)
+    outcome := AttackRollHit
+    if RollChance(criticalChance) {
+        outcome = AttackRollCritical
+    }
+    combatLog.LogAttack(attacker, defender, kind, hitChance, roll, outcome, criticalChance)
+    return outcome
+}
+
+// GetCriticalMeleeDamage multiplies the raw damage before the armor of the victim is applied.
+func (r *Rules) GetCriticalMeleeDamage(attacker *Actor, victim *Actor) int {
+    return r.GetMeleeDamageBreakdown(attacker, victim, true).Total
+}
+
+func (r *Rules) GetCriticalRangedDamage(attacker *Actor, victim *Actor) int {
+    return r.GetRangedDamageBreakdown(attacker, victim, true).Total
+}
+
+// GetCriticalMultiplier is taken from the weapon. Fists double the damage.
+func (a *Actor) GetCriticalMultiplier(isRanged bool) int {
+    weapon := a.GetMeleeWeapon()
+    if isRanged {
+        weapon = a.equippedRanged
+    }
+    if weapon == nil {
+        return 2
+    }
+    return weapon.GetCriticalMultiplier()
+}
+
+// OnCriticalHitPerformed triggers the special effect of the weapon used.
+func (a *Actor) OnCriticalHitPerformed(engine Engine, victim *Actor, isRanged bool) {
+    weapon := a.GetMeleeWeapon()
+    if isRanged {
+        weapon = a.equippedRanged
+    }
+    if weapon != nil && victim.IsAlive() {
+        weapon.OnCriticalHit(engine, a, victim)
+    }
+}
+
+// RollFumble decides what goes wrong. Allies standing next to the attacker are in danger,
+// otherwise armed attackers may lose their grip on the weapon.
+func (r *Rules) RollFumble(attacker *Actor, adjacentAllies []*Actor) (FumbleKind, *Actor) {
+    if len(adjacentAllies) > 0 && RollChance(0.5) {
+        return FumbleKindHitAlly, adjacentAllies[rand.Intn(len(adjacentAllies))]
+    }
+    if attacker.GetMeleeWeapon() != nil && RollChance(0.5) {
+        return FumbleKindDropWeapon, nil
+    }
+    return FumbleKindStumble, nil
+}
+
+// DropMeleeWeapon unequips the melee weapon after a fumble. It stays with the actor.
+// Party members keep it in the party inventory, everyone else in their own.
+func (a *Actor) DropMeleeWeapon() *Weapon {
+    weapon := a.GetMeleeWeapon()
+    if weapon == nil {
+        return nil
+    }
+    weapon.SetWearer(nil)
+    if a.equippedRightHand == weapon {
+        a.equippedRightHand = nil
+    }
+    if a.equippedLeftHand == weapon {
+        a.equippedLeftHand = nil
+    }
+    if a.party != nil {
+        a.party.onItemEquipStatusChanged([]Item{weapon})
+    } else if weapon.GetHolder() != a {
+        a.AddItem(weapon)
+    }
+    return weapon
+}
+
+// RollSkillCheckWithLuck works like RollSkillCheck, but luck can tip a close roll either way.
+// The second return value is true, if luck changed the outcome.
+func (r *Rules) RollSkillCheckWithLuck(skillLevel SkillLevel, difficultyLevel DifficultyLevel, luckModifier int) (bool, bool) {
+    chance := r.difficultyTable[r.GetRelativeDifficulty(skillLevel, difficultyLevel)]
+    roll := rand.Float64()
+    isSuccess := roll < chance
+    luckyChance := chance + 0.02*float64(luckModifier)
+    if luckModifier > 0 && !isSuccess && roll < luckyChance {
+        return true, true
+    }
+    if luckModifier < 0 && isSuccess && roll >= luckyChance {
+        return false, true
+    }
+    return isSuccess, false
+}
+
+// SkillCheckLuckMessage is printed when luck decided a skill check.
+func SkillCheckLuckMessage(actor *Actor, isSuccess bool) string {
+    if isSuccess {
+        return fmt.Sprintf("Luck is on the side of '%s'", actor.Name())
+    }
+    return fmt.Sprintf("Bad luck for '%s'", actor.Name())
+}
+
+// RollLootQuality returns a value between 0 and 1 used to scale loot.
+// Every point of luck above or below neutral is a 10% chance to roll again and keep the better or worse result.
+func (r *Rules) RollLootQuality(random Random, luck int) float64 {
+    quality := random.Float64()
+    luckModifier := luck - neutralLuck
+    for i := 0; i < geometry.Abs(luckModifier); i++ {
+        if random.Float64() >= 0.1 {
+            continue
+        }
+        if luckModifier > 0 {
+            quality = max(quality, random.Float64())
+        } else {
+            quality = min(quality, random.Float64())
+        }
+    }
+    return quality
+}
+
+// GetLootLevelBonus sometimes lets lucky parties find weapons and armor of a higher level.
+func (r *Rules) GetLootLevelBonus(random Random, luck int) int {
+    if random.Float64() < 0.05*float64(luck-neutralLuck) {
+        return 1
+    }
+    return 0
+}
@@ -95,6 +95,8 @@ func NewObjectFromRecord(record recfile.Record, objectTypeName string) Object {
         return NewShrineFromRecord(record)
     case "barbeceu":
         return NewBarbecueFromRecord(record)
+    case "trap":
+        return NewTrapFromRecord(record)
     }
     panic("unknown object type")
 }
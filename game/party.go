@@ -26,6 +26,7 @@ type Party struct {
     splitControlled    *Actor
     usedKeys           map[string]bool
     activeSpellEffects map[OngoingSpellEffect]int
+    formation          Formation
 }
 
 func (p *Party) Name() string {
@@ -44,6 +45,7 @@ func NewParty(leader *Actor) *Party {
         usedKeys:           make(map[string]bool),
         fov:                geometry.NewFOV(geometry.NewRect(-6, -6, 6, 6)),
         activeSpellEffects: make(map[OngoingSpellEffect]int),
+        formation:          FormationLoose,
     }
     leader.OnAddedToParty(p)
     return p
@@ -119,9 +121,12 @@ func (p *Party) RemoveItem(item Item) bool {
 }
 
 type MemberStatus struct {
-    Name        string
-    HealthIcon  int32
-    StatusColor color.Color
+    Name           string
+    HealthIcon     int32
+    StatusColor    color.Color
+    HasIndicator   bool
+    Indicator      rune
+    IndicatorColor color.Color
 }
 
 func (p *Party) Status(engine Engine) []MemberStatus {
@@ -134,10 +139,14 @@ func (p *Party) Status(engine Engine) []MemberStatus {
         } else if canLevelUp {
             nameColor = ega.BrightMagenta
         }
+        indicator, indicatorColor, hasIndicator := member.GetStatusIndicator()
         result = append(result, MemberStatus{
-            Name:        member.Name(),
-            HealthIcon:  healthToIcon(member.health, member.maxHealth),
-            StatusColor: nameColor,
+            Name:           member.Name(),
+            HealthIcon:     healthToIcon(member.health, member.maxHealth),
+            StatusColor:    nameColor,
+            HasIndicator:   hasIndicator,
+            Indicator:      indicator,
+            IndicatorColor: indicatorColor,
         })
     }
     return result
@@ -174,6 +183,11 @@ func (p *Party) GetFlatInventory() []Item {
     }
     return result
 }
+// GetConsumableActions returns the potions, wands and throwables of the party as actions.
+func (p *Party) GetConsumableActions() []Action {
+    return consumableActionsOf(p.GetFlatInventory())
+}
+
 func (p *Party) GetMembers() []*Actor {
     return p.members
 }
@@ -445,6 +459,15 @@ func (p *Party) GetCurrentMapName() string {
     return p.gridMap.GetName()
 }
 
+// GetBestLuck is the luck of the luckiest member, it decides what the party finds.
+func (p *Party) GetBestLuck() int {
+    bestLuck := 0
+    for _, member := range p.members {
+        bestLuck = max(bestLuck, member.GetAttributes().GetAttribute(Luck))
+    }
+    return bestLuck
+}
+
 func (p *Party) AddXPForEveryone(xp int) {
     for _, member := range p.members {
         member.AddXP(xp)
@@ -0,0 +1,132 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "Legacy/recfile"
+    "strconv"
+)
+
+// Patrol walks an NPC along a named path, over and over. At every waypoint it waits for a while.
+// When the NPC was moved off its route, eg. by a fight, it resumes from the nearest waypoint.
+type Patrol struct {
+    pathName    string
+    waypoints   []geometry.Point
+    nextIndex   int
+    waitMinutes int
+    waitLeft    int
+    lastPos     geometry.Point
+    hasLastPos  bool
+}
+
+func NewPatrol(pathName string, waypoints []geometry.Point, waitMinutes int) *Patrol {
+    return &Patrol{
+        pathName:    pathName,
+        waypoints:   waypoints,
+        waitMinutes: waitMinutes,
+    }
+}
+
+// NewPatrolFromRecord reads the Patrol record of an NPC file. The waypoints are set, once the map is known.
+//
+//  Path: guard_patrol
+//  Wait: 5
+func NewPatrolFromRecord(record recfile.Record) *Patrol {
+    dataMap := record.ToMap()
+    waitMinutes, _ := dataMap.GetInt("Wait")
+    return NewPatrol(dataMap["Path"], nil, waitMinutes)
+}
+
+func (p *Patrol) GetPathName() string {
+    return p.pathName
+}
+
+func (p *Patrol) HasWaypoints() bool {
+    return len(p.waypoints) > 0
+}
+
+func (p *Patrol) SetWaypoints(waypoints []geometry.Point) {
+    p.waypoints = waypoints
+    p.nextIndex = 0
+}
+
+// NextWaypoint is called once per minute of world time, with the current position of the NPC.
+// Returns false, while the NPC waits at a waypoint.
+func (p *Patrol) NextWaypoint(currentPos geometry.Point) (geometry.Point, bool) {
+    if len(p.waypoints) == 0 {
+        return geometry.Point{}, false
+    }
+    if p.hasLastPos && currentPos != p.lastPos {
+        p.resumeFromNearestWaypoint(currentPos)
+    }
+    p.lastPos = currentPos
+    p.hasLastPos = true
+    if p.waitLeft > 0 {
+        p.waitLeft--
+        return geometry.Point{}, false
+    }
+    if currentPos == p.waypoints[p.nextIndex] {
+        p.SkipWaypoint()
+        if p.waitMinutes > 0 {
+            p.waitLeft = p.waitMinutes - 1
+            return geometry.Point{}, false
+        }
+    }
+    return p.waypoints[p.nextIndex], true
+}
+
+// OnMoved must be called, when the patrol moved the NPC. Any other movement counts as an interruption.
+func (p *Patrol) OnMoved(newPos geometry.Point) {
+    p.lastPos = newPos
+}
+
+// SkipWaypoint gives up on the current waypoint, eg. when it can't be reached.
+func (p *Patrol) SkipWaypoint() {
+    p.nextIndex = (p.nextIndex + 1) % len(p.waypoints)
+}
+
+func (p *Patrol) resumeFromNearestWaypoint(pos geometry.Point) {
+    nearestDistance := -1
+    for i, waypoint := range p.waypoints {
+        if distance := geometry.DistanceManhattan(pos, waypoint); nearestDistance < 0 || distance < nearestDistance {
+            nearestDistance = distance
+            p.nextIndex = i
+        }
+    }
+    p.waitLeft = 0
+}
+
+// toRecord saves the whole route, since named paths are not part of the saved maps.
+func (p *Patrol) toRecord() recfile.Record {
+    record := recfile.Record{
+        recfile.Field{Name: "patrol", Value: recfile.ToPredicate("patrol", p.pathName, strconv.Itoa(p.nextIndex), strconv.Itoa(p.waitMinutes), strconv.Itoa(p.waitLeft))},
+    }
+    for _, waypoint := range p.waypoints {
+        record = append(record, recfile.Field{Name: "patrolWaypoint", Value: waypoint.Encode()})
+    }
+    return record
+}
+
+func newPatrolFromEncoded(value string, waypoints []geometry.Point) *Patrol {
+    predicate := recfile.StrPredicate(value)
+    if predicate == nil || predicate.ParamCount() < 4 {
+        return nil
+    }
+    patrol := NewPatrol(predicate.GetString(0), waypoints, predicate.GetInt(2))
+    if patrol.nextIndex = predicate.GetInt(1); patrol.nextIndex >= len(waypoints) {
+        patrol.nextIndex = 0
+    }
+    patrol.waitLeft = predicate.GetInt(3)
+    return patrol
+}
+
+func (a *Actor) HasPatrol() bool {
+    return a.patrol != nil && a.patrol.HasWaypoints()
+}
+
+func (a *Actor) GetPatrol() *Patrol {
+    return a.patrol
+}
+
+func (a *Actor) SetPatrol(patrol *Patrol) {
+    a.patrol = patrol
+}
@@ -1,21 +1,35 @@
 package game
 
 import (
+    "Legacy/geometry"
+    "Legacy/recfile"
     "Legacy/util"
+    "fmt"
     "image/color"
 )
 
+type PotionKind string
+
+const (
+    PotionKindMana    PotionKind = "mana"
+    PotionKindHealing PotionKind = "healing"
+)
+
 type Potion struct {
     BaseItem
     isEmpty bool
+    kind    PotionKind
 }
 
 func (b *Potion) GetTooltipLines() []string {
     if b.isEmpty {
         return []string{"Empty potion"}
-    } else {
-        return []string{"Magic potion"}
     }
+    switch b.kind {
+    case PotionKindHealing:
+        return []string{"Healing potion", fmt.Sprintf("Heals %d HP", b.healingAmount())}
+    }
+    return []string{"Magic potion", fmt.Sprintf("Restores %d mana", b.manaAmount())}
 }
 
 func (b *Potion) InventoryIcon() int32 {
@@ -23,8 +37,8 @@ func (b *Potion) InventoryIcon() int32 {
 }
 
 func (b *Potion) CanStackWith(other Item) bool {
-    if _, ok := other.(*Potion); ok {
-        return true
+    if otherPotion, ok := other.(*Potion); ok {
+        return b.kind == otherPotion.kind && b.isEmpty == otherPotion.isEmpty
     } else {
         return false
     }
@@ -58,15 +72,91 @@ func (b *Potion) SetEmpty() {
 func (b *Potion) IsEmpty() bool {
     return b.isEmpty
 }
+func (b *Potion) GetKind() PotionKind {
+    return b.kind
+}
+
+func (b *Potion) manaAmount() int {
+    return 10
+}
+
+func (b *Potion) healingAmount() int {
+    return 20
+}
+
+// OnDrink applies the effect of the potion to the drinker.
+func (b *Potion) OnDrink(engine Engine, drinker *Actor) {
+    switch b.kind {
+    case PotionKindHealing:
+        drinker.SetHealth(min(drinker.GetHealth()+b.healingAmount(), drinker.GetMaxHealth()))
+    default:
+        drinker.AddMana(b.manaAmount())
+    }
+}
+
+// NewDrinkAction lets actors in combat drink the potion.
+func (b *Potion) NewDrinkAction() *BaseAction {
+    drink := NewCombatSkill(fmt.Sprintf("Quaff %s", b.Name()), func(engine Engine, user *Actor) {
+        if !b.isEmpty {
+            engine.DrinkPotion(b, user)
+        }
+    })
+    drink.SetDescription(b.GetTooltipLines())
+    drink.SetCombatUtilityForUseAtLocation(func(engine Engine, caster *Actor, userPosition geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        if b.isEmpty {
+            return -50
+        }
+        switch b.kind {
+        case PotionKindHealing:
+            missingHealth := caster.GetMaxHealth() - caster.GetHealth()
+            if caster.GetHealth() > caster.GetMaxHealth()/2 {
+                return 0
+            }
+            return min(missingHealth, b.healingAmount()) * 2
+        }
+        for _, spell := range caster.GetEquippedSpells() {
+            if !spell.CanPayCost(engine, caster) {
+                return 30
+            }
+        }
+        return 0
+    })
+    drink.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        return 0
+    })
+    return drink
+}
+
 func NewPotion() *Potion {
     return &Potion{
         BaseItem: BaseItem{
             name:      "magic potion",
             baseValue: 250,
         },
+        kind: PotionKindMana,
     }
 }
 
+func NewHealingPotion() *Potion {
+    return &Potion{
+        BaseItem: BaseItem{
+            name:      "healing potion",
+            baseValue: 200,
+        },
+        kind: PotionKindHealing,
+    }
+}
+
+func NewPotionFromPredicate(predicate recfile.StringPredicate) *Potion {
+    if predicate.ParamCount() > 0 && PotionKind(predicate.GetString(0)) == PotionKindHealing {
+        return NewHealingPotion()
+    }
+    return NewPotion()
+}
+
 func (b *Potion) Encode() string {
+    if b.kind == PotionKindHealing {
+        return recfile.ToPredicate("potion", string(b.kind))
+    }
     return "potion()"
 }
@@ -0,0 +1,96 @@
+package game
+
+import (
+    "Legacy/recfile"
+    "strconv"
+)
+
+// QuickSlotCount is the number of hotbar slots every actor has.
+const QuickSlotCount = 4
+
+type QuickSlotKind string
+
+const (
+    QuickSlotKindSpell QuickSlotKind = "spell"
+    QuickSlotKindSkill QuickSlotKind = "skill"
+    QuickSlotKindItem  QuickSlotKind = "item"
+)
+
+// QuickSlot refers to an action by name, because the actions of consumables are created on demand.
+type QuickSlot struct {
+    Kind QuickSlotKind
+    Name string
+}
+
+func (q QuickSlot) IsEmpty() bool {
+    return q.Name == ""
+}
+
+func (q QuickSlot) encode(index int) string {
+    return recfile.ToPredicate(string(q.Kind), strconv.Itoa(index), q.Name)
+}
+
+func decodeQuickSlot(value string) (int, QuickSlot, bool) {
+    predicate := recfile.StrPredicate(value)
+    if predicate == nil || predicate.ParamCount() < 2 {
+        return 0, QuickSlot{}, false
+    }
+    index := predicate.GetInt(0)
+    if index < 0 || index >= QuickSlotCount {
+        return 0, QuickSlot{}, false
+    }
+    return index, QuickSlot{Kind: QuickSlotKind(predicate.Name()), Name: predicate.GetString(1)}, true
+}
+
+func (a *Actor) GetQuickSlot(index int) QuickSlot {
+    return a.quickSlots[index]
+}
+
+func (a *Actor) SetQuickSlot(index int, slot QuickSlot) {
+    a.quickSlots[index] = slot
+}
+
+func (a *Actor) ClearQuickSlot(index int) {
+    a.quickSlots[index] = QuickSlot{}
+}
+
+// GetQuickSlotCandidates returns everything the actor could put into a quick slot.
+func (a *Actor) GetQuickSlotCandidates() map[QuickSlotKind][]Action {
+    return map[QuickSlotKind][]Action{
+        QuickSlotKindSpell: a.GetEquippedSpells(),
+        QuickSlotKindSkill: a.GetActiveSkills(),
+        QuickSlotKindItem:  a.getUsableConsumableActions(),
+    }
+}
+
+// GetQuickSlotAction finds the action of the slot, if the actor still has it.
+func (a *Actor) GetQuickSlotAction(index int) (Action, bool) {
+    slot := a.quickSlots[index]
+    if slot.IsEmpty() {
+        return nil, false
+    }
+    for _, action := range a.GetQuickSlotCandidates()[slot.Kind] {
+        if action.Name() == slot.Name {
+            return action, true
+        }
+    }
+    return nil, false
+}
+
+// getUsableConsumableActions includes the items of the whole party, since any member can use them.
+func (a *Actor) getUsableConsumableActions() []Action {
+    if a.party != nil {
+        return a.party.GetConsumableActions()
+    }
+    return a.GetConsumableActions()
+}
+
+func (a *Actor) quickSlotsToRecord() recfile.Record {
+    var record recfile.Record
+    for index, slot := range a.quickSlots {
+        if !slot.IsEmpty() {
+            record = append(record, recfile.Field{Name: "quickSlot", Value: slot.encode(index)})
+        }
+    }
+    return record
+}
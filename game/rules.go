@@ -61,6 +61,25 @@ func RollChance(chance float64) bool {
     return rand.Float64() < chance
 }
 
+// Random is what loot creation draws from. *rand.Rand satisfies it, so generated levels can use their own seeded source.
+type Random interface {
+    Intn(n int) int
+    Float64() float64
+}
+
+type globalRandom struct{}
+
+func (globalRandom) Intn(n int) int {
+    return rand.Intn(n)
+}
+
+func (globalRandom) Float64() float64 {
+    return rand.Float64()
+}
+
+// GlobalRandom draws from the shared source of math/rand.
+var GlobalRandom Random = globalRandom{}
+
 func (r *Rules) GetBaseValueOfLockpick() int {
     return 50
 }
@@ -155,24 +174,105 @@ func (r *Rules) LevelUp(a *Actor) {
     a.health = a.maxHealth
 }
 
-func (r *Rules) GetMeleeDamage(attacker *Actor, victim *Actor) int {
-    baseMeleeDamage := attacker.GetMeleeDamage()
+// DamageBreakdown holds the terms of a damage calculation, so they can be shown in the combat log.
+type DamageBreakdown struct {
+    Base          int
+    Multiplier    int
+    Reduction     int
+    ReductionName string
+    DamageType    DamageType
+    Resistance    int
+    Percent       int
+    Total         int
+}
+
+func (d DamageBreakdown) String() string {
+    text := fmt.Sprintf("%d", d.Base)
+    if d.Multiplier > 1 {
+        text += fmt.Sprintf(" x%d", d.Multiplier)
+    }
+    if d.Reduction != 0 {
+        text += fmt.Sprintf(" - %d %s", d.Reduction, d.ReductionName)
+    }
+    if d.Resistance != 0 {
+        text += fmt.Sprintf(", %d%% %s resistance", d.Resistance, d.DamageType)
+    }
+    if d.Percent != 100 {
+        text += fmt.Sprintf(", %d%%", d.Percent)
+    }
+    return text + fmt.Sprintf(" = %d %s", d.Total, d.DamageType)
+}
+
+// Scaled is used by weapon skills, that only deal a part of the usual damage.
+func (d DamageBreakdown) Scaled(percent int) DamageBreakdown {
+    d.Percent = percent
+    if d.Total > 0 {
+        d.Total = max(1, d.Total*percent/100)
+    }
+    return d
+}
+
+// newPhysicalDamageBreakdown applies armor first. A hit that isn't fully resisted always does at least one damage.
+func newPhysicalDamageBreakdown(base, multiplier int, damageType DamageType, victim *Actor) DamageBreakdown {
+    rawDamage := base * multiplier
     victimArmor := victim.GetTotalArmor()
-    meleeDamage := max(baseMeleeDamage-victimArmor, 0)
-    if meleeDamage == 0 && baseMeleeDamage > 0 {
-        meleeDamage = 1
+    damage := max(rawDamage-victimArmor, 0)
+    if damage == 0 && rawDamage > 0 {
+        damage = 1
+    }
+    return DamageBreakdown{
+        Base:          base,
+        Multiplier:    multiplier,
+        Reduction:     victimArmor,
+        ReductionName: "armor",
+        DamageType:    damageType,
+        Resistance:    victim.GetResistances().Get(damageType),
+        Percent:       100,
+        Total:         victim.GetResistances().Apply(damage, damageType),
+    }
+}
+
+func (r *Rules) GetMeleeDamageBreakdown(attacker *Actor, victim *Actor, isCritical bool) DamageBreakdown {
+    multiplier := 1
+    if isCritical {
+        multiplier = attacker.GetCriticalMultiplier(false)
     }
-    return meleeDamage
+    return newPhysicalDamageBreakdown(attacker.GetMeleeDamage(), multiplier, attacker.GetMeleeDamageType(), victim)
+}
+
+func (r *Rules) GetRangedDamageBreakdown(attacker *Actor, victim *Actor, isCritical bool) DamageBreakdown {
+    multiplier := 1
+    if isCritical {
+        multiplier = attacker.GetCriticalMultiplier(true)
+    }
+    return newPhysicalDamageBreakdown(attacker.GetRangedDamage(), multiplier, attacker.GetRangedDamageType(), victim)
+}
+
+func (r *Rules) GetMeleeDamage(attacker *Actor, victim *Actor) int {
+    return r.GetMeleeDamageBreakdown(attacker, victim, false).Total
 }
 
 func (r *Rules) GetRangedDamage(attacker *Actor, victim *Actor) int {
-    baseRangedDamage := attacker.GetRangedDamage()
-    victimArmor := victim.GetTotalArmor()
-    rangedDamage := max(0, baseRangedDamage-victimArmor)
-    if rangedDamage == 0 && baseRangedDamage > 0 {
-        rangedDamage = 1
+    return r.GetRangedDamageBreakdown(attacker, victim, false).Total
+}
+
+func (r *Rules) GetSpellDamageBreakdown(victim *Actor, amount int, damageType DamageType) DamageBreakdown {
+    magicDefense := victim.GetMagicDefense()
+    return DamageBreakdown{
+        Base:          amount,
+        Multiplier:    1,
+        Reduction:     magicDefense,
+        ReductionName: "magic defense",
+        DamageType:    damageType,
+        Resistance:    victim.GetResistances().Get(damageType),
+        Percent:       100,
+        Total:         victim.GetResistances().Apply(max(0, amount-magicDefense), damageType),
     }
-    return rangedDamage
+}
+
+// GetSpellDamage is used for spells, thrown items and other damage that ignores armor.
+func (r *Rules) GetSpellDamage(victim *Actor, amount int, damageType DamageType) int {
+    return r.GetSpellDamageBreakdown(victim, amount, damageType).Total
 }
 
 func (r *Rules) DoesMeleeAttackHit(attacker *Actor, defender *Actor) bool {
@@ -185,12 +285,26 @@ func (r *Rules) DoesRangedAttackHit(attacker *Actor, defender *Actor) bool {
     return RollChance(chance)
 }
 
+func (r *Rules) GetShieldBlockChance(defender *Actor) float64 {
+    if !defender.HasShieldEquipped() {
+        return 0
+    }
+    if defender.HasStatusEffectWithName(StatusEffectNameBlocking) {
+        return 0.5
+    }
+    return 0.15
+}
+
+func (r *Rules) DoesShieldBlock(defender *Actor) bool {
+    return RollChance(r.GetShieldBlockChance(defender))
+}
+
 func (r *Rules) GetMeleeHitChance(attacker *Actor, defender *Actor) float64 {
     skillActor := attacker.GetSkills().GetSkillLevel(PhysicalSkillMeleeCombat)
     skillAdversary := defender.GetSkills().GetSkillLevel(PhysicalSkillMeleeCombat)
     relativeDifficulty := DifficultyLevelFromInt(max(-1, min(7, (int(skillAdversary)-int(skillActor))-1)))
     chance := r.difficultyTable[relativeDifficulty]
-    return chance
+    return chance * r.getSightHitFactor(attacker, defender, 0.5)
 }
 
 func (r *Rules) GetRangedHitChance(attacker *Actor, defender *Actor) float64 {
@@ -198,7 +312,19 @@ func (r *Rules) GetRangedHitChance(attacker *Actor, defender *Actor) float64 {
     absoluteDiff := max(defender.GetAbsoluteDifficultyByAttribute(Agility), defender.GetAbsoluteDifficultyByAttribute(Perception))
     relativeDiff := r.GetRelativeDifficulty(skillActor, absoluteDiff).ReducedBy(1)
     chance := r.difficultyTable[relativeDiff]
-    return chance
+    return chance * r.getSightHitFactor(attacker, defender, 0.25)
+}
+
+// getSightHitFactor lowers the chance to hit for blinded attackers and against invisible defenders.
+func (r *Rules) getSightHitFactor(attacker *Actor, defender *Actor, blindedFactor float64) float64 {
+    factor := 1.0
+    if attacker.IsBlinded() {
+        factor *= blindedFactor
+    }
+    if defender.IsInvisible() {
+        factor *= 0.5
+    }
+    return factor
 }
 
 func (r *Rules) GetMinutesPerStepInLevels() int {
@@ -249,6 +375,46 @@ func (r *Rules) GetSkillCheckTable() []string {
     return layout
 }
 
-func (r *Rules) GetCriminalOffenseEvent(nameOfCurrentMap string) string {
-    return "tauci_criminal_offense"
+func (r *Rules) GetBountyForCrime(crime CrimeType) int {
+    switch crime {
+    case CrimeTheft:
+        return 100
+    case CrimePlanting:
+        return 50
+    case CrimeTrespassing:
+        return 50
+    case CrimeAssault:
+        return 250
+    case CrimeMurder:
+        return 1000
+    }
+    return 0
+}
+
+func (r *Rules) GetWitnessRadius() int {
+    return 8
+}
+
+func (r *Rules) GetBountyDecayPerDay() int {
+    return 25
+}
+
+func (r *Rules) GetFineForBounty(bounty int) int {
+    return bounty
+}
+
+func (r *Rules) GetJailDaysForBounty(bounty int) int {
+    return max(1, bounty/100)
+}
+
+// GetMorale returns the current morale of an actor in combat.
+// Morale drops with lost health and with every ally that died in this fight.
+// An actor with a morale of zero or less will try to flee or surrender.
+func (r *Rules) GetMorale(actor *Actor, alliesLost int) int {
+    lostHealthPercent := 100 - (actor.GetHealth()*100)/max(1, actor.GetMaxHealth())
+    return actor.GetBaseMorale() - (lostHealthPercent / 15) - (2 * alliesLost)
+}
+
+func (r *Rules) GetFleeSafeDistance() int {
+    return 12
 }
@@ -0,0 +1,239 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "Legacy/recfile"
+    "fmt"
+    "strconv"
+)
+
+// ScheduleActivity is what an NPC does during a part of the day.
+type ScheduleActivity string
+
+const (
+    ScheduleActivityIdle  ScheduleActivity = "idle"
+    ScheduleActivityWork  ScheduleActivity = "work"
+    ScheduleActivityEat   ScheduleActivity = "eat"
+    ScheduleActivitySleep ScheduleActivity = "sleep"
+)
+
+// ScheduleHomeLocation is where the NPC was placed on the map.
+const ScheduleHomeLocation = "home"
+
+// ScheduleEntry sends an NPC somewhere for a part of the day.
+// Location is "home", a named location or an encoded position like (12,30).
+// Path is the name of a path on the map, which is walked once.
+type ScheduleEntry struct {
+    From     int // minutes since midnight
+    To       int // can be smaller than From, for entries that last through the night
+    Location string
+    Path     string
+    Activity ScheduleActivity
+}
+
+func (e ScheduleEntry) Contains(minuteOfDay int) bool {
+    if e.From <= e.To {
+        return minuteOfDay >= e.From && minuteOfDay < e.To
+    }
+    return minuteOfDay >= e.From || minuteOfDay < e.To
+}
+
+func (e ScheduleEntry) HoursText() string {
+    return fmt.Sprintf("%s and %s", minuteOfDayToString(e.From), minuteOfDayToString(e.To))
+}
+
+// encode separates the parameters with '|', since locations can be positions like (12,30).
+func (e ScheduleEntry) encode() string {
+    return recfile.ToPredicateSep("entry", "|", minuteOfDayToString(e.From), minuteOfDayToString(e.To), e.Location, e.Path, string(e.Activity))
+}
+
+func decodeScheduleEntry(value string) (ScheduleEntry, bool) {
+    predicate := recfile.StrPredicateSep(value, "|")
+    if predicate == nil || predicate.ParamCount() < 5 {
+        return ScheduleEntry{}, false
+    }
+    return ScheduleEntry{
+        From:     minuteOfDayFromString(predicate.GetString(0)),
+        To:       minuteOfDayFromString(predicate.GetString(1)),
+        Location: predicate.GetString(2),
+        Path:     predicate.GetString(3),
+        Activity: ScheduleActivity(predicate.GetString(4)),
+    }, true
+}
+
+// Schedule is the daily routine of an NPC. It keeps track of the route to the current destination.
+type Schedule struct {
+    entries     []ScheduleEntry
+    activeIndex int
+    waypoints   []geometry.Point
+}
+
+// NewScheduleFromRecords reads the entries from the Schedule records of an NPC file.
+//
+//  From: 08:00
+//  To: 18:00
+//  Location: home
+//  Activity: work
+func NewScheduleFromRecords(records []recfile.Record) *Schedule {
+    schedule := &Schedule{activeIndex: -1}
+    for _, record := range records {
+        entry := ScheduleEntry{Activity: ScheduleActivityIdle}
+        for _, field := range record {
+            switch field.Name {
+            case "From":
+                entry.From = minuteOfDayFromString(field.Value)
+            case "To":
+                entry.To = minuteOfDayFromString(field.Value)
+            case "Location":
+                entry.Location = field.Value
+            case "Path":
+                entry.Path = field.Value
+            case "Activity":
+                entry.Activity = ScheduleActivity(field.Value)
+            }
+        }
+        schedule.entries = append(schedule.entries, entry)
+    }
+    return schedule
+}
+
+// toRecord saves the entries along with the current route, since saved maps don't read the NPC files again.
+func (s *Schedule) toRecord() recfile.Record {
+    record := recfile.Record{
+        recfile.Field{Name: "scheduleActive", Value: strconv.Itoa(s.activeIndex)},
+    }
+    for _, entry := range s.entries {
+        record = append(record, recfile.Field{Name: "scheduleEntry", Value: entry.encode()})
+    }
+    for _, waypoint := range s.waypoints {
+        record = append(record, recfile.Field{Name: "scheduleWaypoint", Value: waypoint.Encode()})
+    }
+    return record
+}
+
+func newScheduleFromEncoded(activeIndex int, entries []ScheduleEntry, waypoints []geometry.Point) *Schedule {
+    if activeIndex >= len(entries) {
+        activeIndex = -1
+    }
+    return &Schedule{
+        entries:     entries,
+        activeIndex: activeIndex,
+        waypoints:   waypoints,
+    }
+}
+
+func minuteOfDayFromString(value string) int {
+    var hours, minutes int
+    _, err := fmt.Sscanf(value, "%d:%d", &hours, &minutes)
+    if err != nil {
+        println(fmt.Sprintf("ERROR: invalid time of day in schedule '%s'", value))
+        return 0
+    }
+    return (hours%HoursPerDay)*MinutesPerHour + minutes
+}
+
+func minuteOfDayToString(minuteOfDay int) string {
+    return fmt.Sprintf("%02d:%02d", minuteOfDay/MinutesPerHour, minuteOfDay%MinutesPerHour)
+}
+
+// Update picks the entry for the time of day. Returns true, if it changed and a new route is needed.
+func (s *Schedule) Update(minuteOfDay int) bool {
+    for i, entry := range s.entries {
+        if entry.Contains(minuteOfDay) {
+            if i == s.activeIndex {
+                return false
+            }
+            s.activeIndex = i
+            s.waypoints = nil
+            return true
+        }
+    }
+    return false
+}
+
+func (s *Schedule) ActiveEntry() (ScheduleEntry, bool) {
+    if s.activeIndex < 0 || s.activeIndex >= len(s.entries) {
+        return ScheduleEntry{}, false
+    }
+    return s.entries[s.activeIndex], true
+}
+
+func (s *Schedule) SetRoute(waypoints []geometry.Point) {
+    s.waypoints = waypoints
+}
+
+func (s *Schedule) NextWaypoint() (geometry.Point, bool) {
+    if len(s.waypoints) == 0 {
+        return geometry.Point{}, false
+    }
+    return s.waypoints[0], true
+}
+
+func (s *Schedule) ReachedWaypoint() {
+    if len(s.waypoints) > 0 {
+        s.waypoints = s.waypoints[1:]
+    }
+}
+
+// SkipRoute returns the end of the current route and forgets the rest of it.
+func (s *Schedule) SkipRoute() (geometry.Point, bool) {
+    if len(s.waypoints) == 0 {
+        return geometry.Point{}, false
+    }
+    destination := s.waypoints[len(s.waypoints)-1]
+    s.waypoints = nil
+    return destination, true
+}
+
+// IsWorkingAt is true, if the NPC is at work at that time of the day.
+func (s *Schedule) IsWorkingAt(minuteOfDay int) bool {
+    for _, entry := range s.entries {
+        if entry.Contains(minuteOfDay) {
+            return entry.Activity == ScheduleActivityWork
+        }
+    }
+    return false
+}
+
+// GetOpeningHours describes the work hours, eg. "08:00 and 12:00, 13:00 and 18:00".
+func (s *Schedule) GetOpeningHours() string {
+    hours := ""
+    for _, entry := range s.entries {
+        if entry.Activity != ScheduleActivityWork {
+            continue
+        }
+        if hours != "" {
+            hours += ", "
+        }
+        hours += entry.HoursText()
+    }
+    return hours
+}
+
+func (w WorldTime) MinuteOfDay() int {
+    return w.minutes
+}
+
+func (a *Actor) HasSchedule() bool {
+    return a.schedule != nil
+}
+
+func (a *Actor) GetSchedule() *Schedule {
+    return a.schedule
+}
+
+func (a *Actor) SetHomePosition(pos geometry.Point) {
+    a.homePosition = pos
+}
+
+func (a *Actor) GetHomePosition() geometry.Point {
+    return a.homePosition
+}
+
+// IsOpenForBusiness is true for vendors without a schedule or while they are at work.
+func (a *Actor) IsOpenForBusiness(worldTime WorldTime) bool {
+    if !a.HasSchedule() {
+        return true
+    }
+    return a.schedule.IsWorkingAt(worldTime.MinuteOfDay())
+}
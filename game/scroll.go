@@ -85,7 +85,13 @@ func (b *Scroll) GetContextActions(engine Engine) []util.MenuItem {
         } else {
             actions = append(actions, util.MenuItem{
                 Text:   fmt.Sprintf("Execute \"%s\"", b.spell.name),
-                Action: func() { b.spell.Execute(engine, engine.GetAvatar()) },
+                Action: func() {
+                    if !b.spell.CanPayCost(engine, engine.GetAvatar()) {
+                        engine.Print("Not enough mana!")
+                        return
+                    }
+                    b.spell.Execute(engine, engine.GetAvatar())
+                },
             })
         }
 
@@ -3,7 +3,7 @@ package game
 import (
     "Legacy/ega"
     "Legacy/geometry"
-    "Legacy/renderer"
+    "Legacy/renderer/atlas"
     "image/color"
 )
 
@@ -18,8 +18,8 @@ func NewActiveSkillFromName(name SkillName) *BaseAction {
 func NewMeleeAttackWithFixedDamage(name string, damage int) *BaseAction {
     jab := NewTargetedCombatSkill(name, func(engine Engine, caster *Actor, pos geometry.Point) {
         bloodIcon := int32(104)
-        engine.CombatHitAnimation(pos, renderer.AtlasWorld, bloodIcon, ega.BrightWhite, func() {
-            engine.FixedDamageAt(caster, pos, damage)
+        engine.CombatHitAnimation(pos, atlas.World, bloodIcon, ega.BrightWhite, func() {
+            engine.FixedDamageAt(caster, pos, damage, DamageTypeBlunt)
         })
     })
     jab.SetValidTargets(func(engine Engine, caster *Actor, usePosition geometry.Point) map[geometry.Point]bool {
@@ -34,6 +34,9 @@ func NewMeleeAttackWithFixedDamage(name string, damage int) *BaseAction {
         }
         return result
     })
+    jab.SetHitPreview(func(engine Engine, caster *Actor, victim *Actor) (float64, int) {
+        return 1.0, engine.GetRules().GetSpellDamage(victim, damage, DamageTypeBlunt)
+    })
     jab.SetDescription([]string{
         "Jab an adjacent enemy with your tentacle.",
     })
@@ -75,3 +78,39 @@ func NewTargetedCombatSkill(name string, effect func(engine Engine, user *Actor,
         color: color.White,
     }
 }
+
+// NewDisengageSkill moves the user to a free tile nearby, without provoking opportunity attacks.
+func NewDisengageSkill(name string, distance int) *BaseAction {
+    disengage := NewTargetedCombatSkill(name, func(engine Engine, user *Actor, pos geometry.Point) {
+        if engine.GetGridMap().IsCurrentlyPassable(pos) {
+            engine.Reposition(user, pos)
+        }
+    })
+    disengage.SetValidTargets(freeTilesInReach(distance))
+    disengage.SetCombatUtilityForTargetedUseOnLocation(disengageUtility)
+    return disengage
+}
+
+func freeTilesInReach(distance int) func(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool {
+    return func(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool {
+        gridMap := engine.GetGridMap()
+        result := make(map[geometry.Point]bool)
+        for pos, _ := range gridMap.GetDijkstraMap(usePosition, distance, gridMap.IsCurrentlyPassable) {
+            if pos != usePosition {
+                result[pos] = true
+            }
+        }
+        return result
+    }
+}
+
+// disengageUtility is high for skirmishers and wounded actors, that are caught in a zone of control.
+func disengageUtility(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+    gridMap := engine.GetGridMap()
+    threats := len(GetOpportunityAttackers(gridMap, caster, caster.Pos()))
+    wantsOut := caster.GetCombatBehavior().KeepsDistance() || caster.GetHealth() < caster.GetMaxHealth()/2
+    if threats == 0 || !wantsOut || len(GetOpportunityAttackers(gridMap, caster, target)) > 0 {
+        return -10
+    }
+    return threats * 15
+}
@@ -4,7 +4,7 @@ import (
     "Legacy/ega"
     "Legacy/geometry"
     "Legacy/gridmap"
-    "Legacy/renderer"
+    "Legacy/renderer/atlas"
     "fmt"
     "image/color"
     "math/rand"
@@ -50,6 +50,16 @@ func GetAllSpellNames() []string {
         "Fireball",
         "Icebolt",
         "Healing word of Tauci",
+        "Cause Fear",
+        "Blind",
+        "Invisibility",
+        "Poison Cloud",
+        "Recharge",
+        "Blink",
+        "Summon Monster",
+        "Charm",
+        "Haste",
+        "Slow",
     }
     return names
 }
@@ -65,10 +75,26 @@ func GetSpellNamesByLevel(level int) []string {
         return []string{
             "Bird's Eye",
             "Healing word of Tauci",
+            "Blink",
+        }
+    case 3:
+        return []string{
+            "Cause Fear",
+            "Blind",
+            "Recharge",
+            "Slow",
+        }
+    case 4:
+        return []string{
+            "Invisibility",
+            "Poison Cloud",
+            "Summon Monster",
+            "Haste",
         }
     case 5:
         return []string{
             "Icebolt",
+            "Charm",
         }
     case 6:
         return []string{
@@ -123,7 +149,7 @@ func NewSpellFromName(name string) *Spell {
             healthIncrease := 10 * caster.GetLevel()
             newHealth := min(caster.GetHealth()+healthIncrease, caster.GetMaxHealth())
             caster.SetHealth(newHealth)
-            engine.Print("You feel better!")
+            engine.Print(fmt.Sprintf("%s feels better!", caster.Name()))
         })
         spell.SetDescription([]string{
             "Heal yourself.",
@@ -201,16 +227,22 @@ func NewSpellFromName(name string) *Spell {
             hitPositions := currentMap.GetDijkstraMapWithActorsNotBlocking(pos, radius)
             for p, _ := range hitPositions {
                 hitPos := p
-                engine.CombatHitAnimation(hitPos, renderer.AtlasEntitiesGrayscale, explosionIcon, ega.BrightRed, func() {
-                    engine.FixedDamageAt(caster, hitPos, fireballDamagePerTile)
+                engine.CombatHitAnimation(hitPos, atlas.EntitiesGrayscale, explosionIcon, ega.BrightRed, func() {
+                    engine.FixedDamageAt(caster, hitPos, fireballDamagePerTile, DamageTypeFire)
+                    engine.IgniteAt(hitPos)
                 })
             }
         })
 
         fireball.SetValidTargets(allVisibleTilesInRadius(15))
+        fireball.SetAffectedPositions(allReachableTilesInRadius(radius))
+        fireball.SetHitPreview(func(engine Engine, caster *Actor, victim *Actor) (float64, int) {
+            return 1.0, engine.GetRules().GetSpellDamage(victim, 10*caster.GetLevel(), DamageTypeFire)
+        })
         fireball.SetDescription([]string{
             "Burn everything in a 3 tile radius.",
-            "Damage: level of caster x 10 HP",
+            "Damage: level of caster x 10 HP (fire)",
+            "Sets forests and doors on fire.",
             "Range: 15 tiles",
         })
         fireball.SetScrollTitle("Fire - the great equalizer")
@@ -248,16 +280,21 @@ func NewSpellFromName(name string) *Spell {
             hitPositions := currentMap.GetDijkstraMapWithActorsNotBlocking(pos, radius)
             for p, _ := range hitPositions {
                 hitPos := p
-                engine.CombatHitAnimation(hitPos, renderer.AtlasEntitiesGrayscale, explosionIcon, ega.BrightBlue, func() {
-                    engine.FixedDamageAt(caster, hitPos, iceboltDamage)
+                engine.CombatHitAnimation(hitPos, atlas.EntitiesGrayscale, explosionIcon, ega.BrightBlue, func() {
+                    engine.FixedDamageAt(caster, hitPos, iceboltDamage, DamageTypeCold)
                     engine.FreezeActorAt(hitPos, 3)
+                    engine.ExtinguishAt(hitPos)
                 })
             }
         })
         icebolt.SetValidTargets(allVisibleTilesInRadius(12))
+        icebolt.SetAffectedPositions(allReachableTilesInRadius(radius))
+        icebolt.SetHitPreview(func(engine Engine, caster *Actor, victim *Actor) (float64, int) {
+            return 1.0, engine.GetRules().GetSpellDamage(victim, caster.GetLevel(), DamageTypeCold)
+        })
         icebolt.SetDescription([]string{
             "Freeze everything in a 3 tile radius.",
-            "Damage: level of caster x 1 HP",
+            "Damage: level of caster x 1 HP (cold)",
             "Applies freeze for 3 turns.",
             "Range: 12 tiles",
         })
@@ -284,10 +321,266 @@ func NewSpellFromName(name string) *Spell {
         })
         icebolt.SetMonetaryValue(13000)
         return icebolt
+    case "Cause Fear":
+        spell := newStatusSpell(name, 8, 8, ega.Yellow, func() StatusEffect { return StatusFrightened() })
+        spell.SetDescription([]string{
+            "Fill the heart of your enemy with dread.",
+            "The target flees for 3 turns.",
+            "Range: 8 tiles",
+        })
+        spell.SetScrollTitle("Of things that go bump")
+        spell.SetScrollFile("cause_fear")
+        spell.SetMonetaryValue(6000)
+        return spell
+    case "Blind":
+        spell := newStatusSpell(name, 8, 8, ega.BrightBlack, func() StatusEffect { return StatusBlinded() })
+        spell.SetDescription([]string{
+            "Take the sight of your enemy.",
+            "The target misses more often for 3 turns.",
+            "Range: 8 tiles",
+        })
+        spell.SetScrollTitle("Darkness before the eyes")
+        spell.SetScrollFile("blind")
+        spell.SetMonetaryValue(6000)
+        return spell
+    case "Slow":
+        spell := newStatusSpell(name, 8, 8, ega.BrightMagenta, func() StatusEffect { return StatusSlowed() })
+        spell.SetDescription([]string{
+            "Make your enemy sluggish.",
+            "Less initiative and movement for 3 turns.",
+            "Range: 8 tiles",
+        })
+        spell.SetScrollTitle("Molasses in the veins")
+        spell.SetScrollFile("slow")
+        spell.SetMonetaryValue(6000)
+        return spell
+    case "Haste":
+        spell := NewTargetedSpell(name, 10, func(engine Engine, caster *Actor, pos geometry.Point) {
+            currentMap := engine.GetGridMap()
+            engine.CombatHitAnimation(pos, atlas.EntitiesGrayscale, int32(28), ega.BrightYellow, func() {
+                if currentMap.IsActorAt(pos) {
+                    engine.AddStatusEffect(currentMap.ActorAt(pos), StatusHasted(), 1)
+                }
+            })
+        })
+        spell.SetValidTargets(allVisibleTilesInRadius(5))
+        spell.SetDescription([]string{
+            "Quicken yourself or an ally.",
+            "More initiative and movement for 3 turns.",
+            "Range: 5 tiles",
+        })
+        spell.SetScrollTitle("The hare's blessing")
+        spell.SetScrollFile("haste")
+        spell.SetActionColor(ega.BrightYellow)
+        spell.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+            ally, isAlly := allyPositions[target]
+            if target == caster.Pos() {
+                ally, isAlly = caster, true
+            }
+            if !isAlly || ally.HasStatusEffectWithName(StatusEffectNameHasted) || len(enemyPositions) == 0 {
+                return -10
+            }
+            return 20
+        })
+        spell.SetMonetaryValue(9000)
+        return spell
+    case "Invisibility":
+        spell := NewSpell(name, 12, func(engine Engine, caster *Actor) {
+            engine.AddStatusEffect(caster, StatusInvisible(), 1)
+        })
+        spell.SetDescription([]string{
+            "Vanish from the sight of your enemies.",
+            "Lasts 10 turns or until you attack.",
+        })
+        spell.SetScrollTitle("Now you see me")
+        spell.SetScrollFile("invisibility")
+        spell.SetActionColor(ega.BrightCyan)
+        spell.SetCombatUtilityForUseAtLocation(func(engine Engine, caster *Actor, userPosition geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+            if caster.IsInvisible() || caster.GetHealth() > caster.GetMaxHealth()/2 {
+                return -50
+            }
+            return 30
+        })
+        spell.SetMonetaryValue(9000)
+        return spell
+    case "Poison Cloud":
+        radius := 2
+        spell := NewTargetedSpell(name, 12, func(engine Engine, caster *Actor, pos geometry.Point) {
+            cloudIcon := int32(28)
+            currentMap := engine.GetGridMap()
+            hitPositions := currentMap.GetDijkstraMapWithActorsNotBlocking(pos, radius)
+            for p, _ := range hitPositions {
+                hitPos := p
+                engine.CombatHitAnimation(hitPos, atlas.EntitiesGrayscale, cloudIcon, ega.BrightGreen, func() {
+                    if currentMap.IsActorAt(hitPos) {
+                        engine.AddStatusEffect(currentMap.ActorAt(hitPos), StatusPoisoned(), 2)
+                    }
+                })
+            }
+        })
+        spell.SetValidTargets(allVisibleTilesInRadius(10))
+        spell.SetAffectedPositions(allReachableTilesInRadius(radius))
+        spell.SetDescription([]string{
+            "Poison everything in a 2 tile radius.",
+            "Damage: 2 HP per turn for 5 turns",
+            "Range: 10 tiles",
+        })
+        spell.SetScrollTitle("The breath of the swamp")
+        spell.SetScrollFile("poison_cloud")
+        spell.SetActionColor(ega.BrightGreen)
+        spell.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+            currentMap := engine.GetGridMap()
+            hitPositions := currentMap.GetDijkstraMapWithActorsNotBlocking(target, radius)
+            totalUtility := 0
+            for p, _ := range hitPositions {
+                if enemy, ok := enemyPositions[p]; ok && !enemy.IsImmuneTo(StatusEffectNamePoisoned) {
+                    totalUtility += 15
+                }
+                if _, ok := allyPositions[p]; ok {
+                    totalUtility -= 25
+                }
+                if p == caster.Pos() {
+                    totalUtility -= 50
+                }
+            }
+            return totalUtility
+        })
+        spell.SetMonetaryValue(10000)
+        return spell
+    case "Recharge":
+        spell := NewSpell(name, 15, func(engine Engine, caster *Actor) {
+            wand, hasWand := findWandToRecharge(engine, caster)
+            if !hasWand {
+                engine.Print("There is nothing to recharge.")
+                return
+            }
+            wand.Recharge()
+            engine.Print(fmt.Sprintf("The %s hums with new power.", wand.Name()))
+        })
+        spell.SetDescription([]string{
+            "Restores all charges of a wand.",
+            "Wands in your hands come first.",
+        })
+        spell.SetScrollTitle("A second wind")
+        spell.SetScrollFile("recharge")
+        spell.SetActionColor(ega.BrightMagenta)
+        spell.SetNoCombatUtility()
+        spell.SetMonetaryValue(8000)
+        return spell
+    case "Blink":
+        blinkRange := 5
+        spell := NewTargetedSpell(name, 6, func(engine Engine, caster *Actor, pos geometry.Point) {
+            if engine.GetGridMap().IsCurrentlyPassable(pos) {
+                engine.Reposition(caster, pos)
+            }
+        })
+        spell.SetValidTargets(func(engine Engine, caster *Actor, usePos geometry.Point) map[geometry.Point]bool {
+            gridMap := engine.GetGridMap()
+            validPositions := allVisibleTilesInRadius(blinkRange)(engine, caster, usePos)
+            for pos, _ := range validPositions {
+                if pos == usePos || !gridMap.IsCurrentlyPassable(pos) {
+                    delete(validPositions, pos)
+                }
+            }
+            return validPositions
+        })
+        spell.SetDescription([]string{
+            "Step out of melee to a free tile.",
+            "Does not provoke attacks.",
+            "Range: 5 tiles",
+        })
+        spell.SetScrollTitle("Here and there")
+        spell.SetScrollFile("blink")
+        spell.SetActionColor(ega.BrightBlue)
+        spell.SetCombatUtilityForTargetedUseOnLocation(disengageUtility)
+        spell.SetMonetaryValue(4000)
+        return spell
+    case "Summon Monster":
+        summonRange := 3
+        spell := NewTargetedSpell(name, 12, func(engine Engine, caster *Actor, pos geometry.Point) {
+            if !engine.GetGridMap().IsCurrentlyPassable(pos) {
+                engine.Print("There is no room for anything to appear.")
+                return
+            }
+            monster := GetSummonableMonster(caster.GetLevel())
+            engine.SummonMonsterAt(caster, pos, monster, engine.GetRules().GetSummonDuration(caster.GetLevel()))
+        })
+        spell.SetValidTargets(freeTilesForSummoning(summonRange))
+        spell.SetDescription([]string{
+            "Call a creature to fight at your side.",
+            "Stronger casters call stronger creatures.",
+            "Lasts 4 turns and 1 more per 2 levels.",
+            "Range: 3 tiles",
+        })
+        spell.SetScrollTitle("Something answers")
+        spell.SetScrollFile("summon_monster")
+        spell.SetActionColor(ega.Green)
+        spell.SetCombatUtilityForTargetedUseOnLocation(summonUtility)
+        spell.SetMonetaryValue(9000)
+        return spell
+    case "Charm":
+        charmRange := 6
+        spell := NewTargetedSpell(name, 10, func(engine Engine, caster *Actor, pos geometry.Point) {
+            currentMap := engine.GetGridMap()
+            engine.CombatHitAnimation(pos, atlas.EntitiesGrayscale, int32(28), ega.BrightMagenta, func() {
+                if !currentMap.IsActorAt(pos) {
+                    return
+                }
+                victim := currentMap.ActorAt(pos)
+                if !victim.GetCreatureType().CanBeCharmed() || !RollChance(engine.GetRules().GetCharmChance(caster, victim)) {
+                    engine.Print(fmt.Sprintf("'%s' resists the charm", victim.Name()))
+                    return
+                }
+                engine.Charm(caster, victim, 3)
+            })
+        })
+        spell.SetValidTargets(allVisibleTilesInRadius(charmRange))
+        spell.SetDescription([]string{
+            "Turn an enemy into a friend for 3 turns.",
+            "Clever casters succeed more often.",
+            "Does not work on the undead and the mindless.",
+            "Range: 6 tiles",
+        })
+        spell.SetScrollTitle("A friend in need")
+        spell.SetScrollFile("charm")
+        spell.SetActionColor(ega.BrightMagenta)
+        spell.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+            enemy, isEnemy := enemyPositions[target]
+            if !isEnemy || !engine.IsPlayerControlled(caster) || !enemy.GetCreatureType().CanBeCharmed() {
+                return -10
+            }
+            return 20 + enemy.GetLevel()
+        })
+        spell.SetMonetaryValue(12000)
+        return spell
     }
 
     return nil
 }
+// newStatusSpell puts a status effect on a single target.
+// The AI will use it on enemies that are not yet affected and not immune.
+func newStatusSpell(name string, manaCost int, spellRange int, spellColor color.Color, newEffect func() StatusEffect) *Spell {
+    effectName := newEffect().Name()
+    spell := NewTargetedSpell(name, manaCost, func(engine Engine, caster *Actor, pos geometry.Point) {
+        currentMap := engine.GetGridMap()
+        engine.CombatHitAnimation(pos, atlas.EntitiesGrayscale, int32(28), spellColor, func() {
+            if currentMap.IsActorAt(pos) {
+                engine.AddStatusEffect(currentMap.ActorAt(pos), newEffect(), 1)
+            }
+        })
+    })
+    spell.SetValidTargets(allVisibleTilesInRadius(spellRange))
+    spell.SetActionColor(spellColor)
+    spell.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        enemy, isEnemy := enemyPositions[target]
+        if !isEnemy || enemy.HasStatusEffectWithName(effectName) || enemy.IsImmuneTo(effectName) {
+            return -10
+        }
+        return 25
+    })
+    return spell
+}
+
 func allVisibleTilesInRadius(radius int) func(engine Engine, caster *Actor, usePos geometry.Point) map[geometry.Point]bool {
     return func(engine Engine, caster *Actor, usePos geometry.Point) map[geometry.Point]bool {
         gridMap := engine.GetGridMap()
@@ -302,6 +595,17 @@ func allVisibleTilesInRadius(radius int) func(engine Engine, caster *Actor, useP
         return validPositions
     }
 }
+// allReachableTilesInRadius returns the area of effect of an explosion at the target.
+func allReachableTilesInRadius(radius int) func(engine Engine, caster *Actor, target geometry.Point) []geometry.Point {
+    return func(engine Engine, caster *Actor, target geometry.Point) []geometry.Point {
+        hitPositions := engine.GetGridMap().GetDijkstraMapWithActorsNotBlocking(target, radius)
+        result := make([]geometry.Point, 0, len(hitPositions))
+        for pos, _ := range hitPositions {
+            result = append(result, pos)
+        }
+        return result
+    }
+}
 func allVisibleTilesInRadiusWith(radius int, keep func(engine Engine, pos geometry.Point) bool) func(engine Engine, caster *Actor, usePos geometry.Point) map[geometry.Point]bool {
     return func(engine Engine, caster *Actor, usePos geometry.Point) map[geometry.Point]bool {
         gridMap := engine.GetGridMap()
@@ -322,11 +626,7 @@ func NewSpell(name string, manaCost int, effect func(engine Engine, caster *Acto
             name:   name,
             effect: effect,
             canPayCost: func(engine Engine, user *Actor) bool {
-                if !user.HasMana(manaCost) {
-                    engine.Print("Not enough mana!")
-                    return false
-                }
-                return true
+                return user.HasMana(manaCost)
             },
             payCost: func(engine Engine, user *Actor) {
                 engine.ManaSpent(user, manaCost)
@@ -344,11 +644,7 @@ func NewTargetedSpell(name string, manaCost int, effect func(engine Engine, cast
             targetedEffect:       effect,
             closeModalsForEffect: true,
             canPayCost: func(engine Engine, user *Actor) bool {
-                if !user.HasMana(manaCost) {
-                    engine.Print("Not enough mana!")
-                    return false
-                }
-                return true
+                return user.HasMana(manaCost)
             },
             payCost: func(engine Engine, user *Actor) {
                 engine.ManaSpent(user, manaCost)
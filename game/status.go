@@ -1,6 +1,11 @@
 package game
 
-import "fmt"
+import (
+    "Legacy/ega"
+    "fmt"
+    "image/color"
+    "strings"
+)
 
 type CreatureType string
 
@@ -9,8 +14,56 @@ const (
     CreatureTypeAnimal         CreatureType = "animal"
     CreatureTypeOther          CreatureType = "other"
     CreatureTypeNonIntelligent CreatureType = "non-intelligent"
+    CreatureTypeUndead         CreatureType = "undead"
 )
 
+func CreatureTypeFromString(value string) CreatureType {
+    switch CreatureType(strings.ToLower(strings.TrimSpace(value))) {
+    case CreatureTypeHuman:
+        return CreatureTypeHuman
+    case CreatureTypeAnimal:
+        return CreatureTypeAnimal
+    case CreatureTypeNonIntelligent:
+        return CreatureTypeNonIntelligent
+    case CreatureTypeUndead:
+        return CreatureTypeUndead
+    }
+    return CreatureTypeOther
+}
+
+// IsImmuneTo is true, if status effects with this name have no effect on the creature type.
+func (c CreatureType) IsImmuneTo(effectName StatusEffectName) bool {
+    switch c {
+    case CreatureTypeUndead:
+        // nothing to poison, bleed, scare or put to sleep
+        switch effectName {
+        case StatusEffectNamePoisoned, StatusEffectNameBleeding, StatusEffectNameFrightened, StatusEffectNameSleeping, StatusEffectNameRegenerating:
+            return true
+        }
+    case CreatureTypeNonIntelligent:
+        // too dumb to be afraid and no eyes to speak of
+        switch effectName {
+        case StatusEffectNameFrightened, StatusEffectNameBlinded:
+            return true
+        }
+    }
+    return false
+}
+
+// GetResistances of the creature type, before any equipment.
+func (c CreatureType) GetResistances() Resistances {
+    switch c {
+    case CreatureTypeUndead:
+        return Resistances{DamageTypeHoly: -50, DamageTypePoison: 100, DamageTypeCold: 50, DamageTypePierce: 25}
+    case CreatureTypeAnimal:
+        return Resistances{DamageTypeFire: -25}
+    case CreatureTypeNonIntelligent:
+        // slimes just wobble when hit by a club
+        return Resistances{DamageTypeBlunt: 50, DamageTypeFire: -25}
+    }
+    return Resistances{}
+}
+
 type StatusEffectName string
 
 const (
@@ -19,6 +72,18 @@ const (
     StatusEffectNameWeak      StatusEffectName = "weak"
     StatusEffectNameHolyBonus StatusEffectName = "holy bonus"
     StatusEffectNameBlessed   StatusEffectName = "blessed"
+    StatusEffectNameHasted    StatusEffectName = "hasted"
+    StatusEffectNameSlowed    StatusEffectName = "slowed"
+    StatusEffectNameStunned   StatusEffectName = "stunned"
+    StatusEffectNameBlocking  StatusEffectName = "blocking"
+
+    StatusEffectNamePoisoned     StatusEffectName = "poisoned"
+    StatusEffectNameBleeding     StatusEffectName = "bleeding"
+    StatusEffectNameFrightened   StatusEffectName = "frightened"
+    StatusEffectNameBlinded      StatusEffectName = "blinded"
+    StatusEffectNameInvisible    StatusEffectName = "invisible"
+    StatusEffectNameRegenerating StatusEffectName = "regenerating"
+    StatusEffectNameBurning      StatusEffectName = "burning"
 )
 
 // what do we really need?
@@ -72,6 +137,30 @@ type CombatEffect interface {
     OnNewTurn(engine Engine, actor *Actor)
 }
 
+// WorldTimeEffect is ticked once for every minute of world time that passes outside of combat.
+type WorldTimeEffect interface {
+    StatusEffect
+    OnMinutePassed(engine Engine, actor *Actor)
+}
+
+// OnAttackEffect is notified, whenever the actor attacks someone.
+type OnAttackEffect interface {
+    StatusEffect
+    OnAttack(engine Engine, actor *Actor)
+}
+
+// TintingEffect colors the actor on the map.
+type TintingEffect interface {
+    StatusEffect
+    TintColor() color.Color
+}
+
+// IndicatorEffect is shown next to the name of a party member in the status bar.
+type IndicatorEffect interface {
+    StatusEffect
+    Indicator() (rune, color.Color)
+}
+
 type OnDamageEffect interface {
     StatusEffect
     OnDamageReceived(engine Engine, actor *Actor, amount int)
@@ -91,6 +180,63 @@ func (e *BaseStatusEffect) OnApply(engine Engine, actor *Actor)   {}
 func (e *BaseStatusEffect) OnReapply(engine Engine, actor *Actor) {}
 func (e *BaseStatusEffect) IsExpired() bool                       { return e.isOver }
 
+// TimedStatusEffect runs out after a number of combat turns.
+// Outside of combat, every minute of world time counts as one turn.
+// Reapplying it adds a stack, up to maxStacks, and starts the duration over.
+type TimedStatusEffect struct {
+    BaseStatusEffect
+    duration  int
+    maxStacks int
+    turnsLeft int
+    stacks    int
+}
+
+func (e *TimedStatusEffect) OnApply(engine Engine, actor *Actor) {
+    e.stacks = 1
+    e.turnsLeft = e.duration
+}
+
+func (e *TimedStatusEffect) OnReapply(engine Engine, actor *Actor) {
+    e.stacks = min(e.maxStacks, e.stacks+1)
+    e.turnsLeft = e.duration
+}
+
+func (e *TimedStatusEffect) OnNewTurn(engine Engine, actor *Actor) {
+    e.countDown()
+}
+
+func (e *TimedStatusEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    e.countDown()
+}
+
+func (e *TimedStatusEffect) countDown() {
+    e.turnsLeft--
+    if e.turnsLeft <= 0 {
+        e.isOver = true
+    }
+}
+
+func (e *TimedStatusEffect) turnsLeftText() string {
+    if e.turnsLeft == 1 {
+        return "1 turn left"
+    }
+    return fmt.Sprintf("%d turns left", e.turnsLeft)
+}
+
+// damageOverTime hurts the actor. Outside of combat it will leave them with at least one HP.
+func damageOverTime(engine Engine, actor *Actor, amount int, canKill bool, cause StatusEffectName) {
+    if !canKill {
+        amount = min(amount, actor.GetHealth()-1)
+    }
+    if amount <= 0 || !actor.IsAlive() {
+        return
+    }
+    actor.Damage(engine, amount)
+    if canKill {
+        engine.Print(fmt.Sprintf("%d dmg. to '%s' (%s)", amount, actor.Name(), cause))
+    }
+}
+
 type SleepingEffect struct {
     BaseStatusEffect
 }
@@ -228,6 +374,396 @@ func (e *BlessedEffect) GetDerivedModifiers() []DerivedModifierDefinition {
     }
 }
 
+type HastedEffect struct {
+    BaseStatusEffect
+    turnsLeft       int
+    initiativeBonus int
+}
+
+func (e *HastedEffect) Description() []string {
+    return []string{fmt.Sprintf("+%d initiative", e.initiativeBonus), "+2 movement", fmt.Sprintf("%d turns left", e.turnsLeft)}
+}
+
+func (e *HastedEffect) Name() StatusEffectName {
+    return StatusEffectNameHasted
+}
+
+func StatusHasted() *HastedEffect {
+    return &HastedEffect{}
+}
+
+func (e *HastedEffect) OnApply(engine Engine, actor *Actor) {
+    e.initiativeBonus = max(1, actor.GetInitiative()/2)
+    e.turnsLeft += 3
+}
+
+func (e *HastedEffect) OnReapply(engine Engine, actor *Actor) {
+    e.turnsLeft += 3
+}
+
+func (e *HastedEffect) OnNewTurn(engine Engine, actor *Actor) {
+    e.turnsLeft--
+    if e.turnsLeft <= 0 {
+        e.isOver = true
+    }
+}
+
+func (e *HastedEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    e.OnNewTurn(engine, actor)
+}
+
+func (e *HastedEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *HastedEffect) GetDerivedModifiers() []DerivedModifierDefinition {
+    return []DerivedModifierDefinition{
+        {Attribute: DerivedAttributeInitiative, GetValue: func() int { return e.initiativeBonus }},
+        {Attribute: DerivedAttributeMovementAllowance, GetValue: func() int { return 2 }},
+    }
+}
+
+type SlowedEffect struct {
+    BaseStatusEffect
+    turnsLeft         int
+    initiativePenalty int
+}
+
+func (e *SlowedEffect) Description() []string {
+    return []string{fmt.Sprintf("-%d initiative", e.initiativePenalty), "-2 movement", fmt.Sprintf("%d turns left", e.turnsLeft)}
+}
+
+func (e *SlowedEffect) Name() StatusEffectName {
+    return StatusEffectNameSlowed
+}
+
+func StatusSlowed() *SlowedEffect {
+    return &SlowedEffect{}
+}
+
+func (e *SlowedEffect) OnApply(engine Engine, actor *Actor) {
+    e.initiativePenalty = max(1, actor.GetInitiative()/2)
+    e.turnsLeft += 3
+}
+
+func (e *SlowedEffect) OnReapply(engine Engine, actor *Actor) {
+    e.turnsLeft += 3
+}
+
+func (e *SlowedEffect) OnNewTurn(engine Engine, actor *Actor) {
+    e.turnsLeft--
+    if e.turnsLeft <= 0 {
+        e.isOver = true
+    }
+}
+
+func (e *SlowedEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    e.OnNewTurn(engine, actor)
+}
+
+func (e *SlowedEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *SlowedEffect) GetDerivedModifiers() []DerivedModifierDefinition {
+    return []DerivedModifierDefinition{
+        {Attribute: DerivedAttributeInitiative, GetValue: func() int { return -e.initiativePenalty }},
+        {Attribute: DerivedAttributeMovementAllowance, GetValue: func() int { return -2 }},
+    }
+}
+
+// StunnedEffect makes the actor skip their next turn in combat.
+type StunnedEffect struct {
+    BaseStatusEffect
+}
+
+func (e *StunnedEffect) Description() []string {
+    return []string{"Skips the next turn"}
+}
+
+func (e *StunnedEffect) Name() StatusEffectName {
+    return StatusEffectNameStunned
+}
+
+func StatusStunned() *StunnedEffect {
+    return &StunnedEffect{}
+}
+
+func (e *StunnedEffect) OnNewTurn(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *StunnedEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+// BlockingEffect raises the chance to block with a shield until the next turn.
+type BlockingEffect struct {
+    BaseStatusEffect
+}
+
+func (e *BlockingEffect) Description() []string {
+    return []string{"Improved chance to block", "Until the next turn"}
+}
+
+func (e *BlockingEffect) Name() StatusEffectName {
+    return StatusEffectNameBlocking
+}
+
+func StatusBlocking() *BlockingEffect {
+    return &BlockingEffect{}
+}
+
+func (e *BlockingEffect) OnNewTurn(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *BlockingEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+// PoisonedEffect deals one damage per stack every turn.
+type PoisonedEffect struct {
+    TimedStatusEffect
+}
+
+func (e *PoisonedEffect) Description() []string {
+    return []string{fmt.Sprintf("%d dmg. per turn", e.stacks), e.turnsLeftText()}
+}
+
+func (e *PoisonedEffect) Name() StatusEffectName {
+    return StatusEffectNamePoisoned
+}
+
+func StatusPoisoned() *PoisonedEffect {
+    return &PoisonedEffect{TimedStatusEffect{duration: 5, maxStacks: 5}}
+}
+
+func (e *PoisonedEffect) OnNewTurn(engine Engine, actor *Actor) {
+    damageOverTime(engine, actor, actor.GetResistances().Apply(e.stacks, DamageTypePoison), true, e.Name())
+    e.countDown()
+}
+
+func (e *PoisonedEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    damageOverTime(engine, actor, actor.GetResistances().Apply(e.stacks, DamageTypePoison), false, e.Name())
+    e.countDown()
+}
+
+func (e *PoisonedEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *PoisonedEffect) TintColor() color.Color {
+    return ega.BrightGreen
+}
+
+func (e *PoisonedEffect) Indicator() (rune, color.Color) {
+    return 'P', ega.BrightGreen
+}
+
+// BleedingEffect deals two damage per stack every turn, but doesn't last long.
+type BleedingEffect struct {
+    TimedStatusEffect
+}
+
+func (e *BleedingEffect) Description() []string {
+    return []string{fmt.Sprintf("%d dmg. per turn", 2*e.stacks), e.turnsLeftText()}
+}
+
+func (e *BleedingEffect) Name() StatusEffectName {
+    return StatusEffectNameBleeding
+}
+
+func StatusBleeding() *BleedingEffect {
+    return &BleedingEffect{TimedStatusEffect{duration: 3, maxStacks: 3}}
+}
+
+func (e *BleedingEffect) OnNewTurn(engine Engine, actor *Actor) {
+    damageOverTime(engine, actor, 2*e.stacks, true, e.Name())
+    e.countDown()
+}
+
+func (e *BleedingEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    damageOverTime(engine, actor, 2*e.stacks, false, e.Name())
+    e.countDown()
+}
+
+func (e *BleedingEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *BleedingEffect) TintColor() color.Color {
+    return ega.Red
+}
+
+func (e *BleedingEffect) Indicator() (rune, color.Color) {
+    return 'B', ega.BrightRed
+}
+
+// FrightenedEffect makes computer controlled actors run from their enemies.
+type FrightenedEffect struct {
+    TimedStatusEffect
+}
+
+func (e *FrightenedEffect) Description() []string {
+    return []string{"Flees from enemies", "-2 initiative", e.turnsLeftText()}
+}
+
+func (e *FrightenedEffect) Name() StatusEffectName {
+    return StatusEffectNameFrightened
+}
+
+func StatusFrightened() *FrightenedEffect {
+    return &FrightenedEffect{TimedStatusEffect{duration: 3, maxStacks: 1}}
+}
+
+func (e *FrightenedEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *FrightenedEffect) GetDerivedModifiers() []DerivedModifierDefinition {
+    return []DerivedModifierDefinition{
+        {Attribute: DerivedAttributeInitiative, GetValue: func() int { return -2 }},
+    }
+}
+
+func (e *FrightenedEffect) TintColor() color.Color {
+    return ega.Yellow
+}
+
+func (e *FrightenedEffect) Indicator() (rune, color.Color) {
+    return 'F', ega.Yellow
+}
+
+// BlindedEffect makes attacks miss more often. Blinded actors only notice enemies right next to them.
+type BlindedEffect struct {
+    TimedStatusEffect
+}
+
+func (e *BlindedEffect) Description() []string {
+    return []string{"Half as likely to hit in melee", "Can barely shoot", e.turnsLeftText()}
+}
+
+func (e *BlindedEffect) Name() StatusEffectName {
+    return StatusEffectNameBlinded
+}
+
+func StatusBlinded() *BlindedEffect {
+    return &BlindedEffect{TimedStatusEffect{duration: 3, maxStacks: 1}}
+}
+
+func (e *BlindedEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *BlindedEffect) TintColor() color.Color {
+    return ega.BrightBlack
+}
+
+func (e *BlindedEffect) Indicator() (rune, color.Color) {
+    return 'X', ega.White
+}
+
+// InvisibleEffect hides the actor from its enemies, until it attacks.
+type InvisibleEffect struct {
+    TimedStatusEffect
+}
+
+func (e *InvisibleEffect) Description() []string {
+    return []string{"Cannot be seen from afar", "Half as likely to be hit", "Ends when attacking", e.turnsLeftText()}
+}
+
+func (e *InvisibleEffect) Name() StatusEffectName {
+    return StatusEffectNameInvisible
+}
+
+func StatusInvisible() *InvisibleEffect {
+    return &InvisibleEffect{TimedStatusEffect{duration: 10, maxStacks: 1}}
+}
+
+func (e *InvisibleEffect) OnAttack(engine Engine, actor *Actor) {
+    e.isOver = true
+    engine.Print(fmt.Sprintf("'%s' is visible again", actor.Name()))
+}
+
+func (e *InvisibleEffect) TintColor() color.Color {
+    return ega.Blue
+}
+
+func (e *InvisibleEffect) Indicator() (rune, color.Color) {
+    return 'I', ega.BrightCyan
+}
+
+// RegeneratingEffect heals two HP per stack every turn.
+type RegeneratingEffect struct {
+    TimedStatusEffect
+}
+
+func (e *RegeneratingEffect) Description() []string {
+    return []string{fmt.Sprintf("Heals %d HP per turn", 2*e.stacks), e.turnsLeftText()}
+}
+
+func (e *RegeneratingEffect) Name() StatusEffectName {
+    return StatusEffectNameRegenerating
+}
+
+func StatusRegenerating() *RegeneratingEffect {
+    return &RegeneratingEffect{TimedStatusEffect{duration: 5, maxStacks: 3}}
+}
+
+func (e *RegeneratingEffect) OnNewTurn(engine Engine, actor *Actor) {
+    actor.Heal(2 * e.stacks)
+    e.countDown()
+}
+
+func (e *RegeneratingEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    actor.Heal(2 * e.stacks)
+    e.countDown()
+}
+
+func (e *RegeneratingEffect) Indicator() (rune, color.Color) {
+    return 'R', ega.BrightMagenta
+}
+
+// BurningEffect deals fire damage every turn. Stepping into water puts it out.
+type BurningEffect struct {
+    TimedStatusEffect
+}
+
+func (e *BurningEffect) Description() []string {
+    return []string{fmt.Sprintf("%d fire dmg. per turn", 3*e.stacks), e.turnsLeftText()}
+}
+
+func (e *BurningEffect) Name() StatusEffectName {
+    return StatusEffectNameBurning
+}
+
+func StatusBurning() *BurningEffect {
+    return &BurningEffect{TimedStatusEffect{duration: 3, maxStacks: 2}}
+}
+
+func (e *BurningEffect) OnNewTurn(engine Engine, actor *Actor) {
+    damageOverTime(engine, actor, actor.GetResistances().Apply(3*e.stacks, DamageTypeFire), true, e.Name())
+    e.countDown()
+}
+
+func (e *BurningEffect) OnMinutePassed(engine Engine, actor *Actor) {
+    damageOverTime(engine, actor, actor.GetResistances().Apply(3*e.stacks, DamageTypeFire), false, e.Name())
+    e.countDown()
+}
+
+func (e *BurningEffect) OnRest(engine Engine, actor *Actor) {
+    e.isOver = true
+}
+
+func (e *BurningEffect) TintColor() color.Color {
+    return ega.BrightYellow
+}
+
+func (e *BurningEffect) Indicator() (rune, color.Color) {
+    return 'F', ega.BrightRed
+}
+
 func StatusFromName(name string) StatusEffect {
     effectName := StatusEffectName(name)
     switch effectName {
@@ -241,6 +777,28 @@ func StatusFromName(name string) StatusEffect {
         return StatusHolyBonus()
     case StatusEffectNameBlessed:
         return StatusBlessed()
+    case StatusEffectNameHasted:
+        return StatusHasted()
+    case StatusEffectNameSlowed:
+        return StatusSlowed()
+    case StatusEffectNameStunned:
+        return StatusStunned()
+    case StatusEffectNameBlocking:
+        return StatusBlocking()
+    case StatusEffectNamePoisoned:
+        return StatusPoisoned()
+    case StatusEffectNameBleeding:
+        return StatusBleeding()
+    case StatusEffectNameFrightened:
+        return StatusFrightened()
+    case StatusEffectNameBlinded:
+        return StatusBlinded()
+    case StatusEffectNameInvisible:
+        return StatusInvisible()
+    case StatusEffectNameRegenerating:
+        return StatusRegenerating()
+    case StatusEffectNameBurning:
+        return StatusBurning()
     }
     return nil
 }
@@ -0,0 +1,538 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "math"
+)
+
+type AttackActionType int
+
+const (
+    AttackActionTypeMelee AttackActionType = iota
+    AttackActionTypeRanged
+    AttackActionTypeSpell
+    AttackActionTypeActiveSkill
+    AttackActionTypeMove
+    // AttackActionTypeFlee moves away from the enemies, without attacking.
+    AttackActionTypeFlee
+    // AttackActionTypeEscape leaves the fight.
+    AttackActionTypeEscape
+    // AttackActionTypeSurrender offers to surrender to the enemies.
+    AttackActionTypeSurrender
+)
+
+type BattleAction struct {
+    MovementDestination  geometry.Point
+    ActionType           AttackActionType
+    ActiveAction         Action // skill / spell
+    ActionTargetLocation *geometry.Point
+}
+
+func (a BattleAction) IsValid(actorPos geometry.Point) bool {
+    return a.MovementDestination != actorPos || a.ActiveAction != nil || a.ActionTargetLocation != nil
+}
+
+// Battlefield is what the tactical AI needs to know about an ongoing fight.
+type Battlefield interface {
+    GetMovesLeft(actor *Actor) int
+    GetActiveOpponents(actor *Actor) []*Actor
+    GetActiveAllies(actor *Actor) []*Actor
+}
+
+// TacticalAI decides what actors do in combat, when the player is not controlling them.
+// It remembers which enemy each faction is focusing on and how many allies each faction has lost.
+type TacticalAI struct {
+    engine              Engine
+    battlefield         Battlefield
+    alliesLost          map[string]int
+    focusTargets        map[string]*Actor
+    hasOfferedSurrender map[*Actor]bool
+}
+
+func NewTacticalAI(engine Engine, battlefield Battlefield) *TacticalAI {
+    return &TacticalAI{
+        engine:              engine,
+        battlefield:         battlefield,
+        alliesLost:          make(map[string]int),
+        focusTargets:        make(map[string]*Actor),
+        hasOfferedSurrender: make(map[*Actor]bool),
+    }
+}
+
+// Reset forgets everything about the last fight.
+func (t *TacticalAI) Reset() {
+    clear(t.alliesLost)
+    clear(t.focusTargets)
+    clear(t.hasOfferedSurrender)
+}
+
+// OnCasualty is called for every actor that dies during combat.
+// It lowers the morale of their allies.
+func (t *TacticalAI) OnCasualty(actor *Actor) {
+    t.alliesLost[actor.GetCombatFaction()]++
+    for faction, target := range t.focusTargets {
+        if target == actor {
+            delete(t.focusTargets, faction)
+        }
+    }
+}
+
+// SetFocusTarget lets the whole faction of the attacker prefer the same target.
+func (t *TacticalAI) SetFocusTarget(attacker, target *Actor) {
+    if target == nil || attacker.IsAllyOf(target) {
+        return
+    }
+    t.focusTargets[attacker.GetCombatFaction()] = target
+}
+
+func (t *TacticalAI) HasMoraleBroken(actor *Actor) bool {
+    if t.engine.IsPlayerControlled(actor) || actor.IsCompanion() {
+        return false
+    }
+    return t.engine.GetRules().GetMorale(actor, t.alliesLost[actor.GetCombatFaction()]) <= 0
+}
+
+// ChooseAction returns the best action for the actor and all the positions it can reach this turn.
+// Actors with broken morale will offer to surrender once, if they can, and try to flee otherwise.
+// Frightened actors always try to flee.
+func (t *TacticalAI) ChooseAction(ourActor *Actor) (BattleAction, map[geometry.Point]int) {
+    if ourActor.IsFrightened() {
+        if fleeAction, reachablePositions, canFlee := t.fleeFromEnemies(ourActor); canFlee {
+            return fleeAction, reachablePositions
+        }
+    }
+    if t.HasMoraleBroken(ourActor) {
+        if ourActor.CanSurrender() && !t.hasOfferedSurrender[ourActor] {
+            t.hasOfferedSurrender[ourActor] = true
+            return BattleAction{MovementDestination: ourActor.Pos(), ActionType: AttackActionTypeSurrender}, nil
+        }
+        if ourActor.GetCombatBehavior().CanFlee() {
+            if fleeAction, reachablePositions, canFlee := t.fleeFromEnemies(ourActor); canFlee {
+                return fleeAction, reachablePositions
+            }
+        }
+    }
+    return t.calculateBestAction(ourActor)
+}
+
+// fleeFromEnemies moves the actor as far away from its enemies as it can.
+// Once it is out of sight or far enough away, it leaves the fight.
+// Returns false, if the actor is cornered and has to fight.
+func (t *TacticalAI) fleeFromEnemies(actor *Actor) (BattleAction, map[geometry.Point]int, bool) {
+    gridMap := t.engine.GetGridMap()
+    safeDistance := t.engine.GetRules().GetFleeSafeDistance()
+    threatMap := t.getThreatMap(actor, safeDistance)
+    distanceToThreat := func(pos geometry.Point) int {
+        if distance, ok := threatMap[pos]; ok {
+            return distance
+        }
+        return safeDistance
+    }
+
+    currentDistance := distanceToThreat(actor.Pos())
+    if currentDistance >= safeDistance || (currentDistance > 1 && !t.isSeenByOpponents(actor)) {
+        return BattleAction{MovementDestination: actor.Pos(), ActionType: AttackActionTypeEscape}, nil, true
+    }
+
+    reachablePositions := GetCombatMovementMap(gridMap, actor, t.battlefield.GetMovesLeft(actor))
+
+    bestPos := actor.Pos()
+    bestDistance := currentDistance
+    for pos, cost := range reachablePositions {
+        distance := distanceToThreat(pos)
+        if distance > bestDistance || (distance == bestDistance && cost < reachablePositions[bestPos]) {
+            bestPos = pos
+            bestDistance = distance
+        }
+    }
+    if bestPos == actor.Pos() {
+        return BattleAction{}, nil, false
+    }
+    return BattleAction{MovementDestination: bestPos, ActionType: AttackActionTypeFlee}, reachablePositions, true
+}
+
+func (t *TacticalAI) calculateBestAction(ourActor *Actor) (BattleAction, map[geometry.Point]int) {
+    // let's start simple..
+    // find the nearest enemy, find a free cell position next to him
+    // move towards that position
+    // if we can reach it this turn: attack
+
+    gridMap := t.engine.GetGridMap()
+    reachablePositions := GetCombatMovementMap(gridMap, ourActor, t.battlefield.GetMovesLeft(ourActor))
+    // walking away from enemies in melee gives each of them a free strike
+    leavingPenalty := len(GetOpportunityAttackers(gridMap, ourActor, ourActor.Pos())) * 20
+
+    maxUtility := math.MinInt
+    bestAction := BattleAction{
+        MovementDestination: ourActor.Pos(),
+    }
+    for pos, _ := range reachablePositions {
+        utility, actionAt := t.getUtilityForPosition(ourActor, pos)
+        if pos != ourActor.Pos() {
+            utility -= leavingPenalty
+        }
+        if utility > maxUtility {
+            maxUtility = utility
+            bestAction = actionAt
+        }
+    }
+
+    if bestAction.ActionTargetLocation == nil { // nothing to attack from here, get closer
+        attackType := AttackActionTypeMelee
+        moveTo, attackTarget := t.closeIntoMeleeRange(ourActor, reachablePositions)
+        bestAction = BattleAction{
+            MovementDestination:  moveTo,
+            ActionType:           attackType,
+            ActionTargetLocation: attackTarget,
+        }
+    }
+
+    return bestAction, reachablePositions
+}
+
+func (t *TacticalAI) closeIntoMeleeRange(ourActor *Actor, reachablePositions map[geometry.Point]int) (geometry.Point, *geometry.Point) {
+    // attack the adjacent enemy with the highest priority
+    // or move next to the nearest enemy, wounded and focused enemies count as nearer
+    var bestEnemy *Actor
+    bestPos := ourActor.Pos()
+    bestScore := math.MaxInt
+    currentMap := t.engine.GetGridMap()
+    for _, enemy := range t.getPerceivedOpponents(ourActor) {
+        priority := t.getTargetPriority(ourActor, enemy)
+        if enemy.IsRightNextTo(ourActor) {
+            score := math.MinInt/2 - priority
+            if score < bestScore {
+                bestEnemy = enemy
+                bestPos = ourActor.Pos()
+                bestScore = score
+            }
+            continue
+        }
+        freeNeighbors := currentMap.NeighborsCardinal(enemy.Pos(), func(p geometry.Point) bool {
+            return currentMap.Contains(p) && currentMap.IsWalkableFor(p, ourActor)
+        })
+        for _, freeNeighbor := range freeNeighbors {
+            if _, ok := reachablePositions[freeNeighbor]; ok {
+                score := geometry.DistanceManhattan(ourActor.Pos(), freeNeighbor) - priority/25
+                if score < bestScore {
+                    bestEnemy = enemy
+                    bestPos = freeNeighbor
+                    bestScore = score
+                }
+            }
+        }
+    }
+    if bestEnemy == nil {
+        return t.approachNearestOpponent(ourActor, reachablePositions), nil
+    }
+    enemyPos := bestEnemy.Pos()
+    return bestPos, &enemyPos
+}
+
+// approachNearestOpponent returns the reachable position closest to any opponent.
+// Used when nobody is in reach this turn.
+func (t *TacticalAI) approachNearestOpponent(ourActor *Actor, reachablePositions map[geometry.Point]int) geometry.Point {
+    bestPos := ourActor.Pos()
+    bestDistance := math.MaxInt
+    for _, enemy := range t.getPerceivedOpponents(ourActor) {
+        if distance := geometry.DistanceManhattan(ourActor.Pos(), enemy.Pos()); distance < bestDistance {
+            bestDistance = distance
+        }
+    }
+    if bestDistance == math.MaxInt {
+        return bestPos
+    }
+    for pos, _ := range reachablePositions {
+        for _, enemy := range t.getPerceivedOpponents(ourActor) {
+            if distance := geometry.DistanceManhattan(pos, enemy.Pos()); distance < bestDistance {
+                bestDistance = distance
+                bestPos = pos
+            }
+        }
+    }
+    return bestPos
+}
+
+func (t *TacticalAI) getUtilityForPosition(ourActor *Actor, potentialNewLocation geometry.Point) (int, BattleAction) {
+    // what's our disposition? Stay near for melee, or stay far for ranged?
+    // if we can do ranged attacks, we will. If we can't, we'll try to close in.
+    // for ranged: positions were we can attack but cannot be attacked are favorable
+    gridMap := t.engine.GetGridMap()
+
+    utility := 0
+    bestAction := BattleAction{
+        MovementDestination: potentialNewLocation,
+        ActionType:          AttackActionTypeMelee,
+    }
+    allyPositions := make(map[geometry.Point]*Actor)
+    enemyPositions := make(map[geometry.Point]*Actor)
+    enemies := t.getPerceivedOpponents(ourActor)
+    allies := t.battlefield.GetActiveAllies(ourActor)
+    for _, enemy := range enemies {
+        enemyPositions[enemy.Pos()] = enemy
+    }
+    for _, ally := range allies {
+        allyPositions[ally.Pos()] = ally
+    }
+
+    activeSkills := ourActor.GetActiveSkills()
+    spells := ourActor.GetEquippedSpells()
+    consumables := ourActor.GetConsumableActions()
+
+    /*
+       canMeleeAttack := true
+       canRangedAttack := ourActor.HasRangedWeaponEquipped()
+       canUseActiveSkill := len(activeSkills) > 0
+       canUseSpell := len(spells) > 0
+
+    */
+
+    // assign utility to this position first
+
+    var neighborsWithAllies, neighborsWithEnemies []geometry.Point
+    neighbors := gridMap.GetAllCardinalNeighbors(potentialNewLocation)
+    for _, neighbor := range neighbors {
+        if gridMap.IsActorAt(neighbor) {
+            actorAt := gridMap.ActorAt(neighbor)
+            if actorAt == ourActor {
+                continue
+            }
+            if actorAt.IsAlive() {
+                if ourActor.IsAllyOf(actorAt) {
+                    neighborsWithAllies = append(neighborsWithAllies, neighbor)
+                } else {
+                    neighborsWithEnemies = append(neighborsWithEnemies, neighbor)
+                }
+            }
+        }
+    }
+
+    maxSkillUtility := 0
+    var bestSkill Action
+    var bestLocation geometry.Point
+    for _, skill := range append(append(activeSkills, spells...), consumables...) {
+        if !skill.CanPayCost(t.engine, ourActor) {
+            continue
+        }
+        if skill.IsTargeted() {
+            validTargetsOfSkill := skill.GetValidTargets(t.engine, ourActor, potentialNewLocation)
+            for target, _ := range validTargetsOfSkill {
+                combatUtility := skill.GetCombatUtilityForTargetedUseOnLocation(t.engine, ourActor, target, allyPositions, enemyPositions)
+                if combatUtility > maxSkillUtility {
+                    maxSkillUtility = combatUtility
+                    bestSkill = skill
+                    bestLocation = target
+                }
+            }
+        } else {
+            combatUtility := skill.GetCombatUtilityForUseAtLocation(t.engine, ourActor, potentialNewLocation, allyPositions, enemyPositions)
+            if combatUtility > maxSkillUtility {
+                maxSkillUtility = combatUtility
+                bestSkill = skill
+                bestLocation = potentialNewLocation
+            }
+        }
+    }
+    positionUtility := 0
+    if maxSkillUtility > 0 {
+        bestAction = BattleAction{
+            MovementDestination:  potentialNewLocation,
+            ActionType:           AttackActionTypeActiveSkill,
+            ActiveAction:         bestSkill,
+            ActionTargetLocation: &bestLocation,
+        }
+        positionUtility += t.getRangedUtility(neighborsWithAllies, neighborsWithEnemies)
+    } else if rangedTarget, priority := t.getBestRangedTarget(ourActor, potentialNewLocation, enemies); rangedTarget != nil {
+        targetPos := rangedTarget.Pos()
+        bestAction = BattleAction{
+            MovementDestination:  potentialNewLocation,
+            ActionType:           AttackActionTypeRanged,
+            ActionTargetLocation: &targetPos,
+        }
+        maxSkillUtility = 20 + priority/10
+        positionUtility += t.getRangedUtility(neighborsWithAllies, neighborsWithEnemies)
+    } else {
+        positionUtility += t.getMeleeUtility(neighborsWithAllies, neighborsWithEnemies)
+        if meleeTarget, priority := t.getBestMeleeTarget(ourActor, enemyPositions, neighborsWithEnemies); meleeTarget != nil {
+            targetPos := meleeTarget.Pos()
+            bestAction.ActionTargetLocation = &targetPos
+            positionUtility += priority / 10
+        }
+    }
+
+    if ourActor.GetCombatBehavior().KeepsDistance() {
+        positionUtility += t.getSkirmisherUtility(potentialNewLocation, enemies)
+    }
+
+    distance := geometry.DistanceManhattan(ourActor.Pos(), potentialNewLocation)
+    positionUtility -= distance
+
+    utility = positionUtility + maxSkillUtility
+    return utility, bestAction
+}
+
+func (t *TacticalAI) getRangedUtility(neighborsWithAllies, neighborsWithEnemies []geometry.Point) int {
+    rangedUtility := 0
+    if len(neighborsWithEnemies) == 0 {
+        rangedUtility += 30
+    } else if len(neighborsWithEnemies) > 1 {
+        rangedUtility -= 30
+    }
+
+    if len(neighborsWithAllies) == 0 { // by, default, leave a gap
+        rangedUtility += 10
+    }
+    return rangedUtility
+}
+
+func (t *TacticalAI) getMeleeUtility(neighborsWithAllies, neighborsWithEnemies []geometry.Point) int {
+    meleeUtility := 0
+    if len(neighborsWithEnemies) == 1 {
+        meleeUtility += 30
+    } else if len(neighborsWithEnemies) > 1 {
+        meleeUtility -= 10
+    }
+
+    if len(neighborsWithAllies) == 0 { // by, default, leave a gap
+        meleeUtility += 10
+    }
+    return meleeUtility
+}
+
+// getThreatMap returns the walking distance from the nearest active opponent of the actor.
+func (t *TacticalAI) getThreatMap(actor *Actor, maxDistance int) map[geometry.Point]int {
+    gridMap := t.engine.GetGridMap()
+    threatMap := make(map[geometry.Point]int)
+    for _, opponent := range t.battlefield.GetActiveOpponents(actor) {
+        distances := gridMap.GetDijkstraMap(opponent.Pos(), maxDistance, func(p geometry.Point) bool {
+            return gridMap.Contains(p) && gridMap.IsWalkable(p)
+        })
+        for pos, distance := range distances {
+            if known, ok := threatMap[pos]; !ok || distance < known {
+                threatMap[pos] = distance
+            }
+        }
+    }
+    return threatMap
+}
+
+func (t *TacticalAI) isSeenByOpponents(actor *Actor) bool {
+    for _, opponent := range t.battlefield.GetActiveOpponents(actor) {
+        if t.canSee(opponent, actor) {
+            return true
+        }
+    }
+    return false
+}
+
+// getTargetPriority rates how much the attacker wants to hit this target.
+// Wounded enemies and the enemy that the attacker's side is already focusing on come first.
+func (t *TacticalAI) getTargetPriority(attacker, target *Actor) int {
+    priority := 100 - (target.GetHealth()*100)/max(1, target.GetMaxHealth())
+    if t.focusTargets[attacker.GetCombatFaction()] == target {
+        priority += 50
+    }
+    return priority
+}
+
+// getBestMeleeTarget returns the adjacent enemy with the highest priority.
+func (t *TacticalAI) getBestMeleeTarget(attacker *Actor, enemyPositions map[geometry.Point]*Actor, neighborsWithEnemies []geometry.Point) (*Actor, int) {
+    var bestTarget *Actor
+    bestPriority := math.MinInt
+    for _, pos := range neighborsWithEnemies {
+        enemy, isActiveEnemy := enemyPositions[pos]
+        if !isActiveEnemy {
+            continue
+        }
+        if priority := t.getTargetPriority(attacker, enemy); priority > bestPriority {
+            bestTarget = enemy
+            bestPriority = priority
+        }
+    }
+    return bestTarget, bestPriority
+}
+
+// getBestRangedTarget returns the enemy with the highest priority, that can be shot at from the given position.
+// Returns nil, if the attacker has no ranged weapon.
+func (t *TacticalAI) getBestRangedTarget(attacker *Actor, position geometry.Point, enemies []*Actor) (*Actor, int) {
+    if !attacker.HasRangedWeaponEquipped() {
+        return nil, 0
+    }
+    var bestTarget *Actor
+    bestPriority := math.MinInt
+    for _, enemy := range enemies {
+        lineOfFire := GetLineOfFire(t.engine.GetGridMap(), position, enemy.Pos(), IsBlockingLineOfFireFor(attacker))
+        if len(lineOfFire) == 0 || lineOfFire[len(lineOfFire)-1] != enemy.Pos() {
+            continue
+        }
+        if priority := t.getTargetPriority(attacker, enemy); priority > bestPriority {
+            bestTarget = enemy
+            bestPriority = priority
+        }
+    }
+    return bestTarget, bestPriority
+}
+
+// getSkirmisherUtility favors positions that keep some distance to the nearest enemy.
+func (t *TacticalAI) getSkirmisherUtility(position geometry.Point, enemies []*Actor) int {
+    nearestDistance := math.MaxInt
+    for _, enemy := range enemies {
+        nearestDistance = min(nearestDistance, geometry.DistanceManhattan(position, enemy.Pos()))
+    }
+    if nearestDistance <= 1 {
+        return -30
+    }
+    if nearestDistance >= 3 && nearestDistance <= 6 {
+        return 20
+    }
+    return 0
+}
+
+// getPerceivedOpponents returns the opponents the actor knows about.
+// Invisible opponents and, for blinded actors, everyone who is not right next to them go unnoticed.
+func (t *TacticalAI) getPerceivedOpponents(actor *Actor) []*Actor {
+    var perceived []*Actor
+    for _, opponent := range t.battlefield.GetActiveOpponents(actor) {
+        if opponent.IsRightNextTo(actor) || (!actor.IsBlinded() && !opponent.IsInvisible()) {
+            perceived = append(perceived, opponent)
+        }
+    }
+    return perceived
+}
+
+func (t *TacticalAI) canSee(one, other *Actor) bool {
+    if !one.IsRightNextTo(other) && (one.IsBlinded() || other.IsInvisible()) {
+        return false
+    }
+    los := GetLineOfFire(t.engine.GetGridMap(), one.Pos(), other.Pos(), IsBlockingLineOfFireFor(one))
+    return len(los) > 0
+}
+
+// GetLineOfFire returns the path of a projectile from source to destination, without the source itself.
+// The path ends early at walls and at actors that are blocking.
+func GetLineOfFire(gridMap *gridmap.GridMap[*Actor, Item, Object], source geometry.Point, destination geometry.Point, isActorBlocking func(actor *Actor) bool) []geometry.Point {
+    los := geometry.BresenhamLoS(source, destination, func(x, y int) bool {
+        p := geometry.Point{X: x, Y: y}
+        if !gridMap.Contains(p) {
+            return false
+        }
+        if gridMap.IsActorAt(p) {
+            actorAt := gridMap.ActorAt(p)
+            if isActorBlocking(actorAt) {
+                return false
+            }
+        }
+        isWalkable := gridMap.IsWalkable(p)
+        return isWalkable || p == source
+    })
+    losWithoutSource := los[1:]
+    return losWithoutSource
+}
+
+// IsBlockingLineOfFireFor returns true for all actors that are not allies of the attacker.
+func IsBlockingLineOfFireFor(attacker *Actor) func(actor *Actor) bool {
+    return func(actor *Actor) bool {
+        return !attacker.IsAllyOf(actor)
+    }
+}
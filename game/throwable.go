@@ -0,0 +1,224 @@
+package game
+
+import (
+    "Legacy/ega"
+    "Legacy/geometry"
+    "Legacy/recfile"
+    "Legacy/renderer/atlas"
+    "Legacy/util"
+    "fmt"
+    "image/color"
+)
+
+type ThrowableKind string
+
+const (
+    ThrowableKindOilFlask       ThrowableKind = "oil flask"
+    ThrowableKindYellowPowder   ThrowableKind = "yellow powder"
+    ThrowableKindThrowingDagger ThrowableKind = "throwing dagger"
+)
+
+// Throwable is used up when thrown. It flies to the target and takes effect on impact.
+type Throwable struct {
+    BaseItem
+    kind ThrowableKind
+}
+
+func NewThrowable(kind ThrowableKind) *Throwable {
+    return &Throwable{
+        BaseItem: BaseItem{
+            name:      string(kind),
+            baseValue: throwableValue(kind),
+        },
+        kind: kind,
+    }
+}
+
+func NewThrowableFromPredicate(predicate recfile.StringPredicate) *Throwable {
+    return NewThrowable(ThrowableKind(predicate.GetString(0)))
+}
+
+func (t *Throwable) Encode() string {
+    return recfile.ToPredicate("throwable", string(t.kind))
+}
+
+func throwableValue(kind ThrowableKind) int {
+    switch kind {
+    case ThrowableKindOilFlask:
+        return 150
+    case ThrowableKindYellowPowder:
+        return 120
+    case ThrowableKindThrowingDagger:
+        return 60
+    }
+    return 10
+}
+
+func (t *Throwable) GetKind() ThrowableKind {
+    return t.kind
+}
+
+func (t *Throwable) GetTooltipLines() []string {
+    switch t.kind {
+    case ThrowableKindOilFlask:
+        return []string{"Oil flask", "Burns everything in a 1 tile radius.", fmt.Sprintf("Damage: %d HP", t.damage())}
+    case ThrowableKindYellowPowder:
+        return []string{"Yellow powder", "Blinds everything in a 1 tile radius."}
+    case ThrowableKindThrowingDagger:
+        return []string{"Throwing dagger", fmt.Sprintf("Damage: %d HP", t.damage()), "May cause bleeding."}
+    }
+    return []string{}
+}
+
+func (t *Throwable) InventoryIcon() int32 {
+    switch t.kind {
+    case ThrowableKindOilFlask:
+        return 173
+    case ThrowableKindThrowingDagger:
+        return 187
+    }
+    return 169
+}
+
+func (t *Throwable) Icon(uint64) int32 {
+    switch t.kind {
+    case ThrowableKindOilFlask:
+        return 191
+    case ThrowableKindThrowingDagger:
+        return 220
+    }
+    return int32(205)
+}
+
+func (t *Throwable) TintColor() color.Color {
+    switch t.kind {
+    case ThrowableKindOilFlask:
+        return ega.BrightRed
+    case ThrowableKindYellowPowder:
+        return ega.Yellow
+    }
+    return color.White
+}
+
+func (t *Throwable) CanStackWith(other Item) bool {
+    if otherThrowable, ok := other.(*Throwable); ok {
+        return t.kind == otherThrowable.kind
+    }
+    return false
+}
+
+func (t *Throwable) GetContextActions(engine Engine) []util.MenuItem {
+    return inventoryItemActions(t, engine)
+}
+
+func (t *Throwable) damage() int {
+    switch t.kind {
+    case ThrowableKindOilFlask:
+        return 8
+    case ThrowableKindThrowingDagger:
+        return 6
+    }
+    return 0
+}
+
+func (t *Throwable) damageType() DamageType {
+    if t.kind == ThrowableKindOilFlask {
+        return DamageTypeFire
+    }
+    return DamageTypePierce
+}
+
+func (t *Throwable) radius() int {
+    if t.kind == ThrowableKindThrowingDagger {
+        return 0
+    }
+    return 1
+}
+
+// NewThrowAction lets actors in combat throw the item. It is removed from the inventory when thrown.
+func (t *Throwable) NewThrowAction() *BaseAction {
+    throw := NewTargetedCombatSkill(fmt.Sprintf("Throw %s", t.Name()), func(engine Engine, user *Actor, pos geometry.Point) {
+        for _, hitPos := range t.impactPositions(engine, user, pos) {
+            t.onImpact(engine, user, hitPos)
+        }
+    })
+    throw.canPayCost = func(engine Engine, user *Actor) bool {
+        return t.IsHeld()
+    }
+    throw.payCost = func(engine Engine, user *Actor) {
+        engine.RemoveItem(t)
+    }
+    throw.SetValidTargets(allVisibleTilesInRadius(6))
+    throw.SetAffectedPositions(t.impactPositions)
+    if t.damage() > 0 {
+        throw.SetHitPreview(func(engine Engine, user *Actor, victim *Actor) (float64, int) {
+            return 1.0, engine.GetRules().GetSpellDamage(victim, t.damage(), t.damageType())
+        })
+    }
+    throw.SetDescription(t.GetTooltipLines())
+    throw.SetActionColor(t.TintColor())
+    throw.SetProjectileIcon(t.Icon(0))
+    throw.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        totalUtility := 0
+        for _, hitPos := range t.impactPositions(engine, caster, target) {
+            if enemy, ok := enemyPositions[hitPos]; ok {
+                if t.kind != ThrowableKindYellowPowder || (!enemy.IsBlinded() && !enemy.IsImmuneTo(StatusEffectNameBlinded)) {
+                    totalUtility += 15
+                }
+            }
+            if _, ok := allyPositions[hitPos]; ok {
+                totalUtility -= 25
+            }
+            if hitPos == caster.Pos() {
+                totalUtility -= 50
+            }
+        }
+        return totalUtility
+    })
+    throw.SetCombatUtilityForUseAtLocation(func(engine Engine, caster *Actor, userPosition geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        return 0
+    })
+    return throw
+}
+
+func (t *Throwable) impactPositions(engine Engine, thrower *Actor, target geometry.Point) []geometry.Point {
+    if t.radius() == 0 {
+        return []geometry.Point{target}
+    }
+    return allReachableTilesInRadius(t.radius())(engine, thrower, target)
+}
+
+func (t *Throwable) onImpact(engine Engine, thrower *Actor, pos geometry.Point) {
+    currentMap := engine.GetGridMap()
+    explosionIcon := int32(28)
+    switch t.kind {
+    case ThrowableKindOilFlask:
+        engine.CombatHitAnimation(pos, atlas.EntitiesGrayscale, explosionIcon, ega.BrightRed, func() {
+            engine.FixedDamageAt(thrower, pos, t.damage(), DamageTypeFire)
+        })
+    case ThrowableKindYellowPowder:
+        engine.CombatHitAnimation(pos, atlas.EntitiesGrayscale, explosionIcon, ega.Yellow, func() {
+            if currentMap.IsActorAt(pos) {
+                engine.AddStatusEffect(currentMap.ActorAt(pos), StatusBlinded(), 1)
+            }
+        })
+    case ThrowableKindThrowingDagger:
+        if !currentMap.IsActorAt(pos) {
+            return
+        }
+        victim := currentMap.ActorAt(pos)
+        engine.FixedDamageAt(thrower, pos, t.damage(), DamageTypePierce)
+        if victim.IsAlive() && RollChance(0.25) {
+            engine.AddStatusEffect(victim, StatusBleeding(), 1)
+        }
+    }
+}
+
+func NewRandomThrowable(random Random) *Throwable {
+    allKinds := []ThrowableKind{
+        ThrowableKindOilFlask,
+        ThrowableKindYellowPowder,
+        ThrowableKindThrowingDagger,
+    }
+    return NewThrowable(allKinds[random.Intn(len(allKinds))])
+}
@@ -47,6 +47,24 @@ func (w WorldTime) GetTime() string {
 func (w WorldTime) GetTimeAndDate() string {
     return fmt.Sprintf("%s, %s", w.GetTime(), w.GetDate())
 }
+
+// GetDaylight is the share of sunlight, from moonlight at night up to 1 during the day.
+// Dawn is from 5 to 7, dusk from 18 to 20.
+func (w WorldTime) GetDaylight() float64 {
+    const moonlight = 0.3
+    hours, minutes := w.HoursAndMinutes()
+    timeOfDay := float64(hours) + float64(minutes)/MinutesPerHour
+    switch {
+    case timeOfDay >= 7 && timeOfDay < 18:
+        return 1
+    case timeOfDay >= 5 && timeOfDay < 7:
+        return moonlight + (1-moonlight)*(timeOfDay-5)/2
+    case timeOfDay >= 18 && timeOfDay < 20:
+        return 1 - (1-moonlight)*(timeOfDay-18)/2
+    }
+    return moonlight
+}
+
 func NewWorldTime() WorldTime {
     return WorldTime{
         minutes: 0,
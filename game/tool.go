@@ -115,6 +115,9 @@ func (t *Tool) GetValue() int {
 }
 
 func NewRandomGeneralItemForVendor(level int) Item {
+    if rand.Intn(2) == 0 {
+        return NewRandomThrowable(GlobalRandom)
+    }
     allTools := []ToolType{
         ToolTypePickaxe,
         ToolTypeShovel,
@@ -0,0 +1,204 @@
+package game
+
+import (
+    "Legacy/ega"
+    "Legacy/geometry"
+    "Legacy/recfile"
+    "Legacy/util"
+    "fmt"
+    "image/color"
+)
+
+type TrapType string
+
+const (
+    TrapTypePit       TrapType = "pit"
+    TrapTypeDart      TrapType = "dart"
+    TrapTypeAlarm     TrapType = "alarm"
+    TrapTypeTeleport  TrapType = "teleport"
+    TrapTypePoisonGas TrapType = "poison_gas"
+)
+
+func AllTrapTypes() []TrapType {
+    return []TrapType{TrapTypePit, TrapTypeDart, TrapTypeAlarm, TrapTypeTeleport, TrapTypePoisonGas}
+}
+
+func TrapTypeFromString(trapType string) TrapType {
+    for _, knownType := range AllTrapTypes() {
+        if string(knownType) == trapType {
+            return knownType
+        }
+    }
+    return TrapTypeDart
+}
+
+// Trap is a hidden mechanism. It springs once, when someone steps on it.
+// The difficulty is used for noticing it in passing and for disarming it.
+type Trap struct {
+    BaseObject
+    trapType   TrapType
+    damage     int
+    difficulty DifficultyLevel
+    isArmed    bool
+    // only one passive check per trap, otherwise walking back and forth would find everything
+    wasNoticeRolled bool
+}
+
+func NewTrap(trapType TrapType, damage int, difficulty DifficultyLevel) *Trap {
+    trap := &Trap{
+        BaseObject: BaseObject{
+            icon: 202,
+            name: "a trap",
+        },
+        trapType:   trapType,
+        damage:     damage,
+        difficulty: difficulty,
+        isArmed:    true,
+    }
+    trap.SetDiscoveryMessage(true, []string{"You found a trap."})
+    return trap
+}
+
+func (t *Trap) TintColor() color.Color {
+    if t.isArmed {
+        return ega.BrightRed
+    }
+    return color.White
+}
+
+func (t *Trap) Description() []string {
+    var mechanism string
+    switch t.trapType {
+    case TrapTypePit:
+        mechanism = "A covered pit."
+    case TrapTypeAlarm:
+        mechanism = "A tripwire, tied to a bell."
+    case TrapTypeTeleport:
+        mechanism = "A glowing rune on the floor."
+    case TrapTypePoisonGas:
+        mechanism = "A pressure plate with vents next to it."
+    default:
+        mechanism = "A pressure plate with holes in the walls around it."
+    }
+    if t.isArmed {
+        return []string{mechanism, "Better not step on it."}
+    }
+    return []string{mechanism, "It is harmless now."}
+}
+
+func (t *Trap) IsWalkable(person *Actor) bool {
+    return true
+}
+
+func (t *Trap) IsTransparent() bool {
+    return true
+}
+
+func (t *Trap) IsPassableForProjectile() bool {
+    return true
+}
+
+// IsKnownHazard makes pathfinding walk around traps that have been found.
+func (t *Trap) IsKnownHazard() bool {
+    return t.isArmed && !t.isHidden
+}
+
+func (t *Trap) GetContextActions(engine Engine) []util.MenuItem {
+    actions := t.BaseObject.GetContextActions(engine, t)
+    party := engine.GetParty()
+    if t.isArmed && !t.isHidden && party.GetLockpicks() > 0 {
+        skill := ThievingSkillLockpicking
+        actions = append(actions, util.MenuItem{
+            Text: fmt.Sprintf("Disarm (lockpick) - %s", engine.GetRelativeDifficulty(skill, t.difficulty).ToString()),
+            Action: func() {
+                if !t.isArmed || party.GetLockpicks() == 0 {
+                    return
+                }
+                if engine.SkillCheckAvatar(skill, t.difficulty) {
+                    t.Disarm()
+                    engine.Print("You disarmed the trap.")
+                    return
+                }
+                engine.RemoveLockpick()
+                engine.Print("Your lockpick broke.")
+                if RollChance(0.33) {
+                    engine.SpringTrap(t, engine.GetAvatar())
+                }
+            },
+        })
+    }
+    return actions
+}
+
+func (t *Trap) GetType() TrapType {
+    return t.trapType
+}
+
+func (t *Trap) GetDifficulty() DifficultyLevel {
+    return t.difficulty
+}
+
+func (t *Trap) IsArmed() bool {
+    return t.isArmed
+}
+
+func (t *Trap) Disarm() {
+    t.isArmed = false
+}
+
+// RollNotice is true only the first time it is asked, a trap gets only one passive check.
+func (t *Trap) RollNotice() bool {
+    if t.wasNoticeRolled {
+        return false
+    }
+    t.wasNoticeRolled = true
+    return true
+}
+
+// Spring reveals the trap and returns the damage it deals.
+func (t *Trap) Spring() int {
+    t.isArmed = false
+    t.isHidden = false
+    return t.damage
+}
+
+func (t *Trap) ToRecordAndType() (recfile.Record, string) {
+    return recfile.Record{
+        {Name: "name", Value: t.name},
+        {Name: "icon", Value: recfile.Int32Str(t.icon)},
+        {Name: "pos", Value: t.Pos().Encode()},
+        {Name: "isHidden", Value: recfile.BoolStr(t.isHidden)},
+        {Name: "trapType", Value: string(t.trapType)},
+        {Name: "damage", Value: recfile.IntStr(t.damage)},
+        {Name: "difficulty", Value: t.difficulty.ToString()},
+        {Name: "isArmed", Value: recfile.BoolStr(t.isArmed)},
+        {Name: "wasNoticeRolled", Value: recfile.BoolStr(t.wasNoticeRolled)},
+    }, "trap"
+}
+
+func NewTrapFromRecord(record recfile.Record) *Trap {
+    trap := NewTrap(TrapTypeDart, 0, DifficultyLevelEasy)
+    for _, field := range record {
+        switch field.Name {
+        case "name":
+            trap.name = field.Value
+        case "icon":
+            trap.icon = field.AsInt32()
+        case "pos":
+            trap.SetPos(geometry.MustDecodePoint(field.Value))
+        case "isHidden":
+            trap.isHidden = field.AsBool()
+        case "trapType":
+            trap.trapType = TrapTypeFromString(field.Value)
+        case "damage":
+            trap.damage = field.AsInt()
+        case "difficulty":
+            trap.difficulty = DifficultyLevelFromString(field.Value)
+        case "isArmed":
+            trap.isArmed = field.AsBool()
+        case "wasNoticeRolled":
+            trap.wasNoticeRolled = field.AsBool()
+        }
+    }
+    return trap
+}
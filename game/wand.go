@@ -0,0 +1,221 @@
+package game
+
+import (
+    "Legacy/recfile"
+    "Legacy/util"
+    "fmt"
+    "image/color"
+    "math/rand"
+    "strings"
+)
+
+// Wand holds a spell that can be cast without mana, until its charges are used up.
+// An empty wand is not lost, it can be recharged by a spell or a vendor.
+type Wand struct {
+    BaseItem
+    spell      *Spell
+    spellName  string
+    charges    int
+    maxCharges int
+    wearer     ItemWearer
+}
+
+func NewWand(spellName string, charges int, maxCharges int) *Wand {
+    spell := NewSpellFromName(spellName)
+    if spell == nil {
+        println("ERR: unknown spell for wand:", spellName)
+        return nil
+    }
+    w := &Wand{
+        BaseItem: BaseItem{
+            name: fmt.Sprintf("wand of %s", strings.ToLower(spellName)),
+        },
+        spell:      spell,
+        spellName:  spellName,
+        charges:    min(charges, maxCharges),
+        maxCharges: maxCharges,
+    }
+    spell.canPayCost = func(engine Engine, user *Actor) bool {
+        return w.charges > 0
+    }
+    spell.payCost = func(engine Engine, user *Actor) {
+        w.useCharge(engine)
+    }
+    w.updateLabel()
+    return w
+}
+
+func NewWandFromPredicate(predicate recfile.StringPredicate) *Wand {
+    maxCharges := 5
+    if predicate.ParamCount() > 2 {
+        maxCharges = predicate.GetInt(2)
+    }
+    charges := maxCharges
+    if predicate.ParamCount() > 1 {
+        charges = predicate.GetInt(1)
+    }
+    return NewWand(predicate.GetString(0), charges, maxCharges)
+}
+
+func (w *Wand) Encode() string {
+    return recfile.ToPredicate("wand", w.spellName, recfile.IntStr(w.charges), recfile.IntStr(w.maxCharges))
+}
+
+func (w *Wand) useCharge(engine Engine) {
+    w.charges = max(0, w.charges-1)
+    w.updateLabel()
+    if w.charges == 0 {
+        engine.Print(fmt.Sprintf("The %s is depleted.", w.Name()))
+    }
+}
+
+func (w *Wand) updateLabel() {
+    w.spell.labelWithCost = fmt.Sprintf("%s [%d/%d]", w.spellName, w.charges, w.maxCharges)
+}
+
+func (w *Wand) GetCharges() int {
+    return w.charges
+}
+
+func (w *Wand) GetMaxCharges() int {
+    return w.maxCharges
+}
+
+func (w *Wand) NeedsRecharge() bool {
+    return w.charges < w.maxCharges
+}
+
+// Recharge fills the wand up to its maximum charges.
+func (w *Wand) Recharge() {
+    w.charges = w.maxCharges
+    w.updateLabel()
+}
+
+// GetRechargePrice is what a vendor asks for the missing charges.
+func (w *Wand) GetRechargePrice() int {
+    return (w.maxCharges - w.charges) * w.valuePerCharge()
+}
+
+func (w *Wand) valuePerCharge() int {
+    return max(50, w.spell.GetValue()/20)
+}
+
+func (w *Wand) GetValue() int {
+    return 100 + w.charges*w.valuePerCharge()
+}
+
+func (w *Wand) GetEmbeddedActions() []Action {
+    return []Action{w.spell}
+}
+
+func (w *Wand) GetWearer() ItemWearer {
+    return w.wearer
+}
+
+func (w *Wand) SetWearer(wearer ItemWearer) {
+    w.wearer = wearer
+}
+
+func (w *Wand) Unequip() {
+    if w.wearer != nil {
+        w.wearer.Unequip(w)
+    }
+}
+
+func (w *Wand) IsEquipped() bool {
+    return w.wearer != nil
+}
+
+func (w *Wand) IsBetterThan(other Handheld) bool {
+    return false
+}
+
+func (w *Wand) GetTooltipLines() []string {
+    lines := []string{
+        fmt.Sprintf("Casts \"%s\"", w.spellName),
+        fmt.Sprintf("Charges: %d/%d", w.charges, w.maxCharges),
+        "",
+    }
+    return append(lines, w.spell.GetDescription()...)
+}
+
+func (w *Wand) InventoryIcon() int32 {
+    return 167
+}
+
+func (w *Wand) Icon(uint64) int32 {
+    return int32(205)
+}
+
+func (w *Wand) TintColor() color.Color {
+    return w.spell.GetColor()
+}
+
+func (w *Wand) CanStackWith(other Item) bool {
+    return false
+}
+
+func (w *Wand) GetContextActions(engine Engine) []util.MenuItem {
+    actions := inventoryItemActions(w, engine)
+    if !engine.IsPlayerControlled(w.GetHolder()) {
+        return actions
+    }
+    if w.IsEquipped() {
+        return append([]util.MenuItem{{
+            Text: "Unequip",
+            Action: func() {
+                w.Unequip()
+            }}}, actions...)
+    }
+    return append([]util.MenuItem{{
+        Text: "Equip",
+        Action: func() {
+            engine.ShowEquipMenu(w)
+        }}}, actions...)
+}
+
+// findWandToRecharge prefers the wands in the hands of the caster over those in the party inventory.
+func findWandToRecharge(engine Engine, caster *Actor) (*Wand, bool) {
+    candidates := []Item{}
+    for _, handheld := range []Handheld{caster.equippedRightHand, caster.equippedLeftHand} {
+        if handheld != nil {
+            candidates = append(candidates, handheld)
+        }
+    }
+    if engine.IsPlayerControlled(caster) {
+        candidates = append(candidates, engine.GetPartyEquipment()...)
+    }
+    for _, item := range candidates {
+        if wand, isWand := item.(*Wand); isWand && wand.NeedsRecharge() {
+            return wand, true
+        }
+    }
+    return nil, false
+}
+
+// getWandSpellNames returns the spells up to the given level, that make sense on a wand.
+func getWandSpellNames(level int) []string {
+    var names []string
+    for spellLevel := 1; spellLevel <= max(3, level); spellLevel++ {
+        for _, name := range GetSpellNamesByLevel(spellLevel) {
+            spell := NewSpellFromName(name)
+            if spell.IsTargeted() || name == "Healing word of Tauci" || name == "Invisibility" {
+                names = append(names, name)
+            }
+        }
+    }
+    return names
+}
+
+// NewRandomWand is found in treasure, usually not fully charged.
+func NewRandomWand(random Random, level int) *Wand {
+    spellNames := getWandSpellNames(level)
+    maxCharges := 3 + random.Intn(4)
+    return NewWand(spellNames[random.Intn(len(spellNames))], 1+random.Intn(maxCharges), maxCharges)
+}
+
+func NewRandomWandForVendor(level int) *Wand {
+    spellNames := getWandSpellNames(level)
+    maxCharges := 3 + rand.Intn(4)
+    return NewWand(spellNames[rand.Intn(len(spellNames))], maxCharges, maxCharges)
+}
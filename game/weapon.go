@@ -71,10 +71,61 @@ func NewOnHitEffect(name string) OnHitEffect {
         }
         effect.toolTipLeft = "greed"
         effect.toolTipRight = "loot more gold (33%)"
+    case "stunning":
+        effect.condition = func(engine Engine, weapon *Weapon, attacker, victim *Actor) bool {
+            return rand.Float64() < 0.2 && victim.IsAlive()
+        }
+        effect.apply = func(engine Engine, weapon *Weapon, attacker, victim *Actor) {
+            engine.AddStatusEffect(victim, StatusStunned(), 1)
+        }
+        effect.toolTipLeft = "stun"
+        effect.toolTipRight = "skip a turn (20%)"
+    case "bleeding":
+        effect.condition = func(engine Engine, weapon *Weapon, attacker, victim *Actor) bool {
+            return rand.Float64() < 0.2 && victim.IsAlive()
+        }
+        effect.apply = func(engine Engine, weapon *Weapon, attacker, victim *Actor) {
+            engine.AddStatusEffect(victim, StatusBleeding(), 1)
+        }
+        effect.toolTipLeft = "bleed"
+        effect.toolTipRight = "2 dmg. per turn (20%)"
+    case "venomous":
+        effect.condition = func(engine Engine, weapon *Weapon, attacker, victim *Actor) bool {
+            return rand.Float64() < 0.33 && victim.IsAlive()
+        }
+        effect.apply = func(engine Engine, weapon *Weapon, attacker, victim *Actor) {
+            engine.AddStatusEffect(victim, StatusPoisoned(), 1)
+        }
+        effect.toolTipLeft = "poison"
+        effect.toolTipRight = "1 dmg. per turn (33%)"
+    case "flaming":
+        effect = newElementalOnHitEffect(DamageTypeFire, 4)
+    case "holy":
+        effect = newElementalOnHitEffect(DamageTypeHoly, 4)
     }
     return effect
 }
 
+// newElementalOnHitEffect deals extra damage of the given type on every hit.
+// A victim killed by it is left to whoever dealt the hit, so it only dies once.
+func newElementalOnHitEffect(damageType DamageType, amount int) OnHitEffect {
+    return OnHitEffect{
+        condition: func(engine Engine, weapon *Weapon, attacker, victim *Actor) bool {
+            return victim.IsAlive()
+        },
+        apply: func(engine Engine, weapon *Weapon, attacker, victim *Actor) {
+            breakdown := engine.GetRules().GetSpellDamageBreakdown(victim, amount, damageType)
+            engine.GetCombatLog().LogDamage(attacker, victim, breakdown)
+            if breakdown.Total > 0 {
+                victim.Damage(engine, breakdown.Total)
+                engine.Print(fmt.Sprintf("%d %s dmg. to '%s'", breakdown.Total, damageType, victim.Name()))
+            }
+        },
+        toolTipLeft:  string(damageType),
+        toolTipRight: fmt.Sprintf("+%d dmg. (100%%)", amount),
+    }
+}
+
 type Weapon struct {
     BaseItem
     wearer         ItemWearer
@@ -83,6 +134,7 @@ type Weapon struct {
     material       WeaponMaterial
     level          ItemTier
     onHitEffects   []OnHitEffect
+    damageType     DamageType
     useFixedDamage bool
     fixedDamage    int
 }
@@ -99,9 +151,18 @@ func (a *Weapon) GetTooltipLines() []string {
         {"Level", []string{string(a.level)}},
         {"Type", []string{string(a.weaponType)}},
         {"Material", []string{string(a.material)}},
-        {"Damage", []string{fmt.Sprintf("%d", a.GetBaseDamage())}},
+        {"Damage", []string{fmt.Sprintf("%d %s", a.GetBaseDamage(), a.GetDamageType())}},
         {"Value", []string{fmt.Sprintf("%dg", a.GetValue())}},
     }
+    for _, attack := range a.GetEmbeddedActions() {
+        rows = append(rows, util.TableRow{Label: "Special", Columns: []string{attack.Name()}})
+    }
+    if a.IsSpear() {
+        rows = append(rows, util.TableRow{Label: "Passive", Columns: []string{"Reach"}})
+    } else if a.IsShield() {
+        rows = append(rows, util.TableRow{Label: "Passive", Columns: []string{"Pinning"}})
+    }
+    rows = append(rows, util.TableRow{Label: "Critical", Columns: []string{a.criticalDescription()}})
     if len(a.onHitEffects) > 0 {
         rows = append(rows, util.TableRow{})
         rows = append(rows, util.TableRow{Label: "On Hit", Columns: []string{}})
@@ -267,8 +328,8 @@ const (
     WeaponMaterialObsidian WeaponMaterial = "obsidian"
 )
 
-func getRandomMaterial(lootLevel int) WeaponMaterial {
-    mod := rand.Intn(4) - 2
+func getRandomMaterial(random Random, lootLevel int) WeaponMaterial {
+    mod := random.Intn(4) - 2
     weaponMaterial := min(6, max(1, lootLevel+mod))
 
     switch weaponMaterial {
@@ -287,22 +348,22 @@ func getRandomMaterial(lootLevel int) WeaponMaterial {
     }
     return WeaponMaterialIron
 }
-func getRandomWeaponType() WeaponType {
+func getRandomWeaponType(random Random) WeaponType {
     allWeaponTypes := GetAllWeaponTypes()
-    randomIndex := rand.Intn(len(allWeaponTypes))
+    randomIndex := random.Intn(len(allWeaponTypes))
     return allWeaponTypes[randomIndex]
 }
 
-func NewRandomWeapon(lootLevel int) *Weapon {
-    weaponType := getRandomWeaponType()
-    material := getRandomMaterial(lootLevel)
-    level := tierFromLootLevel(lootLevel)
+func NewRandomWeapon(random Random, lootLevel int) *Weapon {
+    weaponType := getRandomWeaponType(random)
+    material := getRandomMaterial(random, lootLevel)
+    level := tierFromLootLevel(random, lootLevel)
     return NewWeapon(level, weaponType, material)
 }
 
 func NewRandomWeaponForVendor(lootLevel int) *Weapon {
-    weaponType := getRandomWeaponType()
-    material := getRandomMaterial(lootLevel)
+    weaponType := getRandomWeaponType(GlobalRandom)
+    material := getRandomMaterial(GlobalRandom, lootLevel)
     level := "common"
     if lootLevel > 1 {
         level = "uncommon"
@@ -324,6 +385,24 @@ func (a *Weapon) GetBaseDamage() int {
     return int(float64(a.damageByType(a.weaponType, a.level)) * a.materialMultiplier(a.material))
 }
 
+// GetDamageType is slash, pierce or blunt, depending on the weapon type, unless the weapon is special.
+func (a *Weapon) GetDamageType() DamageType {
+    if a.damageType != "" {
+        return a.damageType
+    }
+    switch a.weaponType {
+    case WeaponTypeSword, WeaponTypeGreatSword, WeaponTypeAxe:
+        return DamageTypeSlash
+    case WeaponTypeDagger, WeaponTypeSpear, WeaponTypeBow, WeaponTypeCrossbow:
+        return DamageTypePierce
+    }
+    return DamageTypeBlunt
+}
+
+func (a *Weapon) SetDamageType(damageType DamageType) {
+    a.damageType = damageType
+}
+
 func (a *Weapon) damageByType(weaponType WeaponType, level ItemTier) int {
     switch weaponType {
     case WeaponTypeDagger:
@@ -342,6 +421,10 @@ func (a *Weapon) damageByType(weaponType WeaponType, level ItemTier) int {
         return int(float64(15) * level.Multiplier())
     case WeaponTypeCrossbow:
         return int(float64(17) * level.Multiplier())
+    case WeaponTypeAxe:
+        return int(float64(18) * level.Multiplier())
+    case WeaponTypeShield:
+        return int(float64(8) * level.Multiplier())
     }
     return 1
 }
@@ -378,11 +461,44 @@ func (a *Weapon) IsHeldByPlayer(engine Engine) bool {
 func (a *Weapon) OnHitProc(engine Engine, attacker, victim *Actor) {
     for _, effect := range a.onHitEffects {
         if effect.condition(engine, a, attacker, victim) {
+            engine.GetCombatLog().LogEffect(victim, fmt.Sprintf("suffers on hit effect '%s'", effect.toolTipLeft))
             effect.apply(engine, a, attacker, victim)
         }
     }
 }
 
+func (a *Weapon) GetCriticalMultiplier() int {
+    switch a.weaponType {
+    case WeaponTypeDagger, WeaponTypeAxe, WeaponTypeGreatSword, WeaponTypeCrossbow:
+        return 3
+    }
+    return 2
+}
+
+// OnCriticalHit is the extra effect every weapon type has on a critical hit.
+func (a *Weapon) OnCriticalHit(engine Engine, attacker, victim *Actor) {
+    switch a.weaponType {
+    case WeaponTypeSword, WeaponTypeDagger, WeaponTypeAxe:
+        engine.AddStatusEffect(victim, StatusBleeding(), 1)
+    case WeaponTypeMace, WeaponTypeStaff, WeaponTypeShield:
+        engine.AddStatusEffect(victim, StatusStunned(), 1)
+    case WeaponTypeGreatSword, WeaponTypeSpear:
+        engine.KnockBack(attacker, victim)
+    }
+}
+
+func (a *Weapon) criticalDescription() string {
+    switch a.weaponType {
+    case WeaponTypeSword, WeaponTypeDagger, WeaponTypeAxe:
+        return fmt.Sprintf("x%d, bleeding", a.GetCriticalMultiplier())
+    case WeaponTypeMace, WeaponTypeStaff, WeaponTypeShield:
+        return fmt.Sprintf("x%d, stun", a.GetCriticalMultiplier())
+    case WeaponTypeGreatSword, WeaponTypeSpear:
+        return fmt.Sprintf("x%d, knockback", a.GetCriticalMultiplier())
+    }
+    return fmt.Sprintf("x%d", a.GetCriticalMultiplier())
+}
+
 func (a *Weapon) SetFixedDamage(newValue int) {
     a.useFixedDamage = true
     a.fixedDamage = newValue
@@ -392,6 +508,14 @@ func (a *Weapon) IsDagger() bool {
     return a.weaponType == WeaponTypeDagger
 }
 
+func (a *Weapon) IsShield() bool {
+    return a.weaponType == WeaponTypeShield
+}
+
+func (a *Weapon) IsSpear() bool {
+    return a.weaponType == WeaponTypeSpear
+}
+
 func NewWeaponFromPredicate(encoded recfile.StringPredicate) *Weapon {
     weapon := NewWeapon(
         ItemTier(encoded.GetString(0)),
@@ -404,7 +528,7 @@ func NewWeaponFromPredicate(encoded recfile.StringPredicate) *Weapon {
     return weapon
 }
 func NewWeapon(level ItemTier, weaponType WeaponType, material WeaponMaterial) *Weapon {
-    return &Weapon{
+    weapon := &Weapon{
         weaponType: weaponType,
         material:   material,
         BaseItem: BaseItem{
@@ -412,6 +536,13 @@ func NewWeapon(level ItemTier, weaponType WeaponType, material WeaponMaterial) *
         },
         level: level,
     }
+    if weaponType == WeaponTypeMace {
+        weapon.AddOnHitEffectByName("stunning")
+    }
+    if weaponType == WeaponTypeAxe {
+        weapon.AddOnHitEffectByName("bleeding")
+    }
+    return weapon
 }
 
 func NewNamedWeapon(weaponName string) *Weapon {
@@ -432,6 +563,21 @@ func NewNamedWeapon(weaponName string) *Weapon {
         weapon.SetName("robber's dagger")
         weapon.AddOnHitEffectByName("greed")
         return weapon
+    case "vipers_fang":
+        weapon := NewWeapon(ItemTierRare, WeaponTypeDagger, WeaponMaterialIron)
+        weapon.SetName("viper's fang")
+        weapon.AddOnHitEffectByName("venomous")
+        return weapon
+    case "sunblade":
+        weapon := NewWeapon(ItemTierRare, WeaponTypeSword, WeaponMaterialGold)
+        weapon.SetName("sunblade")
+        weapon.AddOnHitEffectByName("holy")
+        return weapon
+    case "ember_axe":
+        weapon := NewWeapon(ItemTierUncommon, WeaponTypeAxe, WeaponMaterialSteel)
+        weapon.SetName("ember axe")
+        weapon.AddOnHitEffectByName("flaming")
+        return weapon
     }
     println("ERR: unknown weapon name:", weaponName)
     return nil
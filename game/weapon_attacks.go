@@ -0,0 +1,247 @@
+package game
+
+import (
+    "Legacy/ega"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "Legacy/renderer/atlas"
+    "math/rand"
+)
+
+// GetEmbeddedActions returns the special attacks that come with the weapon type.
+// Daggers have no special attack, they strike twice from behind and tumble out of reach instead.
+func (a *Weapon) GetEmbeddedActions() []Action {
+    switch a.weaponType {
+    case WeaponTypeDagger:
+        return []Action{a.newTumble()}
+    case WeaponTypeSpear:
+        return []Action{a.newThrust()}
+    case WeaponTypeAxe:
+        return []Action{a.newCleave()}
+    case WeaponTypeGreatSword:
+        return []Action{a.newSweep()}
+    case WeaponTypeMace:
+        return []Action{a.newSmash()}
+    case WeaponTypeShield:
+        return []Action{a.newShieldBash(), a.newBlock()}
+    case WeaponTypeCrossbow:
+        return []Action{a.newPiercingBolt()}
+    }
+    return []Action{}
+}
+
+// newWeaponAttack creates a targeted attack with this weapon.
+// affectedPositions returns all positions that will be hit, when the attack is aimed at the target.
+func (a *Weapon) newWeaponAttack(
+    name string,
+    damagePercent int,
+    validTargets func(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool,
+    affectedPositions func(engine Engine, user *Actor, usePosition, target geometry.Point) []geometry.Point,
+    onHit func(engine Engine, user *Actor, victim *Actor),
+) *BaseAction {
+    attack := NewTargetedCombatSkill(name, func(engine Engine, user *Actor, pos geometry.Point) {
+        bloodIcon := int32(104)
+        for _, p := range affectedPositions(engine, user, user.Pos(), pos) {
+            hitPos := p
+            engine.CombatHitAnimation(hitPos, atlas.World, bloodIcon, ega.BrightWhite, func() {
+                victim := engine.WeaponDamageAt(user, a, hitPos, damagePercent)
+                if victim != nil && victim.IsAlive() && onHit != nil {
+                    onHit(engine, user, victim)
+                }
+            })
+        }
+    })
+    attack.SetValidTargets(validTargets)
+    attack.SetAffectedPositions(func(engine Engine, user *Actor, target geometry.Point) []geometry.Point {
+        return affectedPositions(engine, user, user.Pos(), target)
+    })
+    attack.SetHitPreview(func(engine Engine, user *Actor, victim *Actor) (float64, int) {
+        rules := engine.GetRules()
+        if a.IsRanged() {
+            return rules.GetRangedHitChance(user, victim), rules.GetRangedDamage(user, victim) * damagePercent / 100
+        }
+        chance := rules.GetMeleeHitChance(user, victim) * (1 - rules.GetShieldBlockChance(victim))
+        return chance, rules.GetMeleeDamage(user, victim) * damagePercent / 100
+    })
+    attack.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        utility := 0
+        for _, pos := range affectedPositions(engine, caster, caster.Pos(), target) {
+            if _, isEnemy := enemyPositions[pos]; isEnemy {
+                utility += damagePercent / 2
+            } else if _, isAlly := allyPositions[pos]; isAlly {
+                utility -= damagePercent
+            }
+        }
+        return utility
+    })
+    attack.SetCombatUtilityForUseAtLocation(func(engine Engine, caster *Actor, userPosition geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        return 0
+    })
+    attack.SetActionColor(a.level.Color())
+    return attack
+}
+
+// Spear: reaches two tiles in a straight line.
+func (a *Weapon) newThrust() *BaseAction {
+    thrust := a.newWeaponAttack("Thrust", 100, func(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool {
+        gridMap := engine.GetGridMap()
+        result := make(map[geometry.Point]bool)
+        for _, direction := range []geometry.Point{geometry.RelativeNorth, geometry.RelativeEast, geometry.RelativeSouth, geometry.RelativeWest} {
+            first := usePosition.Add(direction)
+            if isEnemyAt(gridMap, user, first) {
+                result[first] = true
+                continue
+            }
+            second := first.Add(direction)
+            if gridMap.Contains(first) && gridMap.IsTransparent(first) && isEnemyAt(gridMap, user, second) {
+                result[second] = true
+            }
+        }
+        return result
+    }, onlyTarget, nil)
+    thrust.SetDescription([]string{"Thrust at an enemy up to two tiles away."})
+    return thrust
+}
+
+// Axe: hits all enemies around the user.
+func (a *Weapon) newCleave() *BaseAction {
+    cleave := a.newWeaponAttack("Cleave", 75, adjacentEnemies, func(engine Engine, user *Actor, usePosition, target geometry.Point) []geometry.Point {
+        gridMap := engine.GetGridMap()
+        return gridMap.NeighborsAll(usePosition, func(p geometry.Point) bool {
+            return isEnemyAt(gridMap, user, p)
+        })
+    }, nil)
+    cleave.SetDescription([]string{"Cleave through all adjacent enemies", "for 75% damage."})
+    return cleave
+}
+
+// Great sword: sweeps an arc of three tiles in front of the user. Friend or foe.
+func (a *Weapon) newSweep() *BaseAction {
+    sweep := a.newWeaponAttack("Sweep", 80, adjacentEnemies, func(engine Engine, user *Actor, usePosition, target geometry.Point) []geometry.Point {
+        gridMap := engine.GetGridMap()
+        direction := target.Sub(usePosition)
+        perpendicular := geometry.Point{X: direction.Y, Y: direction.X}
+        var result []geometry.Point
+        for _, p := range []geometry.Point{target.Sub(perpendicular), target, target.Add(perpendicular)} {
+            if gridMap.Contains(p) && gridMap.IsActorAt(p) && gridMap.ActorAt(p) != user {
+                result = append(result, p)
+            }
+        }
+        return result
+    }, nil)
+    sweep.SetDescription([]string{"Sweep an arc of three tiles", "for 80% damage.", "Beware of hitting your allies."})
+    return sweep
+}
+
+// Mace: knocks the enemy back. If they can't move, they are stunned instead.
+func (a *Weapon) newSmash() *BaseAction {
+    smash := a.newWeaponAttack("Smash", 100, adjacentEnemies, onlyTarget, func(engine Engine, user *Actor, victim *Actor) {
+        if !engine.KnockBack(user, victim) {
+            engine.AddStatusEffect(victim, StatusStunned(), 1)
+        }
+    })
+    smash.SetDescription([]string{"Knock an enemy back.", "Stuns, if they can't move."})
+    return smash
+}
+
+// Shield: pushes the enemy back, but does little damage.
+func (a *Weapon) newShieldBash() *BaseAction {
+    bash := a.newWeaponAttack("Shield Bash", 50, adjacentEnemies, onlyTarget, func(engine Engine, user *Actor, victim *Actor) {
+        if !engine.KnockBack(user, victim) || rand.Float64() < 0.25 {
+            engine.AddStatusEffect(victim, StatusStunned(), 1)
+        }
+    })
+    bash.SetDescription([]string{"Bash an enemy for 50% damage", "and push them back."})
+    return bash
+}
+
+func (a *Weapon) newBlock() *BaseAction {
+    block := NewCombatSkill("Block", func(engine Engine, user *Actor) {
+        engine.AddStatusEffect(user, StatusBlocking(), 1)
+    })
+    block.SetDescription([]string{"Raise your shield until your next turn.", "Greatly improves the chance to block."})
+    block.SetCombatUtilityForUseAtLocation(func(engine Engine, caster *Actor, userPosition geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        if caster.GetHealth() > caster.GetMaxHealth()/3 {
+            return 0
+        }
+        adjacentEnemyCount := 0
+        for _, neighbor := range engine.GetGridMap().GetAllCardinalNeighbors(userPosition) {
+            if _, isEnemy := enemyPositions[neighbor]; isEnemy {
+                adjacentEnemyCount++
+            }
+        }
+        return adjacentEnemyCount * 15
+    })
+    block.SetCombatUtilityForTargetedUseOnLocation(func(engine Engine, caster *Actor, target geometry.Point, allyPositions, enemyPositions map[geometry.Point]*Actor) int {
+        return 0
+    })
+    block.SetActionColor(a.level.Color())
+    return block
+}
+
+// Dagger: roll away from the enemies, without giving them an opening.
+func (a *Weapon) newTumble() *BaseAction {
+    tumble := NewDisengageSkill("Tumble", 2)
+    tumble.SetDescription([]string{"Roll up to two tiles away,", "without provoking attacks."})
+    tumble.SetActionColor(a.level.Color())
+    return tumble
+}
+
+// Crossbow: the bolt passes through every enemy in its path.
+func (a *Weapon) newPiercingBolt() *BaseAction {
+    boltRange := 8
+    bolt := a.newWeaponAttack("Piercing Bolt", 100, func(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool {
+        gridMap := engine.GetGridMap()
+        result := make(map[geometry.Point]bool)
+        for _, actor := range gridMap.FindAllNearbyActors(usePosition, boltRange, func(actor *Actor) bool {
+            return actor != user && actor.IsAlive() && !actor.IsHidden() && !user.IsAllyOf(actor) && gridMap.IsInLineOfSight(usePosition, actor.Pos())
+        }) {
+            result[actor.Pos()] = true
+        }
+        return result
+    }, func(engine Engine, user *Actor, usePosition, target geometry.Point) []geometry.Point {
+        gridMap := engine.GetGridMap()
+        direction := target.Sub(usePosition)
+        steps := max(geometry.Abs(direction.X), geometry.Abs(direction.Y))
+        if steps == 0 {
+            return nil
+        }
+        farEnd := usePosition.Add(direction.Mul(boltRange).Div(steps))
+        path := geometry.BresenhamLoS(usePosition, farEnd, func(x, y int) bool {
+            p := geometry.Point{X: x, Y: y}
+            return gridMap.Contains(p) && (p == usePosition || gridMap.IsWalkable(p) || gridMap.IsActorAt(p))
+        })
+        var result []geometry.Point
+        for _, p := range path {
+            if isEnemyAt(gridMap, user, p) {
+                result = append(result, p)
+            }
+        }
+        return result
+    }, nil)
+    bolt.SetDescription([]string{"Shoot a bolt that pierces", "every enemy in a line."})
+    return bolt
+}
+
+func adjacentEnemies(engine Engine, user *Actor, usePosition geometry.Point) map[geometry.Point]bool {
+    gridMap := engine.GetGridMap()
+    result := make(map[geometry.Point]bool)
+    for _, pos := range gridMap.GetAllCardinalNeighbors(usePosition) {
+        if isEnemyAt(gridMap, user, pos) {
+            result[pos] = true
+        }
+    }
+    return result
+}
+
+func onlyTarget(engine Engine, user *Actor, usePosition, target geometry.Point) []geometry.Point {
+    return []geometry.Point{target}
+}
+
+func isEnemyAt(gridMap *gridmap.GridMap[*Actor, Item, Object], user *Actor, pos geometry.Point) bool {
+    if !gridMap.Contains(pos) || !gridMap.IsActorAt(pos) {
+        return false
+    }
+    actor := gridMap.ActorAt(pos)
+    return actor != user && actor.IsAlive() && !user.IsAllyOf(actor)
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "slices"
+)
+
+// Zones of control: everyone who can fight threatens the tiles next to them.
+// Leaving a threatened tile provokes a free melee attack from each enemy that threatens it.
+
+// ExertsZoneOfControl is false for actors that are helpless or busy running away.
+func (a *Actor) ExertsZoneOfControl() bool {
+    return a.CanAct() && !a.IsStunned() && !a.IsFrightened()
+}
+
+// Threatens is true, if the actor could strike at the position in melee.
+// Spears reach two tiles in a straight line, just like their thrust.
+func (a *Actor) Threatens(gridMap *gridmap.GridMap[*Actor, Item, Object], pos geometry.Point) bool {
+    delta := pos.Sub(a.Pos())
+    distance := geometry.DistanceManhattan(a.Pos(), pos)
+    if distance == 1 {
+        return true
+    }
+    weapon := a.GetMeleeWeapon()
+    if distance != 2 || (delta.X != 0 && delta.Y != 0) || weapon == nil || !weapon.IsSpear() || a.IsBlinded() {
+        return false
+    }
+    between := a.Pos().Add(delta.Div(2))
+    return gridMap.IsTransparent(between) && !gridMap.IsActorAt(between)
+}
+
+// PinsOnOpportunityAttack is true for shield bearers. Whoever they hit while passing by, has to stop.
+func (a *Actor) PinsOnOpportunityAttack() bool {
+    return a.HasShieldEquipped()
+}
+
+// GetOpportunityAttackers returns the enemies that get a free strike, when the mover leaves the position.
+// Nobody notices invisible actors slipping away.
+func GetOpportunityAttackers(gridMap *gridmap.GridMap[*Actor, Item, Object], mover *Actor, from geometry.Point) []*Actor {
+    if mover.IsInvisible() {
+        return nil
+    }
+    return gridMap.FindAllNearbyActors(from, 2, func(actor *Actor) bool {
+        return actor != mover && !mover.IsAllyOf(actor) && actor.ExertsZoneOfControl() && actor.Threatens(gridMap, from)
+    })
+}
+
+// GetCombatMovementMap works like a dijkstra map of the tiles the actor can reach this turn.
+// A walk ends in a threatened tile, because going on would provoke an attack.
+// The starting tile is the exception, leaving it is the price for moving at all.
+func GetCombatMovementMap(gridMap *gridmap.GridMap[*Actor, Item, Object], actor *Actor, maxCost int) map[geometry.Point]int {
+    start := actor.Pos()
+    movementMap := map[geometry.Point]int{start: 0}
+    frontier := []geometry.Point{start}
+    for cost := 1; cost <= maxCost && len(frontier) > 0; cost++ {
+        var nextFrontier []geometry.Point
+        for _, pos := range frontier {
+            if pos != start && len(GetOpportunityAttackers(gridMap, actor, pos)) > 0 {
+                continue
+            }
+            for _, neighbor := range gridMap.GetAllCardinalNeighbors(pos) {
+                if _, isKnown := movementMap[neighbor]; isKnown || !gridMap.IsCurrentlyPassable(neighbor) || gridMap.IsKnownHazardAt(neighbor) {
+                    continue
+                }
+                movementMap[neighbor] = cost
+                nextFrontier = append(nextFrontier, neighbor)
+            }
+        }
+        frontier = nextFrontier
+    }
+    return movementMap
+}
+
+// GetCombatPath walks back from the destination through the movement map.
+// It never passes through threatened tiles, unless the map was created without zones of control.
+// The path does not include the position of the actor.
+func GetCombatPath(gridMap *gridmap.GridMap[*Actor, Item, Object], actor *Actor, movementMap map[geometry.Point]int, dest geometry.Point) []geometry.Point {
+    if _, isReachable := movementMap[dest]; !isReachable || actor.Pos() == dest {
+        return []geometry.Point{}
+    }
+    path := []geometry.Point{dest}
+    currentPos := dest
+    for currentPos != actor.Pos() {
+        nextPos := currentPos
+        for _, neighbor := range gridMap.GetAllCardinalNeighbors(currentPos) {
+            cost, isKnown := movementMap[neighbor]
+            if !isKnown || cost >= movementMap[currentPos] {
+                continue
+            }
+            if neighbor == actor.Pos() || len(GetOpportunityAttackers(gridMap, actor, neighbor)) == 0 || nextPos == currentPos {
+                nextPos = neighbor
+            }
+        }
+        if nextPos == currentPos {
+            return []geometry.Point{}
+        }
+        path = append(path, nextPos)
+        currentPos = nextPos
+    }
+    path = path[:len(path)-1]
+    slices.Reverse(path)
+    return path
+}
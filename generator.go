@@ -6,44 +6,58 @@ import (
     "Legacy/geometry"
     "Legacy/gridmap"
     "fmt"
+    "math/rand"
     "strconv"
     "strings"
 )
 
+// defaultDungeonSeed is used for dungeon names without a seed, like !gen_dungeon_level_1.
+// Other dungeons carry their seed in the name, like !gen_dungeon_4711_level_1.
+const defaultDungeonSeed = int64(23)
+
+var ladderUpTile = gridmap.Tile{
+    DefinedIcon:        20,
+    DefinedDescription: "a ladder leading up",
+    IsWalkable:         true,
+    IsTransparent:      true,
+}
+
+var ladderDownTile = gridmap.Tile{
+    DefinedIcon:        21,
+    DefinedDescription: "a ladder leading down",
+    IsWalkable:         true,
+    IsTransparent:      true,
+}
+
 func (g *GridEngine) generateMap(targetMap string) *gridmap.GridMap[*game.Actor, game.Item, game.Object] {
     // !gen_dungeon_level_1
+    branch := g.getDungeonBranch(branchFromName(targetMap))
     level := levelFromName(targetMap)
-    // todo: get map meta data, we go with some defaults for now
+    seed := seedFromName(targetMap)
+    random := rand.New(rand.NewSource(levelSeed(seed, level)))
 
-    mapWidth := 32
-    mapHeight := 32
-    emptyMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](mapWidth, mapHeight, 11)
+    mapWidth := branch.width
+    mapHeight := branch.height
+    emptyMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](mapWidth, mapHeight, gridmap.DefaultMaxVisionRange)
+    emptyMap.SetName(targetMap)
 
-    dunGen := dungen.NewAccretionGenerator()
+    dunGen := dungen.NewGeneratorByName(branch.generator, levelSeed(seed, level))
     generatedLayout := dunGen.Generate(mapWidth, mapHeight)
-
-    ladderUpTile := gridmap.Tile{
-        DefinedIcon:        20,
-        DefinedDescription: "a ladder leading up",
-        IsWalkable:         true,
-        IsTransparent:      true,
-    }
-    ladderDownTile := gridmap.Tile{
-        DefinedIcon:        21,
-        DefinedDescription: "a ladder leading down",
-        IsWalkable:         true,
-        IsTransparent:      true,
+    if len(generatedLayout.AllRooms()) < 2 {
+        // the layout is of no use without two rooms for the ladders
+        generatedLayout = dungen.NewAccretionGeneratorWithSeed(levelSeed(seed, level)).Generate(mapWidth, mapHeight)
     }
+    prefabName, prefabRect, hasPrefab := g.reservePrefabSpace(generatedLayout, branch, random)
 
     wallTile := gridmap.Tile{
-        DefinedIcon:        80,
+        DefinedIcon:        branch.wallIcon,
         DefinedDescription: "a wall",
         IsWalkable:         false,
         IsTransparent:      false,
     }
 
     floorTile := gridmap.Tile{
-        DefinedIcon:        36,
+        DefinedIcon:        branch.floorIcon,
         DefinedDescription: "a floor",
         IsWalkable:         true,
         IsTransparent:      true,
@@ -57,7 +71,6 @@ func (g *GridEngine) generateMap(targetMap string) *gridmap.GridMap[*game.Actor,
                 emptyMap.SetTile(mapPos, wallTile)
             case dungen.Door:
                 emptyMap.SetTile(mapPos, floorTile)
-                emptyMap.AddObject(game.NewDoor(), mapPos)
             case dungen.Room:
                 fallthrough
             case dungen.Corridor:
@@ -68,34 +81,52 @@ func (g *GridEngine) generateMap(targetMap string) *gridmap.GridMap[*game.Actor,
         }
     }
 
-    mapEntryPosition := generatedLayout.GetCenterOfRandomRoom()
+    if hasPrefab {
+        g.stampPrefab(emptyMap, prefabName, prefabRect.Min, branch.displayName+" "+strconv.Itoa(level))
+        connectPrefab(emptyMap, prefabRect, floorTile)
+    }
+
+    rooms := generatedLayout.AllRooms()
+    entryRoom := rooms[random.Intn(len(rooms))]
+    exitRoom := entryRoom
+    for exitRoom == entryRoom && len(rooms) > 1 {
+        exitRoom = rooms[random.Intn(len(rooms))]
+    }
+
+    mapEntryPosition := entryRoom.Center()
+    connectAllFloor(emptyMap, mapEntryPosition, floorTile)
     emptyMap.SetTile(mapEntryPosition, ladderUpTile)
     emptyMap.AddNamedLocation("ladder_up", mapEntryPosition)
 
-    if level == 1 {
+    if level <= branch.firstLevel {
         emptyMap.AddTransitionAt(mapEntryPosition, gridmap.Transition{
-            TargetMap:      "Edge_Town",
-            TargetLocation: "dungeon_entrance",
+            TargetMap:      branch.entryMap,
+            TargetLocation: branch.entranceName(),
         })
     } else {
         emptyMap.AddTransitionAt(mapEntryPosition, gridmap.Transition{
-            TargetMap:      levelName(level - 1),
+            TargetMap:      levelName(branch.name, seed, level-1),
             TargetLocation: "ladder_down",
         })
     }
 
-    mapExitPosition := generatedLayout.GetCenterOfRandomRoom()
-    for mapExitPosition == mapEntryPosition {
-        mapExitPosition = generatedLayout.GetCenterOfRandomRoom()
-    }
+    mapExitPosition := exitRoom.Center()
     emptyMap.SetTile(mapExitPosition, ladderDownTile)
     emptyMap.AddNamedLocation("ladder_down", mapExitPosition)
     emptyMap.AddTransitionAt(mapExitPosition, gridmap.Transition{
-        TargetMap:      levelName(level + 1),
+        TargetMap:      levelName(branch.name, seed, level+1),
         TargetLocation: "ladder_up",
     })
 
-    emptyMap.SetDisplayName("Dungeon Level " + strconv.Itoa(level))
+    // no daylight down here, only the candles next to the ladders
+    emptyMap.SetMaxLightIntensity(branch.maxLightIntensity)
+    emptyMap.AddItem(game.NewCandle(true), mapEntryPosition)
+    emptyMap.AddItem(game.NewCandle(true), mapExitPosition)
+
+    g.placeDoors(emptyMap, generatedLayout, random, wallTile, mapEntryPosition, mapExitPosition)
+    g.populateDungeon(emptyMap, random, level, rooms, entryRoom, exitRoom)
+
+    emptyMap.SetDisplayName(branch.displayName + " " + strconv.Itoa(level))
 
     return emptyMap
 }
@@ -106,7 +137,122 @@ func levelFromName(targetMap string) int {
     return level
 }
 
-func levelName(level int) string {
+// seedFromName returns the seed of names like !gen_dungeon_4711_level_1.
+func seedFromName(targetMap string) int64 {
+    nameParts := strings.Split(targetMap, "_")
+    if len(nameParts) < 5 {
+        return defaultDungeonSeed
+    }
+    seed, err := strconv.ParseInt(nameParts[2], 10, 64)
+    if err != nil {
+        return defaultDungeonSeed
+    }
+    return seed
+}
+
+// levelSeed gives every level of a dungeon its own layout.
+func levelSeed(seed int64, level int) int64 {
+    return seed*1000 + int64(level)
+}
+
+// branchFromName returns the dungeon branch of names like !gen_mines_level_2.
+func branchFromName(targetMap string) string {
+    nameParts := strings.Split(targetMap, "_")
+    if len(nameParts) < 2 {
+        return defaultDungeonBranch
+    }
+    return nameParts[1]
+}
+
+func levelName(branchName string, seed int64, level int) string {
     //!gen_dungeon_level_1
-    return fmt.Sprintf("!gen_dungeon_level_%d", level)
+    if seed == defaultDungeonSeed {
+        return fmt.Sprintf("!gen_%s_level_%d", branchName, level)
+    }
+    return fmt.Sprintf("!gen_%s_%d_level_%d", branchName, seed, level)
+}
+
+// connectAllFloor digs tunnels, until every bit of floor can be reached from the start.
+// Caves and mazes can leave pockets that are cut off from the rest.
+func connectAllFloor(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], start geometry.Point, floorTile gridmap.Tile) {
+    // secret doors count as open, digging around them would give the secret away
+    isPassable := func(pos geometry.Point) bool {
+        return dungeonMap.IsTileWalkable(pos) || dungeonMap.IsSecretDoorAt(pos)
+    }
+    reached := make(map[geometry.Point]bool)
+    markReached := func(from geometry.Point) {
+        for _, pos := range dungeonMap.GetConnected(from, isPassable) {
+            reached[pos] = true
+        }
+    }
+    markReached(start)
+    for y := 0; y < dungeonMap.MapHeight; y++ {
+        for x := 0; x < dungeonMap.MapWidth; x++ {
+            pos := geometry.Point{X: x, Y: y}
+            if reached[pos] || !dungeonMap.IsTileWalkable(pos) {
+                continue
+            }
+            // the flood fill gives up after a while, a long way round still counts as connected
+            if path := dungeonMap.GetJPSPath(start, pos, isPassable); len(path) > 0 && path[len(path)-1] == pos {
+                markReached(pos)
+                continue
+            }
+            digTunnel(dungeonMap, pos, nearestReached(reached, pos), floorTile)
+            markReached(start)
+        }
+    }
+}
+
+func nearestReached(reached map[geometry.Point]bool, pos geometry.Point) geometry.Point {
+    nearest := pos
+    nearestDistance := -1
+    for reachedPos := range reached {
+        distance := geometry.DistanceManhattan(pos, reachedPos)
+        if nearestDistance < 0 || distance < nearestDistance || (distance == nearestDistance && (reachedPos.Y < nearest.Y || (reachedPos.Y == nearest.Y && reachedPos.X < nearest.X))) {
+            nearest = reachedPos
+            nearestDistance = distance
+        }
+    }
+    return nearest
+}
+
+// digTunnel makes an L-shaped tunnel, first along the x-axis, then along the y-axis.
+func digTunnel(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], from, to geometry.Point, floorTile gridmap.Tile) {
+    current := from
+    for current != to {
+        if current.X != to.X {
+            current.X += sign(to.X - current.X)
+        } else {
+            current.Y += sign(to.Y - current.Y)
+        }
+        if !dungeonMap.IsTileWalkable(current) {
+            dungeonMap.SetTile(current, floorTile)
+        }
+    }
+}
+
+// placeDoors puts doors into the openings of the layout. Some of them are secret doors,
+// but never one that is needed to get from the ladder up to the ladder down.
+func (g *GridEngine) placeDoors(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], layout *dungen.DungeonMap, random *rand.Rand, wallTile gridmap.Tile, entry, exit geometry.Point) {
+    for y := 0; y < dungeonMap.MapHeight; y++ {
+        for x := 0; x < dungeonMap.MapWidth; x++ {
+            doorPos := geometry.Point{X: x, Y: y}
+            if layout.GetTile(x, y) != dungen.Door {
+                continue
+            }
+            if random.Float64() < secretDoorChance && !isNeededForPath(dungeonMap, doorPos, entry, exit) {
+                dungeonMap.SetTile(doorPos, wallTile)
+                dungeonMap.SetSecretDoorAt(doorPos)
+                continue
+            }
+            dungeonMap.AddObject(game.NewDoor(), doorPos)
+        }
+    }
+}
+
+func isNeededForPath(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], blocked, start, end geometry.Point) bool {
+    path := dungeonMap.GetJPSPath(start, end, func(p geometry.Point) bool {
+        return p != blocked && dungeonMap.IsTileWalkable(p)
+    })
+    return len(path) == 0 || path[len(path)-1] != end
 }
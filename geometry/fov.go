@@ -117,6 +117,14 @@ func (fov *FOV) Visible(p Point) bool {
 	return fov.ShadowCasting[fov.idx(p)]
 }
 
+// Hide marks a position as not visible, until the next SCCVisionMap call.
+func (fov *FOV) Hide(p Point) {
+	if !p.In(fov.Rg) || fov.ShadowCasting == nil {
+		return
+	}
+	fov.ShadowCasting[fov.idx(p)] = false
+}
+
 func (fov *FOV) idx(p Point) int {
 	p = p.Sub(fov.Rg.Min)
 	w := fov.Rg.Max.X - fov.Rg.Min.X
@@ -0,0 +1,127 @@
+package gridmap
+
+import (
+    "Legacy/geometry"
+    "math/rand"
+)
+
+// Flammable objects can catch fire, like wooden doors.
+type Flammable interface {
+    IsFlammable() bool
+}
+
+const (
+    fireBurnTicks    = 4
+    smokeTicks       = 3
+    fireSpreadChance = 0.35
+)
+
+// EnvironmentChanges is what happened during one tick of the environment.
+type EnvironmentChanges struct {
+    Ignited   []geometry.Point
+    Burning   []geometry.Point
+    BurnedOut []geometry.Point
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsFlammableAt(p geometry.Point) bool {
+    if !m.Contains(p) {
+        return false
+    }
+    tile := m.GetCell(p).TileType
+    if tile.IsWater() {
+        return false
+    }
+    if tile.IsForest() {
+        return true
+    }
+    if objectAt, ok := m.TryGetObjectAt(p); ok {
+        if flammable, isFlammable := any(objectAt).(Flammable); isFlammable {
+            return flammable.IsFlammable()
+        }
+    }
+    return false
+}
+
+// Ignite sets the tile on fire. Returns false, if there is nothing to burn.
+func (m *GridMap[ActorType, ItemType, ObjectType]) Ignite(p geometry.Point) bool {
+    if m.IsBurningAt(p) || !m.IsFlammableAt(p) {
+        return false
+    }
+    if m.fires == nil {
+        m.fires = make(map[geometry.Point]int)
+    }
+    m.fires[p] = fireBurnTicks
+    return true
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsBurningAt(p geometry.Point) bool {
+    _, isBurning := m.fires[p]
+    return isBurning
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsSmokeAt(p geometry.Point) bool {
+    _, isSmoke := m.smoke[p]
+    return isSmoke
+}
+
+// Extinguish puts out the fire, which leaves some smoke behind.
+func (m *GridMap[ActorType, ItemType, ObjectType]) Extinguish(p geometry.Point) bool {
+    if !m.IsBurningAt(p) {
+        return false
+    }
+    delete(m.fires, p)
+    m.addSmoke(p)
+    return true
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) addSmoke(p geometry.Point) {
+    if m.smoke == nil {
+        m.smoke = make(map[geometry.Point]int)
+    }
+    m.smoke[p] = smokeTicks
+}
+
+// HasActiveEnvironment is true, while something is burning or smoking.
+func (m *GridMap[ActorType, ItemType, ObjectType]) HasActiveEnvironment() bool {
+    return len(m.fires) > 0 || len(m.smoke) > 0
+}
+
+// TickEnvironment advances fire and smoke by one step.
+// Fire spreads to flammable neighbors, burns out after a while and leaves smoke.
+// Water next to a fire makes it burn out faster.
+func (m *GridMap[ActorType, ItemType, ObjectType]) TickEnvironment() EnvironmentChanges {
+    var changes EnvironmentChanges
+    for pos, ticksLeft := range m.smoke {
+        if ticksLeft <= 1 {
+            delete(m.smoke, pos)
+        } else {
+            m.smoke[pos] = ticksLeft - 1
+        }
+    }
+
+    burningNow := make([]geometry.Point, 0, len(m.fires))
+    for pos, _ := range m.fires {
+        burningNow = append(burningNow, pos)
+    }
+    for _, pos := range burningNow {
+        for _, neighbor := range m.NeighborsCardinal(pos, m.IsFlammableAt) {
+            if rand.Float64() < fireSpreadChance && m.Ignite(neighbor) {
+                changes.Ignited = append(changes.Ignited, neighbor)
+            }
+        }
+        ticksLeft := m.fires[pos] - 1
+        if m.IsNextToTileWithSpecial(pos, SpecialTileWater) {
+            ticksLeft--
+        }
+        if ticksLeft <= 0 {
+            delete(m.fires, pos)
+            m.addSmoke(pos)
+            changes.BurnedOut = append(changes.BurnedOut, pos)
+            continue
+        }
+        m.fires[pos] = ticksLeft
+        changes.Burning = append(changes.Burning, pos)
+    }
+    changes.Burning = append(changes.Burning, changes.Ignited...)
+    return changes
+}
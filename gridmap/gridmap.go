@@ -131,6 +131,9 @@ func NewPublicZone(name string) *ZoneInfo {
     }
 }
 
+// DefaultMaxVisionRange is how far the party can see on maps that don't say otherwise.
+const DefaultMaxVisionRange = 19
+
 type GlobalMapDataOnDisk struct {
     MissionTitle      string
     Width             int
@@ -142,6 +145,33 @@ type GlobalMapDataOnDisk struct {
     AmbienceSoundCue  string
 }
 
+// GetGlobalData returns what describes the map as a whole, for saving it.
+func (m *GridMap[ActorType, ItemType, ObjectType]) GetGlobalData() GlobalMapDataOnDisk {
+    data := m.globalData
+    data.MissionTitle = m.displayName
+    data.Width = m.MapWidth
+    data.Height = m.MapHeight
+    data.PlayerSpawn = m.PlayerSpawn
+    data.TimeOfDay = m.TimeOfDay
+    data.AmbienceSoundCue = m.AmbienceSoundCue
+    return data
+}
+
+// SetGlobalData restores the data of a saved map. The size is fixed, when the map is created.
+func (m *GridMap[ActorType, ItemType, ObjectType]) SetGlobalData(data GlobalMapDataOnDisk) {
+    m.displayName = data.MissionTitle
+    m.PlayerSpawn = data.PlayerSpawn
+    m.TimeOfDay = data.TimeOfDay
+    m.AmbienceSoundCue = data.AmbienceSoundCue
+    m.SetMaxLightIntensity(data.MaxLightIntensity)
+    m.SetMaxVisionRange(data.MaxVisionRange)
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) SetMaxVisionRange(visionRange int) {
+    m.globalData.MaxVisionRange = visionRange
+    m.maxLOSRange = geometry.NewRect(-visionRange, -visionRange, visionRange+1, visionRange+1)
+}
+
 func (d GlobalMapDataOnDisk) ToString() string {
     return fmt.Sprintf("Mission Title: %s\nWidth: %d\nHeight: %d\nPlayer Spawn: %s\nMax Light Intensity: %f\nMax Vision Range: %d\nTime of Day: %s\nAmbience Sound Cue: %s",
         d.MissionTitle, d.Width, d.Height, d.PlayerSpawn.String(), d.MaxLightIntensity, d.MaxVisionRange, d.TimeOfDay.String(), d.AmbienceSoundCue)
@@ -167,9 +197,8 @@ type GridMap[ActorType interface {
 
     PlayerSpawn geometry.Point
 
-    MapWidth       int
-    MapHeight      int
-    MaxVisionRange int
+    MapWidth  int
+    MapHeight int
 
     pathfinder  *geometry.PathRange
     ListOfZones []*ZoneInfo
@@ -179,8 +208,9 @@ type GridMap[ActorType interface {
     maxLOSRange geometry.Rect
     TimeOfDay   time.Time
 
-    NamedLocations   map[string]geometry.Point
-    AmbienceSoundCue string
+    NamedLocations       map[string]geometry.Point
+    AmbienceSoundCue     string
+    CriminalOffenseEvent string
     noClip           bool
 
     transitionMap map[geometry.Point]Transition
@@ -191,6 +221,13 @@ type GridMap[ActorType interface {
 
     namedPaths  map[string][]geometry.Point
     displayName string
+
+    fires map[geometry.Point]int
+    smoke map[geometry.Point]int
+
+    globalData  GlobalMapDataOnDisk
+    lightLevels []float64
+    lightFOV    *geometry.FOV
 }
 
 func (m *GridMap[ActorType, ItemType, ObjectType]) AddZone(zone *ZoneInfo) {
@@ -512,25 +549,25 @@ func NewEmptyMap[ActorType interface {
     pathRange := geometry.NewPathRange(geometry.NewRect(0, 0, width, height))
     publicSpaceZone := NewPublicZone(PublicZoneName)
     m := &GridMap[ActorType, ItemType, ObjectType]{
-        Cells:           make([]MapCell[ActorType, ItemType, ObjectType], width*height),
-        AllActors:       make([]ActorType, 0),
-        AllDownedActors: make([]ActorType, 0),
-        AllItems:        make([]ItemType, 0),
-        AllObjects:      make([]ObjectType, 0),
-        NamedLocations:  map[string]geometry.Point{},
-        ListOfZones:     []*ZoneInfo{publicSpaceZone},
-        ZoneMap:         NewZoneMap(publicSpaceZone, width, height),
-        MapWidth:        width,
-        MapHeight:       height,
-        TimeOfDay:       time.Now(),
-        pathfinder:      pathRange,
-        maxLOSRange:     geometry.NewRect(-maxVisionRange, -maxVisionRange, maxVisionRange+1, maxVisionRange+1),
-        MaxVisionRange:  maxVisionRange,
-        secretDoors:     make(map[geometry.Point]bool),
-        transitionMap:   make(map[geometry.Point]Transition),
-        namedRects:      make(map[string]geometry.Rect),
-        namedTrigger:    make(map[string]Trigger),
-        namedPaths:      make(map[string][]geometry.Point),
+        Cells:             make([]MapCell[ActorType, ItemType, ObjectType], width*height),
+        AllActors:         make([]ActorType, 0),
+        AllDownedActors:   make([]ActorType, 0),
+        AllItems:          make([]ItemType, 0),
+        AllObjects:        make([]ObjectType, 0),
+        NamedLocations:    map[string]geometry.Point{},
+        ListOfZones:       []*ZoneInfo{publicSpaceZone},
+        ZoneMap:           NewZoneMap(publicSpaceZone, width, height),
+        MapWidth:          width,
+        MapHeight:         height,
+        TimeOfDay:         time.Now(),
+        pathfinder:        pathRange,
+        maxLOSRange:       geometry.NewRect(-maxVisionRange, -maxVisionRange, maxVisionRange+1, maxVisionRange+1),
+        globalData:        GlobalMapDataOnDisk{MaxLightIntensity: 1, MaxVisionRange: maxVisionRange},
+        secretDoors:       make(map[geometry.Point]bool),
+        transitionMap:     make(map[geometry.Point]Transition),
+        namedRects:        make(map[string]geometry.Rect),
+        namedTrigger:      make(map[string]Trigger),
+        namedPaths:        make(map[string][]geometry.Point),
     }
     m.Fill(MapCell[ActorType, ItemType, ObjectType]{
         TileType: Tile{
@@ -604,6 +641,10 @@ func (m *GridMap[ActorType, ItemType, ObjectType]) IsTransparent(p geometry.Poin
         return false
     }
 
+    if m.IsSmokeAt(p) {
+        return false
+    }
+
     return m.GetCell(p).TileType.IsTransparent
 }
 
@@ -726,6 +767,11 @@ func (m *GridMap[ActorType, ItemType, ObjectType]) IsCurrentlyPassable(p geometr
     }
     return m.IsWalkable(p) && (!m.IsActorAt(p)) //&& !knownAsBlocked
 }
+
+// IsCurrentlyPassableAndSafe is for walking around, without stepping into fire or a known trap.
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsCurrentlyPassableAndSafe(p geometry.Point) bool {
+    return m.IsCurrentlyPassable(p) && !m.IsObviousHazardAt(p)
+}
 func (m *GridMap[ActorType, ItemType, ObjectType]) CurrentlyPassableAndSafeForActor(person ActorType) func(p geometry.Point) bool {
     return func(p geometry.Point) bool {
         if !m.Contains(p) ||
@@ -747,7 +793,21 @@ func (m *GridMap[ActorType, ItemType, ObjectType]) IsWalkable(p geometry.Point)
     return cellAt.TileType.IsWalkable
 }
 func (m *GridMap[ActorType, ItemType, ObjectType]) IsObviousHazardAt(p geometry.Point) bool {
-    return m.IsLethalTileAt(p)
+    return m.IsLethalTileAt(p) || m.IsBurningAt(p) || m.IsKnownHazardAt(p)
+}
+
+// KnownHazard is an object that is only avoided once it has been found, like a trap.
+type KnownHazard interface {
+    IsKnownHazard() bool
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsKnownHazardAt(p geometry.Point) bool {
+    if objectAt, ok := m.TryGetObjectAt(p); ok {
+        if hazard, isHazard := any(objectAt).(KnownHazard); isHazard {
+            return hazard.IsKnownHazard()
+        }
+    }
+    return false
 }
 func (m *GridMap[ActorType, ItemType, ObjectType]) IsWalkableFor(p geometry.Point, person ActorType) bool {
     if !m.Contains(p) {
@@ -1060,6 +1120,23 @@ func (m *GridMap[ActorType, ItemType, ObjectType]) LineOfSight(source geometry.P
     })
     return los
 }
+
+// IsInLineOfSight returns true if no opaque tile or object is between source and target.
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsInLineOfSight(source geometry.Point, target geometry.Point) bool {
+    isVisible := true
+    m.Bresenheim(source, target, func(x, y int) bool {
+        visited := geometry.Point{X: x, Y: y}
+        if visited == source || visited == target {
+            return true
+        }
+        if !m.IsTransparent(visited) {
+            isVisible = false
+            return false
+        }
+        return true
+    })
+    return isVisible
+}
 func (m *GridMap[ActorType, ItemType, ObjectType]) Bresenheim(source geometry.Point, target geometry.Point, visitor func(x, y int) bool) {
     var dx, dy, e, slope int
     x1, y1 := target.X, target.Y
@@ -1335,7 +1412,7 @@ func (m *GridMap[ActorType, ItemType, ObjectType]) AddItem(item ItemType, spawnP
 }
 
 func (m *GridMap[ActorType, ItemType, ObjectType]) UpdateFieldOfView(fov *geometry.FOV, fovPosition geometry.Point) {
-    visionRange := 19 // 10
+    visionRange := m.globalData.MaxVisionRange
     visionRangeSquared := visionRange * visionRange
 
     var fovRange = geometry.NewRect(-visionRange, -visionRange, visionRange+1, visionRange+1)
@@ -1346,6 +1423,11 @@ func (m *GridMap[ActorType, ItemType, ObjectType]) UpdateFieldOfView(fov *geomet
     }, false)
 
     for _, p := range visionMap {
+        // in the dark, only what is right next to you can be made out
+        if m.IsDarkAt(p) && geometry.DistanceChebyshev(p, fovPosition) > 1 {
+            fov.Hide(p)
+            continue
+        }
         if !m.IsExplored(p) {
             m.SetExplored(p)
         }
@@ -0,0 +1,107 @@
+package gridmap
+
+import (
+    "Legacy/geometry"
+)
+
+// LightEmitter is implemented by items and objects that shed light, like candles and torches.
+// A radius of zero means the light is out.
+type LightEmitter interface {
+    GetLightRadius() int
+}
+
+// DarknessThreshold is the light level below which nothing can be made out.
+const DarknessThreshold = 0.15
+
+const fireLightRadius = 3
+
+// lightPropagation stops light at walls and closed doors.
+type lightPropagation struct {
+    isTransparent func(p geometry.Point) bool
+    radius        int
+}
+
+func (l lightPropagation) Cost(src geometry.Point, from geometry.Point, to geometry.Point) float64 {
+    if from == src || l.isTransparent(from) {
+        return 1
+    }
+    return float64(l.radius + 1)
+}
+
+func (l lightPropagation) MaxCost(src geometry.Point) float64 {
+    return float64(l.radius)
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) SetMaxLightIntensity(intensity float64) {
+    m.globalData.MaxLightIntensity = intensity
+}
+
+// UpdateLighting recomputes the light level of every cell.
+// Daylight is scaled by the max. light intensity of the map, so caves stay dark at noon.
+// Light sources lying around, fires and the given carried lights add to that.
+func (m *GridMap[ActorType, ItemType, ObjectType]) UpdateLighting(daylight float64, carriedLights map[geometry.Point]int) {
+    ambient := daylight * m.globalData.MaxLightIntensity
+    if m.lightLevels == nil || len(m.lightLevels) != len(m.Cells) {
+        m.lightLevels = make([]float64, len(m.Cells))
+    }
+    for i := range m.lightLevels {
+        m.lightLevels[i] = ambient
+    }
+    if m.lightFOV == nil {
+        m.lightFOV = geometry.NewFOV(geometry.NewRect(0, 0, m.MapWidth, m.MapHeight))
+    }
+
+    sources := make(map[geometry.Point]int)
+    addSource := func(pos geometry.Point, radius int) {
+        if radius > sources[pos] {
+            sources[pos] = radius
+        }
+    }
+    for _, item := range m.AllItems {
+        if emitter, isEmitter := any(item).(LightEmitter); isEmitter {
+            addSource(item.Pos(), emitter.GetLightRadius())
+        }
+    }
+    for _, object := range m.AllObjects {
+        if emitter, isEmitter := any(object).(LightEmitter); isEmitter {
+            addSource(object.Pos(), emitter.GetLightRadius())
+        }
+    }
+    for pos, _ := range m.fires {
+        addSource(pos, fireLightRadius)
+    }
+    for pos, radius := range carriedLights {
+        addSource(pos, radius)
+    }
+
+    for src, radius := range sources {
+        if radius <= 0 || !m.Contains(src) {
+            continue
+        }
+        lighter := lightPropagation{isTransparent: m.IsTransparent, radius: radius}
+        m.lightFOV.SetRange(geometry.NewRect(src.X-radius, src.Y-radius, src.X+radius+1, src.Y+radius+1).Intersect(geometry.NewRect(0, 0, m.MapWidth, m.MapHeight)))
+        for _, node := range m.lightFOV.LightMap(lighter, []geometry.Point{src}) {
+            index := node.P.Y*m.MapWidth + node.P.X
+            brightness := 1.0 - node.Cost/float64(radius+1)
+            if brightness > m.lightLevels[index] {
+                m.lightLevels[index] = brightness
+            }
+        }
+    }
+}
+
+// GetLightLevelAt returns a value between 0 (pitch black) and 1 (broad daylight).
+// Maps without a light map are fully lit.
+func (m *GridMap[ActorType, ItemType, ObjectType]) GetLightLevelAt(p geometry.Point) float64 {
+    if !m.Contains(p) {
+        return 0
+    }
+    if len(m.lightLevels) != len(m.Cells) {
+        return 1
+    }
+    return min(1, m.lightLevels[p.Y*m.MapWidth+p.X])
+}
+
+func (m *GridMap[ActorType, ItemType, ObjectType]) IsDarkAt(p geometry.Point) bool {
+    return m.GetLightLevelAt(p) < DarknessThreshold
+}
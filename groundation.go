@@ -7,6 +7,11 @@ import (
 )
 
 func (g *GridEngine) mapLookup(x, y int, tick uint64) (*ebiten.Image, int32, color.Color) {
+    textureAtlas, textureIndex, tintColor := g.unlitMapLookup(x, y, tick)
+    return textureAtlas, textureIndex, g.dimmedByLight(geometry.Point{X: x, Y: y}, tintColor)
+}
+
+func (g *GridEngine) unlitMapLookup(x, y int, tick uint64) (*ebiten.Image, int32, color.Color) {
     location := geometry.Point{X: x, Y: y}
     if !g.currentMap.Contains(location) {
         return g.worldTiles, 0, color.Black
@@ -16,7 +21,8 @@ func (g *GridEngine) mapLookup(x, y int, tick uint64) (*ebiten.Image, int32, col
         // can see this tile, so we lookup dynamic entities
         if g.currentMap.IsActorAt(location) {
             actorAt := g.currentMap.GetActor(location)
-            if !actorAt.IsHidden() {
+            isInvisibleEnemy := actorAt.IsInvisible() && !g.IsPlayerControlled(actorAt)
+            if !actorAt.IsHidden() && !isInvisibleEnemy {
                 if actorAt.IsTinted() {
                     return g.grayScaleEntityTiles, actorAt.Icon(tick), actorAt.TintColor()
                 }
@@ -39,12 +45,19 @@ func (g *GridEngine) mapLookup(x, y int, tick uint64) (*ebiten.Image, int32, col
         }
     }
 
+    if g.currentMap.IsBurningAt(location) {
+        return g.worldTiles, fireIcon, color.White
+    }
+
     if g.currentMap.IsObjectAt(location) {
         objectAt := g.currentMap.ObjectAt(location)
         if !objectAt.IsHidden() {
             return g.entityTiles, objectAt.Icon(tick), objectAt.TintColor()
         }
     }
+    if g.currentMap.IsSmokeAt(location) {
+        return g.worldTiles, smokeIcon, color.White
+    }
     tile := g.currentMap.GetCell(location)
 
     return g.worldTiles, tile.TileType.DefinedIcon, color.White
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 	"path"
+	"slices"
 	"sort"
 	"strings"
 )
@@ -41,6 +42,7 @@ func (g *GridEngine) Init() {
 	g.combatManager = NewCombatState(g)
 	g.rules = game.NewRules()
 	g.worldTime = game.NewWorldTime()
+	g.dungeonBranches = loadDungeonBranches()
 
 	g.ldtkMapProject, _ = ldtk_go.Open("assets/Legacy.ldtk")
 
@@ -55,12 +57,14 @@ func (g *GridEngine) Init() {
 
 	g.defenseBuffsButton = geometry.Point{X: 15, Y: 0}
 	g.offenseBuffsButton = geometry.Point{X: 24, Y: 0}
+	g.quickSlotsButton = geometry.Point{X: 30, Y: 0}
 
 	g.avatar = game.NewActor("---", 7)
 	g.playerParty = game.NewParty(g.avatar)
 	g.playerParty.InitWithRules(g.rules)
 	g.playerKnowledge = game.NewPlayerKnowledge()
 	g.flags = game.NewFlags()
+	g.bounties = game.NewBounties()
 
 	//g.currentMap = g.loadMap("WorldMap")
 	g.setMap(g.loadMap("Bed_Room"))
@@ -236,9 +240,19 @@ func (g *GridEngine) loadMap(mapName string) *gridmap.GridMap[*game.Actor, game.
 	npcLayer := currentMap.LayerByIdentifier("NPCs")
 	zoneLayer := currentMap.LayerByIdentifier("Zones")
 
-	loadedMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](environmentLayer.CellWidth, environmentLayer.CellHeight, 9)
+	loadedMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](environmentLayer.CellWidth, environmentLayer.CellHeight, gridmap.DefaultMaxVisionRange)
 	loadedMap.SetName(mapName)
 	loadedMap.SetDisplayName(mapDisplayName)
+	// levels without this property are lit by the sun
+	if lightIntensity := currentMap.PropertyByIdentifier("MaxLightIntensity"); lightIntensity != nil {
+		loadedMap.SetMaxLightIntensity(lightIntensity.AsFloat64())
+	}
+	if visionRange := currentMap.PropertyByIdentifier("MaxVisionRange"); visionRange != nil && !visionRange.IsNull() {
+		loadedMap.SetMaxVisionRange(visionRange.AsInt())
+	}
+	if offenseEvent := currentMap.PropertyByIdentifier("CriminalOffenseEvent"); offenseEvent != nil && !offenseEvent.IsNull() {
+		loadedMap.CriminalOffenseEvent = offenseEvent.AsString()
+	}
 
 	for _, metaEntity := range metaLayer.Entities {
 		gridPos := g.entityGridPos(metaEntity)
@@ -269,50 +283,13 @@ func (g *GridEngine) loadMap(mapName string) *gridmap.GridMap[*game.Actor, game.
 	for _, tile := range environmentLayer.Tiles {
 		posX, posY := environmentLayer.ToGridPosition(tile.Position[0], tile.Position[1])
 		pos := geometry.Point{X: posX, Y: posY}
-		enums := worldTileset.EnumsForTile(tile.ID)
-
-		walkable := !enums.Contains("IsBlockingMovement")
-		transparent := !enums.Contains("IsBlockingView")
-
-		specialTile := gridmap.SpecialTileNone
-		if enums.Contains("IsForest") && mapName == "WorldMap" {
-			specialTile = gridmap.SpecialTileForest
-		} else if enums.Contains("IsMountain") {
-			if mapName == "WorldMap" {
-				specialTile = gridmap.SpecialTileMountain
-			} else {
-				walkable = false
-				transparent = false
-			}
-		} else if enums.Contains("IsSwamp") && mapName == "WorldMap" {
-			specialTile = gridmap.SpecialTileSwamp
-		} else if enums.Contains("IsWater") {
-			specialTile = gridmap.SpecialTileWater
-		} else if enums.Contains("IsVoid") {
-			specialTile = gridmap.SpecialTileVoid
-		} else if enums.Contains("IsBed") {
-			specialTile = gridmap.SpecialTileBed
-		} else if enums.Contains("IsBreakable") {
-			if tile.ID == 108 {
-				specialTile = gridmap.SpecialTileBreakableGems
-			} else if tile.ID == 109 {
-				specialTile = gridmap.SpecialTileBreakableGold
-			} else if tile.ID >= 75 || tile.ID <= 79 {
-				specialTile = gridmap.SpecialTileBreakableGlass
-			} else {
-				specialTile = gridmap.SpecialTileBreakable
-			}
-		}
 		loadedMap.SetCell(pos, gridmap.MapCell[*game.Actor, game.Item, game.Object]{
-			TileType: gridmap.Tile{
-				DefinedIcon:   int32(tile.ID),
-				IsWalkable:    walkable,
-				IsTransparent: transparent,
-				Special:       specialTile,
-			},
+			TileType: tileFromLDtk(worldTileset, tile, mapName),
 		})
 	}
 
+	g.addDungeonEntrances(loadedMap)
+
 	for _, entity := range objectLayer.Entities {
 		posX, posY := objectLayer.ToGridPosition(entity.Position[0], entity.Position[1])
 		pos := geometry.Point{X: posX, Y: posY}
@@ -338,77 +315,128 @@ func (g *GridEngine) loadMap(mapName string) *gridmap.GridMap[*game.Actor, game.
 	for _, entity := range npcLayer.Entities {
 		posX, posY := npcLayer.ToGridPosition(entity.Position[0], entity.Position[1])
 		pos := geometry.Point{X: posX, Y: posY}
-		name := entity.PropertyByIdentifier("Name").AsString()
-		isHidden := entity.PropertyByIdentifier("IsHidden").AsBool()
-		iconFrames := entity.PropertyByIdentifier("IconFrames").AsInt()
-
-		var discoveryMessage []string
-		if !entity.PropertyByIdentifier("OnDiscovery").IsNull() {
-			discoveryMessage = strings.Split(entity.PropertyByIdentifier("OnDiscovery").AsString(), "\n")
+		npc := g.getNPCFromEntity(entity)
+		npc.SetHomePosition(pos)
+		if patrol := npc.GetPatrol(); patrol != nil && !patrol.HasWaypoints() {
+			patrol.SetWaypoints(slices.Clone(loadedMap.GetNamedPath(patrol.GetPathName())))
 		}
-
-		var combatFaction string
-		if !entity.PropertyByIdentifier("CombatFaction").IsNull() {
-			combatFaction = entity.PropertyByIdentifier("CombatFaction").AsString()
+		isAlive := entity.PropertyByIdentifier("IsAlive").AsBool()
+		if isAlive {
+			loadedMap.AddActor(npc, pos)
+			g.onActorMovedOrTeleported(loadedMap, npc, pos)
+		} else {
+			npc.SetHealth(0)
+			loadedMap.AddDownedActor(npc, pos)
 		}
+	}
 
-		textureIndex, enumsForIcon := g.resolveLDTKIcon(entity.PropertyByIdentifier("Icon"))
+	return loadedMap
+}
+func (g *GridEngine) getNPCFromEntity(entity *ldtk_go.Entity) *game.Actor {
+	name := entity.PropertyByIdentifier("Name").AsString()
+	isHidden := entity.PropertyByIdentifier("IsHidden").AsBool()
+	iconFrames := entity.PropertyByIdentifier("IconFrames").AsInt()
 
-		npcFilename := path.Join("assets", "npc", name+".txt")
-		// NOVA
-		if g.NovaPlays() && name == "hungry_caterpillar" {
-			textureIndex = 188
-		}
+	var discoveryMessage []string
+	if !entity.PropertyByIdentifier("OnDiscovery").IsNull() {
+		discoveryMessage = strings.Split(entity.PropertyByIdentifier("OnDiscovery").AsString(), "\n")
+	}
 
-		var npc *game.Actor
-		if doesFileExist(npcFilename) {
-			npc = game.NewActorFromFile(mustOpen(npcFilename), int32(textureIndex), g.gridRenderer.AutolayoutArrayToIconPages)
-		} else {
-			npc = game.NewActor(name, int32(textureIndex))
-		}
-		npc.SetIconFrames(iconFrames)
-		npc.SetInternalName(name)
-		npc.SetDiscoveryMessage(isHidden, discoveryMessage)
-		npc.SetCombatFaction(combatFaction)
+	var combatFaction string
+	if !entity.PropertyByIdentifier("CombatFaction").IsNull() {
+		combatFaction = entity.PropertyByIdentifier("CombatFaction").AsString()
+	}
 
-		if !enumsForIcon.Contains("IsHumanoid") {
-			npc.SetDeathIcon(224)
-		}
+	textureIndex, enumsForIcon := g.resolveLDTKIcon(entity.PropertyByIdentifier("Icon"))
 
-		// vendor inventory
-		vendorProp := entity.PropertyByIdentifier("AddVendorInventory")
-		vendorItemLevelProp := entity.PropertyByIdentifier("VendorItemLevel")
+	npcFilename := path.Join("assets", "npc", name+".txt")
+	// NOVA
+	if g.NovaPlays() && name == "hungry_caterpillar" {
+		textureIndex = 188
+	}
 
-		if !vendorProp.IsNull() && !vendorItemLevelProp.IsNull() {
-			lootType := game.Loot(strings.ToLower(vendorProp.Value.(string)))
-			lootLevel := vendorItemLevelProp.AsInt()
-			vendorItems := g.CreateItemsForVendor(lootType, lootLevel)
-			npc.SetVendorInventory(vendorItems)
-		}
+	var npc *game.Actor
+	if doesFileExist(npcFilename) {
+		npc = game.NewActorFromFile(mustOpen(npcFilename), int32(textureIndex), g.gridRenderer.AutolayoutArrayToIconPages)
+	} else {
+		npc = game.NewActor(name, int32(textureIndex))
+	}
+	npc.SetIconFrames(iconFrames)
+	npc.SetInternalName(name)
+	npc.SetDiscoveryMessage(isHidden, discoveryMessage)
+	npc.SetCombatFaction(combatFaction)
+	npc.SetGuard(entity.PropertyByIdentifier("IsGuard").AsBool())
 
-		npcLevel := entity.PropertyByIdentifier("Level").AsInt()
-		if npcLevel > npc.GetLevel() {
-			for npc.GetLevel() < npcLevel {
-				g.rules.LevelUp(npc)
-			}
-		}
-		if !entity.PropertyByIdentifier("IsAggressive").IsNull() {
-			npc.SetAggressive(entity.PropertyByIdentifier("IsAggressive").AsBool())
+	if !enumsForIcon.Contains("IsHumanoid") {
+		npc.SetDeathIcon(224)
+	}
+
+	// vendor inventory
+	vendorProp := entity.PropertyByIdentifier("AddVendorInventory")
+	vendorItemLevelProp := entity.PropertyByIdentifier("VendorItemLevel")
+
+	if !vendorProp.IsNull() && !vendorItemLevelProp.IsNull() {
+		lootType := game.Loot(strings.ToLower(vendorProp.Value.(string)))
+		lootLevel := vendorItemLevelProp.AsInt()
+		vendorItems := g.CreateItemsForVendor(lootType, lootLevel)
+		npc.SetVendorInventory(vendorItems)
+	}
+
+	npcLevel := entity.PropertyByIdentifier("Level").AsInt()
+	if npcLevel > npc.GetLevel() {
+		for npc.GetLevel() < npcLevel {
+			g.rules.LevelUp(npc)
 		}
-		if !entity.PropertyByIdentifier("EngagementRange").IsNull() {
-			npc.SetNPCEngagementRange(entity.PropertyByIdentifier("EngagementRange").AsInt())
+	}
+	if !entity.PropertyByIdentifier("IsAggressive").IsNull() {
+		npc.SetAggressive(entity.PropertyByIdentifier("IsAggressive").AsBool())
+	}
+	if !entity.PropertyByIdentifier("EngagementRange").IsNull() {
+		npc.SetNPCEngagementRange(entity.PropertyByIdentifier("EngagementRange").AsInt())
+	}
+	return npc
+}
+func tileFromLDtk(worldTileset *ldtk_go.Tileset, tile *ldtk_go.Tile, mapName string) gridmap.Tile {
+	enums := worldTileset.EnumsForTile(tile.ID)
+
+	walkable := !enums.Contains("IsBlockingMovement")
+	transparent := !enums.Contains("IsBlockingView")
+
+	specialTile := gridmap.SpecialTileNone
+	if enums.Contains("IsForest") && mapName == "WorldMap" {
+		specialTile = gridmap.SpecialTileForest
+	} else if enums.Contains("IsMountain") {
+		if mapName == "WorldMap" {
+			specialTile = gridmap.SpecialTileMountain
+		} else {
+			walkable = false
+			transparent = false
 		}
-		isAlive := entity.PropertyByIdentifier("IsAlive").AsBool()
-		if isAlive {
-			loadedMap.AddActor(npc, pos)
-			g.onActorMovedOrTeleported(loadedMap, npc, pos)
+	} else if enums.Contains("IsSwamp") && mapName == "WorldMap" {
+		specialTile = gridmap.SpecialTileSwamp
+	} else if enums.Contains("IsWater") {
+		specialTile = gridmap.SpecialTileWater
+	} else if enums.Contains("IsVoid") {
+		specialTile = gridmap.SpecialTileVoid
+	} else if enums.Contains("IsBed") {
+		specialTile = gridmap.SpecialTileBed
+	} else if enums.Contains("IsBreakable") {
+		if tile.ID == 108 {
+			specialTile = gridmap.SpecialTileBreakableGems
+		} else if tile.ID == 109 {
+			specialTile = gridmap.SpecialTileBreakableGold
+		} else if tile.ID >= 75 || tile.ID <= 79 {
+			specialTile = gridmap.SpecialTileBreakableGlass
 		} else {
-			npc.SetHealth(0)
-			loadedMap.AddDownedActor(npc, pos)
+			specialTile = gridmap.SpecialTileBreakable
 		}
 	}
-
-	return loadedMap
+	return gridmap.Tile{
+		DefinedIcon:   int32(tile.ID),
+		IsWalkable:    walkable,
+		IsTransparent: transparent,
+		Special:       specialTile,
+	}
 }
 func (g *GridEngine) resolveLDTKIcon(prop *ldtk_go.Property) (int32, ldtk_go.EnumSet) {
 	tileData := prop.Value.(map[string]interface{})
@@ -465,11 +493,6 @@ func (g *GridEngine) handleTransition(loadedMap *gridmap.GridMap[*game.Actor, ga
 	loadedMap.AddNamedLocation(nameOfLocation, gridPos)
 	if nameOfLocation == "player_spawn" {
 		g.spawnPosition = gridPos
-	} else if nameOfLocation == "dungeon_entrance" && loadedMap.GetName() == "Edge_Town" {
-		loadedMap.AddTransitionAt(gridPos, gridmap.Transition{
-			TargetMap:      "!gen_dungeon_level_1",
-			TargetLocation: "ladder_up",
-		})
 	} else if transition := g.entityToTransition(metaEntity); !transition.IsEmpty() {
 		loadedMap.AddTransitionAt(gridPos, transition)
 	}
@@ -597,6 +620,8 @@ func (g *GridEngine) getObjectFromEntity(entity *ldtk_go.Entity, mapDisplayName
 		return g.getWellFromEntity(entity)
 	case "Clock":
 		return g.getClockFromEntity(entity)
+	case "Trap":
+		return g.getTrapFromEntity(entity)
 	}
 	return nil
 }
@@ -695,6 +720,17 @@ func (g *GridEngine) getClockFromEntity(entity *ldtk_go.Entity) game.Object {
 	//name := entity.PropertyByIdentifier("Name").AsString()
 	return game.NewClock()
 }
+func (g *GridEngine) getTrapFromEntity(entity *ldtk_go.Entity) game.Object {
+	trapType := game.TrapTypeFromString(strings.ToLower(entity.PropertyByIdentifier("TrapType").AsString()))
+	damage := entity.PropertyByIdentifier("Damage").AsInt()
+	difficulty := entity.PropertyByIdentifier("Difficulty").AsString()
+	trap := game.NewTrap(trapType, damage, game.DifficultyLevelFromString(strings.ReplaceAll(difficulty, "_", " ")))
+	// traps are hidden, unless the level wants to show one off
+	if entity.PropertyByIdentifier("IsVisible").AsBool() {
+		trap.Discover()
+	}
+	return trap
+}
 func (g *GridEngine) getWellFromEntity(entity *ldtk_go.Entity) game.Object {
 	hasRope := entity.PropertyByIdentifier("HasRope").AsBool()
 	hasPlanks := entity.PropertyByIdentifier("HasPlanks").AsBool()
@@ -249,6 +249,7 @@ func (g *GridEngine) handleShortcuts() {
     // D + 1-4         - Character details (1-4)
     // F + 1-4         - Character status effects (1-4)
     // Shift + 1-4 - Switch control to character (1-4)
+    // Ctrl + 1-4  - Use quick slot (1-4) of the active character
     // O + 1-4     - Optimize equip for character (1-4)
     // U + 1-4     - Strip gear from character (1-4)
     // F12 - Toggle fullscreen
@@ -327,13 +328,19 @@ func (g *GridEngine) handleShortcuts() {
     }
 
     if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
-        if g.playerParty.HasFollowers() {
+        if g.combatManager.IsTargeting() {
+            g.combatManager.CycleTarget()
+        } else if g.playerParty.HasFollowers() {
             nextMember := g.playerParty.GetNextActiveMember(g.GetAvatar())
             g.SwitchAvatarTo(nextMember)
         }
     }
 
-    if charIndex >= 0 && charIndex < len(g.playerParty.GetMembers()) {
+    if charIndex >= 0 && ebiten.IsKeyPressed(ebiten.KeyControl) {
+        if !g.IsWindowOpen() {
+            g.useQuickSlot(g.GetAvatar(), charIndex)
+        }
+    } else if charIndex >= 0 && charIndex < len(g.playerParty.GetMembers()) {
         if ebiten.IsKeyPressed(ebiten.KeyO) {
             g.playerParty.GetMember(charIndex).AutoEquip(g)
             g.Print(fmt.Sprintf("Equipped %s", g.playerParty.GetMember(charIndex).Name()))
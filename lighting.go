@@ -0,0 +1,46 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "image/color"
+)
+
+// updateLighting recomputes the light map from daylight, the light sources on the map and the lights carried around.
+// The party shares its lights with the avatar.
+func (g *GridEngine) updateLighting() {
+    carriedLights := make(map[geometry.Point]int)
+    for _, actor := range g.currentMap.Actors() {
+        radius := actor.GetCarriedLightRadius()
+        if actor == g.GetAvatar() {
+            radius = max(radius, g.playerParty.GetCarriedLightRadius())
+        }
+        if radius > 0 {
+            carriedLights[actor.Pos()] = radius
+        }
+    }
+    g.currentMap.UpdateLighting(g.worldTime.GetDaylight(), carriedLights)
+}
+
+// dimmedByLight darkens the tint color according to the light level at the position.
+func (g *GridEngine) dimmedByLight(pos geometry.Point, tintColor color.Color) color.Color {
+    lightLevel := g.currentMap.GetLightLevelAt(pos)
+    if lightLevel >= 1 {
+        return tintColor
+    }
+    brightness := 0.4 + 0.6*lightLevel
+    r, gr, b, a := tintColor.RGBA()
+    return color.RGBA{
+        R: uint8(float64(r>>8) * brightness),
+        G: uint8(float64(gr>>8) * brightness),
+        B: uint8(float64(b>>8) * brightness),
+        A: uint8(a >> 8),
+    }
+}
+
+// sneakCheckInLight is easier for a sneaking party member in the dark.
+func (g *GridEngine) sneakCheckInLight(sneaker *game.Actor, observer *game.Actor) bool {
+    lightBonus := g.rules.GetSneakBonusForLight(g.currentMap.GetLightLevelAt(sneaker.Pos()))
+    difficulty := observer.GetAbsoluteDifficultyByAttribute(game.Perception).ReducedBy(lightBonus)
+    return g.SkillCheck(sneaker, game.ThievingSkillSneak, difficulty)
+}
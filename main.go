@@ -1,7 +1,6 @@
 package main
 
 import (
-    "Legacy/dungen"
     "Legacy/ega"
     "Legacy/game"
     "Legacy/geometry"
@@ -12,7 +11,6 @@ import (
     "Legacy/renderer"
     "Legacy/ui"
     "Legacy/util"
-    "bufio"
     "errors"
     "fmt"
     "github.com/hajimehoshi/ebiten/v2"
@@ -20,7 +18,6 @@ import (
     _ "image/png"
     "log"
     "math"
-    "os"
     "path"
     "sort"
     "strings"
@@ -48,8 +45,10 @@ type GridEngine struct {
     playerParty     *game.Party
     playerKnowledge *game.PlayerKnowledge
     flags           *game.Flags
+    bounties        *game.Bounties
     activeEvents    []game.GameEvent
     mapsInMemory    map[string]*gridmap.GridMap[*game.Actor, game.Item, game.Object]
+    dungeonBranches map[string]dungeonBranch
 
     // combat
     combatManager *CombatState
@@ -84,6 +83,7 @@ type GridEngine struct {
 
     defenseBuffsButton geometry.Point
     offenseBuffsButton geometry.Point
+    quickSlotsButton   geometry.Point
 
     gridRenderer  *renderer.DualGridRenderer
     mapRenderer   *renderer.MapRenderer
@@ -117,26 +117,7 @@ type GridEngine struct {
     overlayPositions        map[geometry.Point]color.Color
 }
 
-func testDungeonGenerator() {
-    gen := dungen.NewAccretionGenerator()
-
-    // get input from keyboard
-    scanner := bufio.NewScanner(os.Stdin)
-    text := ""
-    for text != "q" {
-        dunMap := gen.Generate(32, 16)
-        dunMap.Print()
-        scanner.Scan()
-        text = scanner.Text()
-    }
-}
 func main() {
-    /*
-       testDungeonGenerator()
-       return
-
-    */
-
     // Create a CPU profile file
     /*
        cpuProfileFile, err := os.Create("cpu.prof")
@@ -176,6 +157,7 @@ func main() {
         movementRoutine:      gocoro.NewCoroutine(),
         overlayPositions:     make(map[geometry.Point]color.Color),
     }
+    util.TicksPerSecond = ebiten.ActualTPS
     ebiten.SetWindowTitle(gameTitle)
     ebiten.SetWindowSize(scaledScreenWidth, scaledScreenHeight)
     ebiten.SetScreenClearedEveryFrame(true)
@@ -208,6 +190,7 @@ func (g *GridEngine) TryMoveNPCOnPath(actor *game.Actor, dest geometry.Point) {
     if !actor.IsSleeping() && actor.IsAlive() {
         if g.currentMap.IsCurrentlyPassable(dest) {
             g.currentMap.MoveActor(actor, dest)
+            g.checkForTrapAt(g.currentMap, actor, dest)
         }
     }
 }
@@ -275,7 +258,11 @@ func (g *GridEngine) SkillCheck(actor *game.Actor, skill game.SkillName, difficu
         return game.RollChance(0.01)
     }
     skillLevel := skills.GetLevel(skill)
-    return g.rules.RollSkillCheck(skillLevel, difficulty)
+    isSuccess, wasLuck := g.rules.RollSkillCheckWithLuck(skillLevel, difficulty, actor.GetLuckModifier())
+    if wasLuck {
+        g.Print(game.SkillCheckLuckMessage(actor, isSuccess))
+    }
+    return isSuccess
 }
 
 func (g *GridEngine) OnMouseWheel(x int, y int, dy float64) bool {
@@ -288,13 +275,31 @@ func (g *GridEngine) GetWorldTime() game.WorldTime {
 
 func (g *GridEngine) AdvanceWorldTime(days, hours, minutes int) {
     g.worldTime = g.worldTime.WithAddedDays(days).WithAddedMinutes(hours*game.MinutesPerHour + minutes)
+    g.onWorldTimeAdvanced(days*game.MinutesPerDay + hours*game.MinutesPerHour + minutes)
 }
 
 func (g *GridEngine) AdvanceWorldTimeWithMessage(days, hours, minutes int) {
-    g.worldTime = g.worldTime.WithAddedDays(days).WithAddedMinutes(hours*game.MinutesPerHour + minutes)
+    g.AdvanceWorldTime(days, hours, minutes)
     g.printTimePassedMessage(days, hours, minutes)
 }
 
+func (g *GridEngine) onWorldTimeAdvanced(minutes int) {
+    g.bounties.OnTimePassed(minutes, g.rules.GetBountyDecayPerDay())
+    if !g.IsInCombat() {
+        // in combat, status effects tick with the turns instead
+        for _, actor := range g.currentMap.Actors() {
+            actor.OnWorldTimePassed(g, minutes)
+        }
+        for i := 0; i < minutes && g.currentMap.HasActiveEnvironment(); i++ {
+            g.tickEnvironment()
+        }
+    }
+    g.updateSchedules(minutes)
+    g.updatePatrols(minutes)
+    g.updateLighting()
+    g.currentMap.UpdateFieldOfView(g.playerParty.GetFoV(), g.GetAvatar().Pos())
+}
+
 func (g *GridEngine) printTimePassedMessage(days int, hours int, minutes int) {
     if days == 0 {
         if hours == 0 {
@@ -474,6 +479,10 @@ func (g *GridEngine) GetRules() *game.Rules {
     return g.rules
 }
 
+func (g *GridEngine) GetCombatLog() *game.CombatLog {
+    return g.combatManager.combatLog
+}
+
 func (g *GridEngine) GetPartyEquipment() []game.Item {
     return g.playerParty.GetFlatInventory()
 }
@@ -499,16 +508,98 @@ func (g *GridEngine) TileAnimation(pos geometry.Point, atlasName renderer.AtlasN
     g.animator.AddDefaultHitAnimation(pos, atlasName, icon, tintColor, whenDone)
 }
 
-func (g *GridEngine) FixedDamageAt(caster *game.Actor, pos geometry.Point, amount int) {
+func (g *GridEngine) FixedDamageAt(caster *game.Actor, pos geometry.Point, amount int, damageType game.DamageType) {
     if g.currentMap.IsActorAt(pos) {
         actor := g.currentMap.ActorAt(pos)
-        g.DeliverSpellDamage(caster, actor, amount)
+        g.DeliverSpellDamage(caster, actor, amount, damageType)
         g.combatManager.OnCombatAction(caster, actor)
         if !actor.IsAlive() {
             g.actorDied(actor)
         }
     }
 }
+// WeaponDamageAt strikes the actor at pos with the weapon, for a percentage of the usual damage.
+// Returns the actor that was hit, or nil on a miss.
+func (g *GridEngine) WeaponDamageAt(attacker *game.Actor, weapon *game.Weapon, pos geometry.Point, damagePercent int) *game.Actor {
+    if !g.currentMap.IsActorAt(pos) {
+        return nil
+    }
+    victim := g.currentMap.ActorAt(pos)
+    if victim == attacker || !victim.IsAlive() {
+        return nil
+    }
+    g.combatManager.OnCombatAction(attacker, victim)
+    var attackRoll game.AttackRoll
+    var breakdown game.DamageBreakdown
+    if weapon.IsRanged() {
+        attackRoll = g.rules.RollRangedAttack(g.GetCombatLog(), attacker, victim)
+        breakdown = g.rules.GetRangedDamageBreakdown(attacker, victim, attackRoll.IsCritical())
+    } else {
+        attackRoll = g.rules.RollMeleeAttack(g.GetCombatLog(), attacker, victim)
+        breakdown = g.rules.GetMeleeDamageBreakdown(attacker, victim, attackRoll.IsCritical())
+        if attackRoll.IsHit() && g.rules.DoesShieldBlock(victim) {
+            g.GetCombatLog().LogEffect(victim, "blocks with the shield")
+            g.Print(fmt.Sprintf("'%s' blocks the attack", victim.Name()))
+            return nil
+        }
+    }
+    if attackRoll.IsFumble() {
+        g.combatManager.combat.Fumble(attacker)
+        return nil
+    }
+    if !attackRoll.IsHit() {
+        g.Print(fmt.Sprintf("'%s' misses '%s'", attacker.Name(), victim.Name()))
+        return nil
+    }
+    if attackRoll.IsCritical() {
+        g.Print(fmt.Sprintf("Critical hit by '%s'!", attacker.Name()))
+    }
+    breakdown = breakdown.Scaled(damagePercent)
+    g.GetCombatLog().LogDamage(attacker, victim, breakdown)
+    if damage := breakdown.Total; damage > 0 {
+        victim.Damage(g, damage)
+        g.Print(fmt.Sprintf("%d dmg. to '%s'", damage, victim.Name()))
+    } else {
+        g.Print(fmt.Sprintf("No dmg. to '%s'", victim.Name()))
+    }
+    weapon.OnHitProc(g, attacker, victim)
+    if attackRoll.IsCritical() && victim.IsAlive() {
+        weapon.OnCriticalHit(g, attacker, victim)
+    }
+    if !victim.IsAlive() {
+        g.actorDied(victim)
+    }
+    return victim
+}
+
+// KnockBack pushes the victim one tile away from the attacker.
+// Returns false, if there is no room behind the victim.
+func (g *GridEngine) KnockBack(attacker *game.Actor, victim *game.Actor) bool {
+    delta := victim.Pos().Sub(attacker.Pos())
+    direction := geometry.Point{X: sign(delta.X), Y: sign(delta.Y)}
+    dest := victim.Pos().Add(direction)
+    if direction == geometry.PointZero || !g.currentMap.Contains(dest) || !g.currentMap.IsCurrentlyPassable(dest) {
+        return false
+    }
+    g.moveActorInCombat(victim, dest)
+    g.Print(fmt.Sprintf("'%s' is knocked back", victim.Name()))
+    return true
+}
+
+// Reposition moves the actor without provoking opportunity attacks.
+func (g *GridEngine) Reposition(actor *game.Actor, dest geometry.Point) {
+    g.moveActorInCombat(actor, dest)
+}
+
+func sign(x int) int {
+    if x < 0 {
+        return -1
+    } else if x > 0 {
+        return 1
+    }
+    return 0
+}
+
 func (g *GridEngine) PlayerTriesBackstab(opponent *game.Actor) {
     if !opponent.IsAlive() {
         g.Print(fmt.Sprintf("'%s' is already dead.", opponent.Name()))
@@ -516,9 +607,12 @@ func (g *GridEngine) PlayerTriesBackstab(opponent *game.Actor) {
     }
     if g.SkillCheckAvatarVs(game.PhysicalSkillBackstab, opponent, game.Perception) {
         g.Kill(opponent)
+        if !opponent.IsAggressive() {
+            g.reportCrime(game.CrimeMurder, opponent, opponent.Pos())
+        }
     } else {
         g.Print("Your attack was noticed!")
-        g.onCriminalOffense(opponent)
+        g.onCriminalOffense(game.CrimeAssault, opponent)
     }
 }
 func (g *GridEngine) PlayerStartsCombat(opponent *game.Actor) {
@@ -526,6 +620,9 @@ func (g *GridEngine) PlayerStartsCombat(opponent *game.Actor) {
         g.Print(fmt.Sprintf("'%s' is already dead.", opponent.Name()))
         return
     }
+    if !g.IsInCombat() && !opponent.IsAggressive() {
+        g.reportCrime(game.CrimeAssault, opponent, opponent.Pos())
+    }
     g.combatManager.MeleeAttack(g.GetAvatar(), opponent)
 }
 
@@ -565,6 +662,9 @@ func (g *GridEngine) MapToScreenCoordinates(pos geometry.Point) geometry.Point {
 func (g *GridEngine) OnMouseMoved(x int, y int) (bool, ui.Tooltip) {
     screenSize := g.gridRenderer.GetSmallGridScreenSize()
     oneFourth := screenSize.X / 4
+    if slotIndex, isQuickSlot := g.quickSlotAt(x, y); isQuickSlot {
+        return true, g.quickSlotTooltip(slotIndex, x, y)
+    }
     if y == screenSize.Y-1 {
         // each 1/4 of the screen is a different UI
         if x < oneFourth {
@@ -627,6 +727,11 @@ func (g *GridEngine) OnMouseClicked(x int, y int) bool {
         return true
     }
 
+    if slotIndex, isQuickSlot := g.quickSlotAt(x, y); isQuickSlot {
+        g.useQuickSlot(g.GetAvatar(), slotIndex)
+        return true
+    }
+
     if y == screenSize.Y-2 {
         if x == g.foodButton.X {
             g.TryRestParty()
@@ -676,6 +781,7 @@ func (g *GridEngine) onViewedActorMoved(newPosition geometry.Point) {
     } else {
         g.mapWindow.EnsurePositionIsInview(newPosition, 5)
     }
+    g.updateLighting()
     g.currentMap.UpdateFieldOfView(g.playerParty.GetFoV(), newPosition)
 }
 
@@ -701,7 +807,7 @@ func (g *GridEngine) drawPrintMessage(screen *ebiten.Image, upper bool) {
 
 func (g *GridEngine) DrinkPotion(potion *game.Potion, member *game.Actor) {
 
-    member.AddMana(10)
+    potion.OnDrink(g, member)
     g.growGrassAt(member.Pos())
     g.RemoveItem(potion)
 
@@ -778,16 +884,7 @@ func (g *GridEngine) TryPickpocketItem(item game.Item, victim *game.Actor) {
         g.flags.IncrementFlag("pickpocket_successes")
     } else {
         g.Print(fmt.Sprintf("You were caught stealing \"%s\"", item.Name()))
-        g.onCriminalOffense(victim)
-    }
-}
-
-func (g *GridEngine) onCriminalOffense(victim *game.Actor) {
-    offenseEvent := g.rules.GetCriminalOffenseEvent(g.currentMap.GetName())
-    if offenseEvent != "" {
-        g.TriggerEvent(offenseEvent)
-    } else {
-        g.EnemyStartsCombat(victim)
+        g.onCriminalOffense(game.CrimeTheft, victim)
     }
 }
 
@@ -799,7 +896,7 @@ func (g *GridEngine) TryPlantItem(item game.Item, victim *game.Actor) {
         g.flags.IncrementFlag("plant_successes")
     } else {
         g.Print(fmt.Sprintf("You were caught planting \"%s\"", item.Name()))
-        g.onCriminalOffense(victim)
+        g.onCriminalOffense(game.CrimePlanting, victim)
     }
 }
 
@@ -818,6 +915,22 @@ func (g *GridEngine) createScrollsForVendor(level, amount int) []game.Item {
     return armor
 }
 
+func (g *GridEngine) createWandsForVendor(level, amount int) []game.Item {
+    var wands []game.Item
+    for i := 0; i < amount; i++ {
+        wands = append(wands, game.NewRandomWandForVendor(level))
+    }
+    return wands
+}
+
+func (g *GridEngine) createThrowablesForLoot(random game.Random, amount int) []game.Item {
+    var throwables []game.Item
+    for i := 0; i < amount; i++ {
+        throwables = append(throwables, game.NewRandomThrowable(random))
+    }
+    return throwables
+}
+
 func (g *GridEngine) createItemsForGeneralStoreVendor(level int, amount int) []game.Item {
     var items []game.Item
     for i := 0; i < amount; i++ {
@@ -832,10 +945,10 @@ func (g *GridEngine) createArmorForVendor(level, amount int) []game.Item {
     }
     return armor
 }
-func (g *GridEngine) createArmorForLoot(level, amount int) []game.Item {
+func (g *GridEngine) createArmorForLoot(random game.Random, level, amount int) []game.Item {
     var armor []game.Item
     for i := 0; i < amount; i++ {
-        armor = append(armor, game.NewRandomArmor(level))
+        armor = append(armor, game.NewRandomArmor(random, level))
     }
     return armor
 }
@@ -848,10 +961,10 @@ func (g *GridEngine) createWeaponsForVendor(level int, amount int) []game.Item {
     return weapons
 }
 
-func (g *GridEngine) createWeaponsForLoot(level int, amount int) []game.Item {
+func (g *GridEngine) createWeaponsForLoot(random game.Random, level int, amount int) []game.Item {
     var weapons []game.Item
     for i := 0; i < amount; i++ {
-        weapons = append(weapons, game.NewRandomWeapon(level))
+        weapons = append(weapons, game.NewRandomWeapon(random, level))
     }
     return weapons
 }
@@ -865,13 +978,27 @@ func (g *GridEngine) RunAnimationScript(script func(exe *gocoro.Execution)) erro
 }
 
 func (g *GridEngine) actorDied(actor *game.Actor) {
+    if g.IsInCombat() {
+        g.combatManager.tactics.OnCasualty(actor)
+    }
+    // dead companions neither share the xp nor get dismissed after the fight
+    delete(g.combatManager.companions, actor)
     g.growBloodAt(actor.Pos())
     g.dropActorInventory(actor)
     if !g.IsPlayerControlled(actor) {
-        g.currentMap.SetActorToDowned(actor) // IS THIS A GOOD IDEA?
-        g.Print(fmt.Sprintf("'%s' died", actor.Name()))
-        // award xp for the Kill
-        g.AddXP(actor.GetXPForKilling())
+        if actor.IsSummoned() {
+            // nothing is left behind
+            g.currentMap.RemoveActor(actor)
+            g.Print(fmt.Sprintf("'%s' is destroyed", actor.Name()))
+        } else {
+            g.currentMap.SetActorToDowned(actor) // IS THIS A GOOD IDEA?
+            g.Print(fmt.Sprintf("'%s' died", actor.Name()))
+        }
+        // award xp for the Kill, companions take their share
+        if !actor.IsCompanion() {
+            g.AddXP(g.rules.GetXPShare(actor.GetXPForKilling(), g.GetPartySize(), len(g.combatManager.companions)))
+        }
+        actor.ReleaseCompanion()
         println(fmt.Sprintf("'%s' died at %s", actor.Name(), actor.Pos().String()))
     }
 }
@@ -972,8 +1099,13 @@ func (g *GridEngine) openPartyOverView() {
     g.ShowFixedFormatText(g.playerParty.GetPartyOverview())
 }
 
-func (g *GridEngine) DeliverMeleeDamage(attacker *game.Actor, victim *game.Actor) {
-    damage := g.rules.GetMeleeDamage(attacker, victim)
+func (g *GridEngine) DeliverMeleeDamage(attacker *game.Actor, victim *game.Actor, isCritical bool) {
+    breakdown := g.rules.GetMeleeDamageBreakdown(attacker, victim, isCritical)
+    g.GetCombatLog().LogDamage(attacker, victim, breakdown)
+    damage := breakdown.Total
+    if isCritical {
+        g.Print(fmt.Sprintf("Critical hit by '%s'!", attacker.Name()))
+    }
 
     if damage > 0 {
         victim.Damage(g, damage)
@@ -984,10 +1116,18 @@ func (g *GridEngine) DeliverMeleeDamage(attacker *game.Actor, victim *game.Actor
 
     // hit procs
     attacker.OnMeleeHitPerformed(g, victim)
+    if isCritical {
+        attacker.OnCriticalHitPerformed(g, victim, false)
+    }
 }
 
-func (g *GridEngine) DeliverRangedDamage(attacker *game.Actor, victim *game.Actor) {
-    damage := g.rules.GetRangedDamage(attacker, victim)
+func (g *GridEngine) DeliverRangedDamage(attacker *game.Actor, victim *game.Actor, isCritical bool) {
+    breakdown := g.rules.GetRangedDamageBreakdown(attacker, victim, isCritical)
+    g.GetCombatLog().LogDamage(attacker, victim, breakdown)
+    damage := breakdown.Total
+    if isCritical {
+        g.Print(fmt.Sprintf("Critical hit by '%s'!", attacker.Name()))
+    }
 
     if damage > 0 {
         victim.Damage(g, damage)
@@ -997,15 +1137,18 @@ func (g *GridEngine) DeliverRangedDamage(attacker *game.Actor, victim *game.Acto
     }
     // hit procs
     attacker.OnRangedHitPerformed(g, victim)
+    if isCritical {
+        attacker.OnCriticalHitPerformed(g, victim, true)
+    }
 }
 
-func (g *GridEngine) DeliverSpellDamage(attacker *game.Actor, victim *game.Actor, amount int) {
-    baseSpellDamage := amount
-    victimDefense := victim.GetMagicDefense()
-    damage := baseSpellDamage - victimDefense
+func (g *GridEngine) DeliverSpellDamage(attacker *game.Actor, victim *game.Actor, amount int, damageType game.DamageType) {
+    breakdown := g.rules.GetSpellDamageBreakdown(victim, amount, damageType)
+    g.GetCombatLog().LogDamage(attacker, victim, breakdown)
+    damage := breakdown.Total
     if damage > 0 {
         victim.Damage(g, damage)
-        g.Print(fmt.Sprintf("%d dmg. to '%s'", damage, victim.Name()))
+        g.Print(fmt.Sprintf("%d %s dmg. to '%s'", damage, damageType, victim.Name()))
     } else {
         g.Print(fmt.Sprintf("No dmg. to '%s'", victim.Name()))
     }
@@ -1044,9 +1187,6 @@ func (g *GridEngine) getAllLoadedMaps() map[string]*gridmap.GridMap[*game.Actor,
     return mapsInMemory
 }
 
-func (g *GridEngine) openPrintLog() {
-    g.ShowFixedFormatText(g.printLog)
-}
 
 func (g *GridEngine) AddSkill(avatar *game.Actor, skill string) {
     avatar.GetSkills().IncrementSkill(game.SkillName(skill))
@@ -1054,9 +1194,15 @@ func (g *GridEngine) AddSkill(avatar *game.Actor, skill string) {
 }
 
 func (g *GridEngine) AddStatusEffect(actor *game.Actor, effect game.StatusEffect, stacks int) {
+    if actor.IsImmuneTo(effect.Name()) {
+        g.GetCombatLog().LogEffect(actor, fmt.Sprintf("is immune to %s", effect.Name()))
+        g.Print(fmt.Sprintf("%s is immune to %s", actor.Name(), effect.Name()))
+        return
+    }
     for i := 0; i < stacks; i++ {
         actor.AddStatusEffect(g, effect)
     }
+    g.GetCombatLog().LogEffect(actor, fmt.Sprintf("receives %s x%d", effect.Name(), stacks))
     if stacks > 1 {
         g.Print(fmt.Sprintf("%s received %s status x%d", actor.Name(), effect.Name(), stacks))
     } else {
@@ -1090,7 +1236,7 @@ func (g *GridEngine) loadPartyIcons() {
 func (g *GridEngine) CreateItemsForVendor(lootType game.Loot, level int) []game.Item {
     switch lootType {
     case game.LootScrolls:
-        return g.createScrollsForVendor(level, 10)
+        return append(g.createScrollsForVendor(level, 8), g.createWandsForVendor(level, 2)...)
     case game.LootCommon:
         return g.createItemsForGeneralStoreVendor(level, 10)
     case game.LootArmor:
@@ -1205,7 +1351,7 @@ func (g *GridEngine) TryMoveAvatarWithPathfinding(pos geometry.Point) {
     currentMap := g.currentMap
     ourActor := g.GetAvatar()
     currentPath := currentMap.GetJPSPath(ourActor.Pos(), pos, func(point geometry.Point) bool {
-        return currentMap.Contains(point) && currentMap.IsWalkableFor(point, ourActor)
+        return currentMap.Contains(point) && currentMap.IsWalkableFor(point, ourActor) && (point == pos || !currentMap.IsObviousHazardAt(point))
     })
     // remove the first element, which is the current position
     if len(currentPath) > 0 {
@@ -4,14 +4,13 @@ import (
     "Legacy/ega"
     "Legacy/game"
     "Legacy/geometry"
-    "Legacy/gocoro"
     "Legacy/ui"
     "Legacy/util"
     "fmt"
     "github.com/hajimehoshi/ebiten/v2"
     "image/color"
     "os"
-    "time"
+    "slices"
 )
 
 func (g *GridEngine) openContextMenu(menu []util.MenuItem) {
@@ -72,22 +71,29 @@ func (g *GridEngine) openActiveSkillsMenu(member *game.Actor, actions []game.Act
             TextColor:   itemColor,
             TooltipText: activeSkill.GetDescription(),
             Action: func() {
-                if !activeSkill.CanPayCost(g, member) {
-                    g.Print("You can't pay the cost for this ability.")
-                    return
-                }
-                if activeSkill.IsTargeted() {
-                    g.CloseAllModals()
-                    g.combatManager.PlayerUsesActiveSkill(member, activeSkill)
-                } else {
-                    activeSkill.Execute(g, member)
-                }
+                g.useActiveSkill(member, activeSkill)
             },
         })
     }
     g.OpenMenu(menuItems)
 }
 
+func (g *GridEngine) useActiveSkill(member *game.Actor, activeSkill game.Action) {
+    if !activeSkill.CanPayCost(g, member) {
+        g.Print("You can't pay the cost for this ability.")
+        return
+    }
+    if activeSkill.IsTargeted() {
+        g.CloseAllModals()
+        g.combatManager.PlayerUsesActiveSkill(member, activeSkill)
+    } else if g.IsInCombat() {
+        g.CloseAllModals()
+        g.combatManager.UseUntargetedAction(member, activeSkill)
+    } else {
+        activeSkill.Execute(g, member)
+    }
+}
+
 func (g *GridEngine) ShowDrinkPotionMenu(potion *game.Potion) {
     var menuItems []util.MenuItem
     for _, m := range g.playerParty.GetMembers() {
@@ -271,6 +277,12 @@ func (g *GridEngine) openDebugMenu() {
                 g.ShowScrollableText(g.flags.GetDebugInfo(), color.White, false)
             },
         },
+        {
+            Text: "Show all Bounties",
+            Action: func() {
+                g.ShowScrollableText(g.bounties.GetDebugInfo(), color.White, false)
+            },
+        },
         {
             Text: "Show XP Table",
             Action: func() {
@@ -302,45 +314,7 @@ func (g *GridEngine) startActorPath(internalName, pathName string) {
         g.Print(fmt.Sprintf("No actor %s", internalName))
         return
     }
-    err := g.RunAnimationScript(g.getNPCMovementRoutine(actor, waypoints))
-    if err != nil {
-        println(err.Error())
-    }
-}
-func (g *GridEngine) getNPCMovementRoutine(actor *game.Actor, waypoints []geometry.Point) func(exe *gocoro.Execution) {
-    return func(exe *gocoro.Execution) {
-        for _, currentWaypoint := range waypoints {
-            for actor.IsSleeping() {
-                _ = exe.YieldTime(2 * time.Second)
-            }
-            var currentPath []geometry.Point
-            moveBlockedCount := 0
-            for actor.Pos() != currentWaypoint {
-                //
-                if currentPath == nil || len(currentPath) == 0 {
-                    currentPath = g.currentMap.GetJPSPath(actor.Pos(), currentWaypoint, g.currentMap.IsCurrentlyPassable)
-                    if len(currentPath) > 0 {
-                        currentPath = currentPath[1:] // remove the first element, which is the current position
-                    }
-                }
-                for len(currentPath) > 0 {
-                    moveBlockedCount = 0
-                    nextPos := currentPath[0]
-                    currentPath = currentPath[1:]
-                    for actor.Pos() != nextPos {
-                        g.TryMoveNPCOnPath(actor, nextPos)
-                        _ = exe.YieldTime(900 * time.Millisecond)
-                        moveBlockedCount++
-                        if moveBlockedCount > 5 {
-                            currentPath = nil
-                            moveBlockedCount = 0
-                            break
-                        }
-                    }
-                }
-            }
-        }
-    }
+    actor.SetPatrol(game.NewPatrol(pathName, slices.Clone(waypoints), 0))
 }
 
 func (g *GridEngine) ChangeAppearance() {
@@ -361,6 +335,7 @@ func (g *GridEngine) saveGameToDirectory(directory string) {
 
     savePartyState(g.playerParty, directory) // current map is saved in party state
     saveExtendedState(g.flags, g.playerKnowledge, directory)
+    saveBounties(g.bounties, directory)
     saveAllMaps(g.getAllLoadedMaps(), directory)
 
     g.PlacePartyBackOnCurrentMap()
@@ -374,6 +349,7 @@ func (g *GridEngine) loadGameFromDirectory(directory string) {
     g.playerParty = party
 
     g.flags, g.playerKnowledge = loadExtendedState(directory) //TODO
+    g.bounties = loadBounties(directory)
     g.mapsInMemory = loadAllMaps(directory)
 
     // set the current map
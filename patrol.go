@@ -0,0 +1,54 @@
+package main
+
+import (
+    "Legacy/game"
+)
+
+// updatePatrols walks the patrolling NPCs on the current map one step per minute of world time.
+// Patrols pause while the party is fighting or talking to someone.
+func (g *GridEngine) updatePatrols(minutes int) {
+    if g.IsInCombat() || g.IsInConversation() {
+        return
+    }
+    steps := min(minutes, maxScheduleStepsPerUpdate)
+    for _, actor := range g.currentMap.Actors() {
+        if !g.isPatrolling(actor) {
+            continue
+        }
+        g.walkPatrol(actor, steps)
+    }
+}
+
+// isPatrolling is true for NPCs with a patrol, unless their schedule has them doing something else.
+func (g *GridEngine) isPatrolling(actor *game.Actor) bool {
+    if !actor.HasPatrol() || !actor.IsAlive() || actor.IsSleeping() || g.IsPlayerControlled(actor) {
+        return false
+    }
+    if !actor.HasSchedule() {
+        return true
+    }
+    schedule := actor.GetSchedule()
+    if _, isOnTheWay := schedule.NextWaypoint(); isOnTheWay {
+        return false
+    }
+    entry, hasEntry := schedule.ActiveEntry()
+    return hasEntry && entry.Activity == game.ScheduleActivityWork
+}
+
+func (g *GridEngine) walkPatrol(actor *game.Actor, steps int) {
+    patrol := actor.GetPatrol()
+    for i := 0; i < steps; i++ {
+        waypoint, isWalking := patrol.NextWaypoint(actor.Pos())
+        if !isWalking {
+            continue
+        }
+        path := g.currentMap.GetJPSPath(actor.Pos(), waypoint, g.currentMap.IsCurrentlyPassableAndSafe)
+        if len(path) < 2 {
+            // blocked, try the next one
+            patrol.SkipWaypoint()
+            continue
+        }
+        g.TryMoveNPCOnPath(actor, path[1])
+        patrol.OnMoved(actor.Pos())
+    }
+}
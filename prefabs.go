@@ -0,0 +1,139 @@
+package main
+
+import (
+    "Legacy/dungen"
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "fmt"
+    "math/rand"
+    "slices"
+)
+
+// reservePrefabSpace picks one of the prefabs of the branch and makes room for it in the layout.
+// Prefabs are small LDtk levels, like vaults, shrines or boss arenas.
+func (g *GridEngine) reservePrefabSpace(layout *dungen.DungeonMap, branch dungeonBranch, random *rand.Rand) (string, geometry.Rect, bool) {
+    if len(branch.prefabs) == 0 || random.Float64() >= prefabChance {
+        return "", geometry.Rect{}, false
+    }
+    prefabName := branch.prefabs[random.Intn(len(branch.prefabs))]
+    prefab := g.ldtkMapProject.LevelByIdentifier(prefabName)
+    if prefab == nil {
+        println(fmt.Sprintf("ERROR: no prefab level named '%s'", prefabName))
+        return "", geometry.Rect{}, false
+    }
+    environmentLayer := prefab.LayerByIdentifier("Environment")
+    space, hasSpace := layout.ReserveSpace(environmentLayer.CellWidth, environmentLayer.CellHeight, random)
+    return prefabName, space, hasSpace
+}
+
+// stampPrefab copies the tiles and entities of a prefab level into a generated map.
+// It uses the same conversions as loadMap, so prefabs are authored like any other level.
+func (g *GridEngine) stampPrefab(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], prefabName string, offset geometry.Point, mapDisplayName string) {
+    worldTileset := g.ldtkMapProject.TilesetByIdentifier("World")
+    prefab := g.ldtkMapProject.LevelByIdentifier(prefabName)
+
+    environmentLayer := prefab.LayerByIdentifier("Environment")
+    metaLayer := prefab.LayerByIdentifier("Meta")
+    itemLayer := prefab.LayerByIdentifier("Items")
+    objectLayer := prefab.LayerByIdentifier("Objects")
+    npcLayer := prefab.LayerByIdentifier("NPCs")
+
+    for _, tile := range environmentLayer.Tiles {
+        posX, posY := environmentLayer.ToGridPosition(tile.Position[0], tile.Position[1])
+        dungeonMap.SetTile(offset.Add(geometry.Point{X: posX, Y: posY}), tileFromLDtk(worldTileset, tile, dungeonMap.GetName()))
+    }
+
+    for _, entity := range metaLayer.Entities {
+        posX, posY := metaLayer.ToGridPosition(entity.Position[0], entity.Position[1])
+        // transitions and spawn points make no sense inside a dungeon
+        switch entity.Identifier {
+        case "Secret_Door":
+            dungeonMap.SetSecretDoorAt(offset.Add(geometry.Point{X: posX, Y: posY}))
+        case "Waypoint":
+            if nameProp := entity.PropertyByIdentifier("Name"); !nameProp.IsNull() {
+                var path []geometry.Point
+                for _, waypoint := range g.getPathFromRootEntity(entity) {
+                    path = append(path, offset.Add(waypoint))
+                }
+                dungeonMap.AddNamedPath(nameProp.AsString(), path)
+            }
+        }
+    }
+
+    for _, entity := range objectLayer.Entities {
+        posX, posY := objectLayer.ToGridPosition(entity.Position[0], entity.Position[1])
+        if mapObject := g.getObjectFromEntity(entity, mapDisplayName); mapObject != nil {
+            dungeonMap.AddObject(mapObject, offset.Add(geometry.Point{X: posX, Y: posY}))
+        } else {
+            println(fmt.Sprintf("ERROR: could not create object from entity %v", entity))
+        }
+    }
+
+    for _, entity := range itemLayer.Entities {
+        posX, posY := itemLayer.ToGridPosition(entity.Position[0], entity.Position[1])
+        if item := g.getItemFromEntity(entity, mapDisplayName); item != nil {
+            dungeonMap.AddItem(item, offset.Add(geometry.Point{X: posX, Y: posY}))
+        } else {
+            println(fmt.Sprintf("ERROR: could not create item from entity %v", entity))
+        }
+    }
+
+    for _, entity := range npcLayer.Entities {
+        posX, posY := npcLayer.ToGridPosition(entity.Position[0], entity.Position[1])
+        pos := offset.Add(geometry.Point{X: posX, Y: posY})
+        npc := g.getNPCFromEntity(entity)
+        npc.SetHomePosition(pos)
+        if patrol := npc.GetPatrol(); patrol != nil && !patrol.HasWaypoints() {
+            patrol.SetWaypoints(slices.Clone(dungeonMap.GetNamedPath(patrol.GetPathName())))
+        }
+        if entity.PropertyByIdentifier("IsAlive").AsBool() {
+            dungeonMap.AddActor(npc, pos)
+            g.onNPCMovedOrTeleported(dungeonMap, npc, pos)
+        } else {
+            npc.SetHealth(0)
+            dungeonMap.AddDownedActor(npc, pos)
+        }
+    }
+}
+
+// connectPrefab digs a tunnel from every opening in the outer wall of the prefab to the closest floor outside of it.
+func connectPrefab(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], prefabRect geometry.Rect, floorTile gridmap.Tile) {
+    prefabRect.Iter(func(pos geometry.Point) {
+        if !prefabRect.IsOnEdge(pos) || (!dungeonMap.IsTileWalkable(pos) && !dungeonMap.IsSecretDoorAt(pos)) {
+            return
+        }
+        for _, direction := range []geometry.CompassDirection{geometry.North, geometry.South, geometry.East, geometry.West} {
+            outside := pos.Add(direction.ToPoint())
+            if dungeonMap.Contains(outside) && !prefabRect.Contains(outside) {
+                digTunnelAround(dungeonMap, outside, prefabRect, floorTile)
+            }
+        }
+    })
+}
+
+// digTunnelAround makes the shortest tunnel from start to any floor outside the avoided area.
+func digTunnelAround(dungeonMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], start geometry.Point, avoid geometry.Rect, floorTile gridmap.Tile) {
+    cameFrom := map[geometry.Point]geometry.Point{start: start}
+    queue := []geometry.Point{start}
+    for len(queue) > 0 {
+        current := queue[0]
+        queue = queue[1:]
+        if dungeonMap.IsTileWalkable(current) {
+            for current != start {
+                current = cameFrom[current]
+                dungeonMap.SetTile(current, floorTile)
+            }
+            return
+        }
+        for _, direction := range []geometry.CompassDirection{geometry.North, geometry.South, geometry.East, geometry.West} {
+            next := current.Add(direction.ToPoint())
+            isOnBorder := next.X <= 0 || next.Y <= 0 || next.X >= dungeonMap.MapWidth-1 || next.Y >= dungeonMap.MapHeight-1
+            if _, isVisited := cameFrom[next]; isVisited || isOnBorder || avoid.Contains(next) {
+                continue
+            }
+            cameFrom[next] = current
+            queue = append(queue, next)
+        }
+    }
+}
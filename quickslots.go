@@ -0,0 +1,127 @@
+package main
+
+import (
+    "Legacy/ega"
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/ui"
+    "Legacy/util"
+    "fmt"
+    "github.com/hajimehoshi/ebiten/v2"
+    "image/color"
+)
+
+// useQuickSlot triggers the action in the slot. An empty slot opens the assignment menu instead.
+func (g *GridEngine) useQuickSlot(member *game.Actor, index int) {
+    slot := member.GetQuickSlot(index)
+    if slot.IsEmpty() {
+        g.openQuickSlotAssignMenu(member, index)
+        return
+    }
+    if g.IsInCombat() && (g.combatManager.IsAITurn() || !g.combatManager.canAct(member)) {
+        g.Print("You cannot act anymore this turn")
+        return
+    }
+    action, isAvailable := member.GetQuickSlotAction(index)
+    if !isAvailable {
+        g.Print(fmt.Sprintf("%s is not available.", slot.Name))
+        return
+    }
+    g.useActiveSkill(member, action)
+}
+
+func (g *GridEngine) openQuickSlotMenu(member *game.Actor) {
+    var menuItems []util.MenuItem
+    for i := 0; i < game.QuickSlotCount; i++ {
+        index := i
+        menuItems = append(menuItems, util.MenuItem{
+            Text: g.quickSlotLabel(member, index),
+            Action: func() {
+                g.openQuickSlotAssignMenu(member, index)
+            },
+        })
+    }
+    g.openMenuWithTitle(fmt.Sprintf("%s - Quick slots", member.Name()), menuItems)
+}
+
+func (g *GridEngine) openQuickSlotAssignMenu(member *game.Actor, index int) {
+    var menuItems []util.MenuItem
+    candidates := member.GetQuickSlotCandidates()
+    for _, kind := range []game.QuickSlotKind{game.QuickSlotKindSpell, game.QuickSlotKindSkill, game.QuickSlotKindItem} {
+        for _, a := range candidates[kind] {
+            action := a
+            slot := game.QuickSlot{Kind: kind, Name: action.Name()}
+            menuItems = append(menuItems, util.MenuItem{
+                Text:        action.Name(),
+                TooltipText: action.GetDescription(),
+                Action: func() {
+                    member.SetQuickSlot(index, slot)
+                    g.Print(fmt.Sprintf("%s assigned to quick slot %d", slot.Name, index+1))
+                },
+            })
+        }
+    }
+    if !member.GetQuickSlot(index).IsEmpty() {
+        menuItems = append(menuItems, util.MenuItem{
+            Text: "Clear",
+            Action: func() {
+                member.ClearQuickSlot(index)
+            },
+        })
+    }
+    if len(menuItems) == 0 {
+        g.ShowText([]string{"Nothing available."})
+        return
+    }
+    g.openMenuWithTitle(fmt.Sprintf("Quick slot %d", index+1), menuItems)
+}
+
+func (g *GridEngine) quickSlotLabel(member *game.Actor, index int) string {
+    slot := member.GetQuickSlot(index)
+    if slot.IsEmpty() {
+        return fmt.Sprintf("%d: -", index+1)
+    }
+    if action, isAvailable := member.GetQuickSlotAction(index); isAvailable {
+        return fmt.Sprintf("%d: %s", index+1, action.LabelWithCost())
+    }
+    return fmt.Sprintf("%d: %s", index+1, slot.Name)
+}
+
+// quickSlotAt maps a cell of the upper border to a quick slot of the avatar.
+func (g *GridEngine) quickSlotAt(x, y int) (int, bool) {
+    if y != g.quickSlotsButton.Y || x < g.quickSlotsButton.X {
+        return 0, false
+    }
+    offset := x - g.quickSlotsButton.X
+    if offset%2 != 0 || offset/2 >= game.QuickSlotCount {
+        return 0, false
+    }
+    return offset / 2, true
+}
+
+func (g *GridEngine) quickSlotTooltip(index int, x, y int) ui.Tooltip {
+    lines := []string{fmt.Sprintf("Ctrl+%s", g.quickSlotLabel(g.GetAvatar(), index))}
+    if action, isAvailable := g.GetAvatar().GetQuickSlotAction(index); isAvailable {
+        lines = append(lines, "")
+        lines = append(lines, action.GetDescription()...)
+    }
+    return ui.NewTextTooltip(g.gridRenderer, lines, geometry.Point{X: x, Y: y})
+}
+
+// drawQuickSlotBar shows the slots of the avatar on the upper border.
+// Bright slots are ready, dimmed ones can't be used right now.
+func (g *GridEngine) drawQuickSlotBar(screen *ebiten.Image) {
+    avatar := g.GetAvatar()
+    for i := 0; i < game.QuickSlotCount; i++ {
+        var slotColor color.Color = ega.BrightBlack
+        if action, isAvailable := avatar.GetQuickSlotAction(i); isAvailable {
+            slotColor = ega.White
+            if action.CanPayCost(g, avatar) {
+                slotColor = ega.BrightWhite
+            }
+        } else if !avatar.GetQuickSlot(i).IsEmpty() {
+            slotColor = ega.Red
+        }
+        g.gridRenderer.DrawColoredString(screen, g.quickSlotsButton.X+i*2, g.quickSlotsButton.Y, fmt.Sprintf("%d", i+1), slotColor)
+    }
+}
@@ -0,0 +1,11 @@
+// Package atlas names the texture atlases, so that game logic can refer to them without loading the renderer.
+package atlas
+
+type Name int
+
+const (
+    Characters Name = iota
+    World
+    Entities
+    EntitiesGrayscale
+)
@@ -2,6 +2,7 @@ package renderer
 
 import (
     "Legacy/geometry"
+    "Legacy/renderer/atlas"
     "Legacy/util"
     "github.com/hajimehoshi/ebiten/v2"
     "image"
@@ -9,13 +10,13 @@ import (
     "strings"
 )
 
-type AtlasName int
+type AtlasName = atlas.Name
 
 const (
-    AtlasCharacters AtlasName = iota
-    AtlasWorld
-    AtlasEntities
-    AtlasEntitiesGrayscale
+    AtlasCharacters        = atlas.Characters
+    AtlasWorld             = atlas.World
+    AtlasEntities          = atlas.Entities
+    AtlasEntitiesGrayscale = atlas.EntitiesGrayscale
 )
 
 type DualGridRenderer struct {
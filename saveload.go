@@ -9,6 +9,7 @@ import (
     "os"
     "path"
     "strconv"
+    "time"
 )
 
 // what do we need to save?
@@ -74,10 +75,33 @@ func loadMap(metaFilename string, binFilename string) *gridmap.GridMap[*game.Act
         return nil
     }
     // create a map and read the tiles
-    gridMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](width, height, 12)
+    gridMap := gridmap.NewEmptyMap[*game.Actor, game.Item, game.Object](width, height, gridmap.DefaultMaxVisionRange)
     gridMap.ReadTiles(f)
     f.Close()
 
+    // older saves lack some of the fields, they keep the defaults of the new map
+    globalData := gridMap.GetGlobalData()
+    for _, field := range coreInfo {
+        switch field.Name {
+        case "criminalOffenseEvent":
+            gridMap.CriminalOffenseEvent = field.Value
+        case "displayName":
+            globalData.MissionTitle = field.Value
+        case "playerSpawn":
+            globalData.PlayerSpawn = geometry.MustDecodePoint(field.Value)
+        case "maxLightIntensity":
+            globalData.MaxLightIntensity = field.AsFloat()
+        case "maxVisionRange":
+            globalData.MaxVisionRange = field.AsInt()
+        case "timeOfDay":
+            if timeOfDay, err := time.Parse(time.RFC3339, field.Value); err == nil {
+                globalData.TimeOfDay = timeOfDay
+            }
+        case "ambienceSoundCue":
+            globalData.AmbienceSoundCue = field.Value
+        }
+    }
+    gridMap.SetGlobalData(globalData)
     placeMapObjects(gridMap, mapRecords)
 
     return gridMap
@@ -179,10 +203,18 @@ func saveMap(name string, gridMap *gridmap.GridMap[*game.Actor, game.Item, game.
     var coreInfo []recfile.Record
     var transitionInfos []recfile.Record
     var secretDoorInfos []recfile.Record
+    globalData := gridMap.GetGlobalData()
     coreInfo = append(coreInfo, recfile.Record{
         recfile.Field{Name: "mapName", Value: gridMap.GetName()},
-        recfile.Field{Name: "width", Value: recfile.IntStr(gridMap.MapWidth)},
-        recfile.Field{Name: "height", Value: recfile.IntStr(gridMap.MapHeight)},
+        recfile.Field{Name: "width", Value: recfile.IntStr(globalData.Width)},
+        recfile.Field{Name: "height", Value: recfile.IntStr(globalData.Height)},
+        recfile.Field{Name: "criminalOffenseEvent", Value: gridMap.CriminalOffenseEvent},
+        recfile.Field{Name: "displayName", Value: globalData.MissionTitle},
+        recfile.Field{Name: "playerSpawn", Value: globalData.PlayerSpawn.Encode()},
+        recfile.Field{Name: "maxLightIntensity", Value: recfile.FloatStr(globalData.MaxLightIntensity)},
+        recfile.Field{Name: "maxVisionRange", Value: recfile.IntStr(globalData.MaxVisionRange)},
+        recfile.Field{Name: "timeOfDay", Value: globalData.TimeOfDay.Format(time.RFC3339)},
+        recfile.Field{Name: "ambienceSoundCue", Value: globalData.AmbienceSoundCue},
     })
 
     for pos, transition := range gridMap.Transitions() {
@@ -231,6 +263,35 @@ func loadExtendedState(directory string) (*game.Flags, *game.PlayerKnowledge) {
     // TODO
     return game.NewFlags(), game.NewPlayerKnowledge()
 }
+func saveBounties(bounties *game.Bounties, destinationPath string) {
+    filename := path.Join(destinationPath, "bounties.rec")
+    f, err := os.Create(filename)
+    if err != nil {
+        fmt.Println("Error creating bounties file: " + err.Error())
+        return
+    }
+    defer f.Close()
+    writeErr := recfile.Write(f, []recfile.Record{bounties.ToRecord()})
+    if writeErr != nil {
+        fmt.Println("Error writing bounties to file: " + writeErr.Error())
+    }
+}
+
+func loadBounties(sourcePath string) *game.Bounties {
+    filename := path.Join(sourcePath, "bounties.rec")
+    f, err := os.Open(filename)
+    if err != nil {
+        // older saves have no bounties
+        return game.NewBounties()
+    }
+    defer f.Close()
+    records := recfile.Read(f)
+    if len(records) == 0 {
+        return game.NewBounties()
+    }
+    return game.NewBountiesFromRecord(records[0])
+}
+
 func savePartyState(party *game.Party, destinationPath string) bool {
     partyRecords := partyToRecords(party)
     // create a file
@@ -279,6 +340,8 @@ func recordsToParty(records map[string][]recfile.Record) (*game.Party, string) {
             party.SetGold(field.AsInt())
         case "lockpicks":
             party.SetLockpicks(field.AsInt())
+        case "formation":
+            party.SetFormation(game.FormationFromString(field.Value))
         case "item":
             previousItem = game.NewItemFromString(field.Value)
             party.AddItem(previousItem)
@@ -301,6 +364,7 @@ func partyToRecords(party *game.Party) map[string][]recfile.Record {
         recfile.Field{Name: "food", Value: strconv.Itoa(party.GetFood())},
         recfile.Field{Name: "gold", Value: strconv.Itoa(party.GetGold())},
         recfile.Field{Name: "lockpicks", Value: strconv.Itoa(party.GetLockpicks())},
+        recfile.Field{Name: "formation", Value: string(party.GetFormation())},
         recfile.Field{Name: "currentMap", Value: party.GetCurrentMapName()},
     }
 
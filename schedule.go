@@ -0,0 +1,107 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "slices"
+)
+
+// when more time passes at once, NPCs don't walk but just arrive at their destination
+const maxScheduleStepsPerUpdate = 30
+
+// updateSchedules lets the NPCs on the current map follow their daily routines.
+// They take one step per minute of world time.
+func (g *GridEngine) updateSchedules(minutes int) {
+    if g.IsInCombat() {
+        return
+    }
+    for _, actor := range g.currentMap.Actors() {
+        if !g.isFollowingSchedule(actor) {
+            continue
+        }
+        g.startScheduleEntryIfDue(actor)
+        if minutes > maxScheduleStepsPerUpdate {
+            g.skipToScheduleDestination(actor)
+            continue
+        }
+        g.walkSchedule(actor, minutes)
+    }
+}
+
+// catchUpWithSchedules moves the NPCs to where they would be by now.
+// Called when the party enters a map, time went on while they were gone.
+func (g *GridEngine) catchUpWithSchedules() {
+    for _, actor := range g.currentMap.Actors() {
+        if !g.isFollowingSchedule(actor) {
+            continue
+        }
+        g.startScheduleEntryIfDue(actor)
+        g.skipToScheduleDestination(actor)
+    }
+}
+
+func (g *GridEngine) isFollowingSchedule(actor *game.Actor) bool {
+    return actor.HasSchedule() && actor.IsAlive() && !actor.IsSleeping() && !g.IsPlayerControlled(actor) && !actor.IsAggressive()
+}
+
+func (g *GridEngine) startScheduleEntryIfDue(actor *game.Actor) {
+    schedule := actor.GetSchedule()
+    if !schedule.Update(g.worldTime.MinuteOfDay()) {
+        return
+    }
+    entry, _ := schedule.ActiveEntry()
+    schedule.SetRoute(g.getScheduleRoute(actor, entry))
+}
+
+// getScheduleRoute returns the waypoints for the entry, or nil if the destination is not on this map.
+func (g *GridEngine) getScheduleRoute(actor *game.Actor, entry game.ScheduleEntry) []geometry.Point {
+    if entry.Path != "" {
+        return slices.Clone(g.currentMap.GetNamedPath(entry.Path))
+    }
+    if entry.Location == "" || entry.Location == game.ScheduleHomeLocation {
+        return []geometry.Point{actor.GetHomePosition()}
+    }
+    if pos, err := geometry.NewPointFromEncodedString(entry.Location); err == nil {
+        return []geometry.Point{pos}
+    }
+    if pos, isNamedLocation := g.currentMap.NamedLocations[entry.Location]; isNamedLocation {
+        return []geometry.Point{pos}
+    }
+    return nil
+}
+
+func (g *GridEngine) walkSchedule(actor *game.Actor, steps int) {
+    schedule := actor.GetSchedule()
+    for i := 0; i < steps; i++ {
+        waypoint, hasWaypoint := schedule.NextWaypoint()
+        if !hasWaypoint {
+            return
+        }
+        if actor.Pos() == waypoint {
+            schedule.ReachedWaypoint()
+            continue
+        }
+        path := g.currentMap.GetJPSPath(actor.Pos(), waypoint, g.currentMap.IsCurrentlyPassableAndSafe)
+        if len(path) < 2 {
+            // already as close as it gets
+            schedule.ReachedWaypoint()
+            continue
+        }
+        g.TryMoveNPCOnPath(actor, path[1])
+    }
+}
+
+func (g *GridEngine) skipToScheduleDestination(actor *game.Actor) {
+    destination, hasDestination := actor.GetSchedule().SkipRoute()
+    if !hasDestination || actor.Pos() == destination {
+        return
+    }
+    if g.currentMap.IsCurrentlyPassable(destination) {
+        g.currentMap.MoveActor(actor, destination)
+        return
+    }
+    freeCells := g.currentMap.GetFreeCellsForDistribution(destination, 1, g.currentMap.IsCurrentlyPassable)
+    if len(freeCells) > 0 {
+        g.currentMap.MoveActor(actor, freeCells[0])
+    }
+}
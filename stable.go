@@ -50,6 +50,7 @@ func (g *GridEngine) onActorMovedOrTeleported(loadedMap *gridmap.GridMap[*game.A
         object := g.currentMap.ObjectAt(newPos)
         object.OnActorWalkedOn(actor)
     }
+    g.checkForTrapAt(loadedMap, actor, newPos)
     g.checkMoveHooks(actor, newPos)
 }
 
@@ -90,10 +91,14 @@ func (g *GridEngine) onPartyMemberMovedOrTeleported(loadedMap *gridmap.GridMap[*
     if g.isSneaking {
         g.updateSneakOverlays()
     }
+    if partyMember == g.GetAvatar() {
+        g.noticeTrapsNearby(newLocation)
+    }
+    g.checkForGuardsOnPatrol(newLocation)
     // check if we are near any aggressive actors, that would want to start combat
     for _, actor := range loadedMap.GetFilteredActorsInRadius(newLocation, 11, g.aggressiveActorsFilter(newLocation)) {
         if actor.IsInEngagementZone(newLocation) {
-            if !g.isSneaking || !g.SkillCheckVs(partyMember, game.ThievingSkillSneak, actor, game.Perception) {
+            if !g.isSneaking || !g.sneakCheckInLight(partyMember, actor) {
                 g.EnemyStartsCombat(actor)
                 return
             }
@@ -304,6 +309,7 @@ func (g *GridEngine) PopModal() {
 
 func (g *GridEngine) drawPeaceTimeStatusBar(screen *ebiten.Image) {
     g.drawUpperStatusBar(screen)
+    g.drawQuickSlotBar(screen)
 
     if g.ticksForPrint > 0 {
         g.drawPrintMessage(screen, false)
@@ -313,14 +319,14 @@ func (g *GridEngine) drawPeaceTimeStatusBar(screen *ebiten.Image) {
 }
 func (g *GridEngine) drawWarTimeStatusBar(screen *ebiten.Image) {
     g.drawLowerStatusBar(screen)
+    g.drawQuickSlotBar(screen)
     if g.ticksForPrint > 0 {
         g.drawPrintMessage(screen, true)
+    } else if g.combatManager.IsTargeting() {
+        screenSize := g.gridRenderer.GetSmallGridScreenSize()
+        g.combatManager.drawTargetInfo(screen, 1, screenSize.Y-2, ega.BrightYellow)
     } else {
-        if g.combatManager.IsAITurn() {
-            g.drawTextOnUpperStatusbar(screen, "Wait for your turn...")
-        } else {
-            g.drawCombatActionBar(screen)
-        }
+        g.drawTurnOrderStrip(screen)
     }
     /*
        else {
@@ -381,6 +387,7 @@ func (g *GridEngine) transitionToLocation(targetMap string, destPos geometry.Poi
 func (g *GridEngine) setMap(nextMap *gridmap.GridMap[*game.Actor, game.Item, game.Object]) {
     g.currentMap = nextMap
     g.levelHooks = game.GetHooksForLevel(g, g.currentMap.GetName())
+    g.catchUpWithSchedules()
 }
 
 type Interactables struct {
@@ -1,6 +1,7 @@
 package main
 
 import (
+    "Legacy/ega"
     "github.com/hajimehoshi/ebiten/v2"
     "image/color"
     "strconv"
@@ -16,11 +17,36 @@ func (g *GridEngine) drawLowerStatusBar(screen *ebiten.Image) {
         x = i * 10
         g.gridRenderer.DrawOnSmallGrid(screen, x, y, charStatus.HealthIcon)
         g.gridRenderer.DrawColoredString(screen, x+1, y, charStatus.Name, charStatus.StatusColor)
+        if charStatus.HasIndicator {
+            g.gridRenderer.DrawColoredString(screen, x+9, y, string(charStatus.Indicator), charStatus.IndicatorColor)
+        }
         //g.gridRenderer.DrawOnSmallGrid(screen, x+9, y, int(g.fontIndex[divider]))
     }
 }
-func (g *GridEngine) drawCombatActionBar(screen *ebiten.Image) {
-    //TOO MUCH?
+func (g *GridEngine) drawTurnOrderStrip(screen *ebiten.Image) {
+    screenSize := g.gridRenderer.GetSmallGridScreenSize()
+    yPos := screenSize.Y - 2
+    xPos := 1
+    entryWidth := 4
+    for i, actor := range g.combatManager.GetTurnOrder() {
+        if xPos+entryWidth > screenSize.X-1 {
+            break
+        }
+        name := actor.Name()
+        if len(name) > entryWidth-1 {
+            name = name[:entryWidth-1]
+        }
+        textColor := ega.BrightRed
+        if i == 0 {
+            textColor = ega.BrightYellow
+        } else if g.IsPlayerControlled(actor) {
+            textColor = ega.BrightWhite
+        } else if actor.IsCompanion() {
+            textColor = ega.BrightGreen
+        }
+        g.gridRenderer.DrawColoredString(screen, xPos, yPos, name, textColor)
+        xPos += entryWidth
+    }
 }
 func (g *GridEngine) drawUpperStatusBar(screen *ebiten.Image) {
     screenSize := g.gridRenderer.GetSmallGridScreenSize()
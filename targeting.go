@@ -0,0 +1,245 @@
+package main
+
+import (
+    "Legacy/ega"
+    "Legacy/game"
+    "Legacy/geometry"
+    "cmp"
+    "fmt"
+    "github.com/hajimehoshi/ebiten/v2"
+    "image/color"
+    "slices"
+)
+
+// targetPreview describes what will happen, if the current target is confirmed.
+type targetPreview struct {
+    path     []geometry.Point
+    affected []geometry.Point
+    info     string
+}
+
+// beginTargeting lets the player pick one of the valid targets.
+// Tab cycles through the targets, the arrow keys and the mouse select one, enter or a click confirms.
+func (c *CombatState) beginTargeting(attacker *game.Actor, validTargets map[geometry.Point]bool, preview func(target geometry.Point) targetPreview, onConfirm func(targetPos geometry.Point)) {
+    c.waitForTarget = func(targetPos geometry.Point) {
+        if _, isValidPos := c.validTargets[targetPos]; !isValidPos {
+            return
+        }
+        c.endTargeting()
+        onConfirm(targetPos)
+    }
+    c.validTargets = validTargets
+    c.previewTarget = preview
+    c.targetCursor = attacker.Pos()
+    c.hasTargetCursor = false
+    cycleOrder := c.getTargetCycleOrder()
+    if len(cycleOrder) > 0 {
+        c.selectTarget(cycleOrder[0])
+    } else {
+        c.updateTargetOverlay()
+    }
+}
+
+func (c *CombatState) endTargeting() {
+    c.waitForTarget = nil
+    c.validTargets = nil
+    c.previewTarget = nil
+    c.hasTargetCursor = false
+    c.currentPreview = targetPreview{}
+    c.engine.ClearOverlay()
+}
+
+func (c *CombatState) IsTargeting() bool {
+    return c.waitForTarget != nil
+}
+
+func (c *CombatState) GetTargetInfo() string {
+    return c.currentPreview.info
+}
+
+func (c *CombatState) selectTarget(targetPos geometry.Point) {
+    c.targetCursor = targetPos
+    c.hasTargetCursor = true
+    if c.previewTarget != nil {
+        c.currentPreview = c.previewTarget(targetPos)
+    }
+    c.updateTargetOverlay()
+}
+
+func (c *CombatState) confirmTarget() {
+    if c.hasTargetCursor && c.waitForTarget != nil {
+        c.waitForTarget(c.targetCursor)
+    }
+}
+
+// CycleTarget selects the next valid target that has an actor on it.
+func (c *CombatState) CycleTarget() {
+    cycleOrder := c.getTargetCycleOrder()
+    if len(cycleOrder) == 0 {
+        return
+    }
+    nextIndex := 0
+    if c.hasTargetCursor {
+        nextIndex = (slices.Index(cycleOrder, c.targetCursor) + 1) % len(cycleOrder)
+    }
+    c.selectTarget(cycleOrder[nextIndex])
+}
+
+// getTargetCycleOrder returns the valid targets with actors on them, nearest first.
+// If there are none, all valid targets are returned.
+func (c *CombatState) getTargetCycleOrder() []geometry.Point {
+    currentMap := c.engine.currentMap
+    origin := c.engine.GetAvatar().Pos()
+    var withActors, all []geometry.Point
+    for pos, _ := range c.validTargets {
+        all = append(all, pos)
+        if currentMap.IsActorAt(pos) && currentMap.ActorAt(pos) != c.engine.GetAvatar() {
+            withActors = append(withActors, pos)
+        }
+    }
+    result := withActors
+    if len(result) == 0 {
+        result = all
+    }
+    slices.SortFunc(result, func(one, two geometry.Point) int {
+        distanceOne := geometry.DistanceManhattan(origin, one)
+        distanceTwo := geometry.DistanceManhattan(origin, two)
+        if distanceOne != distanceTwo {
+            return distanceOne - distanceTwo
+        }
+        if one.Y != two.Y {
+            return cmp.Compare(one.Y, two.Y)
+        }
+        return cmp.Compare(one.X, two.X)
+    })
+    return result
+}
+
+// moveTargetCursor selects the next valid target in the given direction.
+func (c *CombatState) moveTargetCursor(direction geometry.Point) {
+    start := c.engine.GetAvatar().Pos()
+    if c.hasTargetCursor {
+        start = c.targetCursor
+    }
+    potentialTargetPos := start.Add(direction)
+    for counter := 0; counter < 20; counter++ {
+        if _, isValidPos := c.validTargets[potentialTargetPos]; isValidPos {
+            c.selectTarget(potentialTargetPos)
+            return
+        }
+        potentialTargetPos = potentialTargetPos.Add(direction)
+    }
+}
+
+func (c *CombatState) onTargetHovered(screenX, screenY int) bool {
+    if !c.engine.IsScreenPosInsideMap(screenX, screenY) {
+        return false
+    }
+    mapPos := c.engine.ScreenToMap(screenX, screenY)
+    if _, isValidPos := c.validTargets[mapPos]; !isValidPos || (c.hasTargetCursor && mapPos == c.targetCursor) {
+        return false
+    }
+    c.selectTarget(mapPos)
+    return true
+}
+
+func (c *CombatState) updateTargetOverlay() {
+    overlay := toColorMap(c.validTargets, ega.Green)
+    for _, pos := range c.currentPreview.path {
+        overlay[pos] = ega.BrightCyan
+    }
+    for _, pos := range c.currentPreview.affected {
+        overlay[pos] = ega.BrightRed
+    }
+    if c.hasTargetCursor {
+        overlay[c.targetCursor] = ega.BrightYellow
+    }
+    c.engine.SetOverlay(overlay)
+}
+
+func (c *CombatState) previewRangedAttack(attacker *game.Actor) func(target geometry.Point) targetPreview {
+    return func(target geometry.Point) targetPreview {
+        path := c.getLineOfSight(attacker.Pos(), target, c.isActorBlockingSightForAttacker(attacker))
+        if len(path) == 0 {
+            return targetPreview{}
+        }
+        finalDestination := path[len(path)-1]
+        preview := targetPreview{path: path[:len(path)-1], affected: []geometry.Point{finalDestination}}
+        if c.engine.currentMap.IsActorAt(finalDestination) {
+            victim := c.engine.currentMap.ActorAt(finalDestination)
+            chance := c.engine.rules.GetRangedHitChance(attacker, victim)
+            damage := c.engine.rules.GetRangedDamage(attacker, victim)
+            preview.info = formatHitPreview(victim.Name(), chance, damage)
+        }
+        return preview
+    }
+}
+
+func (c *CombatState) previewOrchestratedRangedAttack() func(target geometry.Point) targetPreview {
+    return func(target geometry.Point) targetPreview {
+        preview := targetPreview{affected: []geometry.Point{target}}
+        if !c.engine.currentMap.IsActorAt(target) {
+            return preview
+        }
+        victim := c.engine.currentMap.ActorAt(target)
+        expectedDamage := 0.0
+        for _, partyMember := range c.engine.GetPartyMembers() {
+            if !partyMember.HasRangedWeaponEquipped() {
+                continue
+            }
+            chance := c.engine.rules.GetRangedHitChance(partyMember, victim)
+            expectedDamage += chance * float64(c.engine.rules.GetRangedDamage(partyMember, victim))
+        }
+        preview.info = fmt.Sprintf("%s  Volley: ~%d dmg.", victim.Name(), int(expectedDamage))
+        return preview
+    }
+}
+
+func (c *CombatState) previewTargetedAction(attacker *game.Actor, action game.Action) func(target geometry.Point) targetPreview {
+    return func(target geometry.Point) targetPreview {
+        path := c.getLineOfSight(attacker.Pos(), target, c.isActorBlockingSightForAttacker(attacker))
+        if len(path) == 0 {
+            return targetPreview{}
+        }
+        finalDestination := path[len(path)-1]
+        preview := targetPreview{
+            path:     path[:len(path)-1],
+            affected: action.GetAffectedPositions(c.engine, attacker, finalDestination),
+        }
+        var victims []*game.Actor
+        for _, pos := range preview.affected {
+            if c.engine.currentMap.IsActorAt(pos) {
+                victims = append(victims, c.engine.currentMap.ActorAt(pos))
+            }
+        }
+        if len(victims) == 1 {
+            if chance, damage, hasPreview := action.GetHitPreview(c.engine, attacker, victims[0]); hasPreview {
+                preview.info = formatHitPreview(victims[0].Name(), chance, damage)
+            } else {
+                preview.info = victims[0].Name()
+            }
+        } else if len(victims) > 1 {
+            expectedDamage := 0.0
+            for _, victim := range victims {
+                if chance, damage, hasPreview := action.GetHitPreview(c.engine, attacker, victim); hasPreview {
+                    expectedDamage += chance * float64(damage)
+                }
+            }
+            preview.info = fmt.Sprintf("%d targets  Total: ~%d dmg.", len(victims), int(expectedDamage))
+        }
+        return preview
+    }
+}
+
+func formatHitPreview(name string, chance float64, damage int) string {
+    expectedDamage := int(chance * float64(damage))
+    return fmt.Sprintf("%s  Hit: %d%%  Dmg: %d (~%d)", name, int(chance*100), damage, expectedDamage)
+}
+
+func (c *CombatState) drawTargetInfo(screen *ebiten.Image, xPos, yPos int, textColor color.Color) {
+    info := c.GetTargetInfo()
+    if info == "" {
+        info = "Select a target (Tab to cycle)"
+    }
+    c.engine.gridRenderer.DrawColoredString(screen, xPos, yPos, info, textColor)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+    "Legacy/game"
+    "Legacy/geometry"
+    "Legacy/gridmap"
+    "Legacy/renderer"
+    "fmt"
+    "image/color"
+    "math/rand"
+)
+
+const (
+    trapNoticeRadius = 2
+    alarmRadius      = 12
+    poisonGasRadius  = 1
+    teleportDistance = 10
+)
+
+// checkForTrapAt springs an armed trap under the actor. NPCs are just as likely to walk into one as the party.
+func (g *GridEngine) checkForTrapAt(loadedMap *gridmap.GridMap[*game.Actor, game.Item, game.Object], actor *game.Actor, pos geometry.Point) {
+    if objectAt, hasObject := loadedMap.TryGetObjectAt(pos); hasObject {
+        if trap, isTrap := objectAt.(*game.Trap); isTrap && trap.IsArmed() {
+            g.SpringTrap(trap, actor)
+        }
+    }
+}
+
+func (g *GridEngine) SpringTrap(trap *game.Trap, victim *game.Actor) {
+    damage := trap.Spring()
+    g.flags.IncrementFlag("traps_sprung")
+    switch trap.GetType() {
+    case game.TrapTypePit:
+        g.Print(fmt.Sprintf("'%s' fell into a pit", victim.Name()))
+        g.damageByTrap(victim, damage, game.DamageTypeBlunt)
+    case game.TrapTypeAlarm:
+        g.Print(fmt.Sprintf("'%s' tripped an alarm", victim.Name()))
+        g.soundAlarm(trap.Pos(), victim)
+    case game.TrapTypeTeleport:
+        g.Print(fmt.Sprintf("'%s' stepped on a teleport rune", victim.Name()))
+        g.teleportByTrap(victim)
+    case game.TrapTypePoisonGas:
+        g.Print(fmt.Sprintf("'%s' released poison gas", victim.Name()))
+        for _, actor := range g.currentMap.GetFilteredActorsInRadius(trap.Pos(), poisonGasRadius, func(actor *game.Actor) bool { return actor.IsAlive() }) {
+            g.AddStatusEffect(actor, game.StatusPoisoned(), 1+damage/4)
+        }
+    default:
+        g.Print(fmt.Sprintf("'%s' stepped on a trap", victim.Name()))
+        g.damageByTrap(victim, damage, game.DamageTypePierce)
+    }
+}
+
+func (g *GridEngine) damageByTrap(victim *game.Actor, damage int, damageType game.DamageType) {
+    bloodIcon := int32(104)
+    g.CombatHitAnimation(victim.Pos(), renderer.AtlasWorld, bloodIcon, color.White, func() {
+        g.DeliverSpellDamage(nil, victim, damage, damageType)
+        if !victim.IsAlive() {
+            g.actorDied(victim)
+        }
+    })
+}
+
+// soundAlarm wakes up everyone nearby. If the party set it off, hostiles in earshot come for them.
+func (g *GridEngine) soundAlarm(pos geometry.Point, victim *game.Actor) {
+    listeners := g.currentMap.GetFilteredActorsInRadius(pos, alarmRadius, func(actor *game.Actor) bool {
+        return actor.IsAlive() && !g.IsPlayerControlled(actor)
+    })
+    for _, listener := range listeners {
+        if listener.IsSleeping() {
+            listener.RemoveStatusEffect(g, game.StatusEffectNameSleeping)
+        }
+    }
+    if !g.IsPlayerControlled(victim) || g.IsInCombat() {
+        return
+    }
+    for _, listener := range listeners {
+        if listener.IsAggressive() {
+            g.EnemyStartsCombat(listener)
+            return
+        }
+    }
+}
+
+// teleportByTrap sends the victim to a random place on the same map. The whole party goes, if one of them stepped on it.
+func (g *GridEngine) teleportByTrap(victim *game.Actor) {
+    travelers := []*game.Actor{victim}
+    if g.IsPlayerControlled(victim) {
+        travelers = g.playerParty.GetMembers()
+    }
+    var candidates []geometry.Point
+    for y := 0; y < g.currentMap.MapHeight; y++ {
+        for x := 0; x < g.currentMap.MapWidth; x++ {
+            pos := geometry.Point{X: x, Y: y}
+            if g.currentMap.IsCurrentlyPassableAndSafe(pos) && !g.currentMap.IsObjectAt(pos) && geometry.DistanceManhattan(pos, victim.Pos()) > teleportDistance {
+                candidates = append(candidates, pos)
+            }
+        }
+    }
+    if len(candidates) == 0 {
+        return
+    }
+    destination := candidates[rand.Intn(len(candidates))]
+    freeCells := g.currentMap.GetFreeCellsForDistribution(destination, len(travelers)-1, func(pos geometry.Point) bool {
+        return pos != destination && g.currentMap.IsCurrentlyPassableAndSafe(pos) && !g.currentMap.IsObjectAt(pos)
+    })
+    freeCells = append([]geometry.Point{destination}, freeCells...)
+    travelers = travelers[:min(len(travelers), len(freeCells))]
+    for i, traveler := range travelers {
+        g.currentMap.MoveActor(traveler, freeCells[i])
+    }
+    // everyone has to arrive, before the first one can start a fight
+    for _, traveler := range travelers {
+        g.onActorMovedOrTeleported(g.currentMap, traveler, traveler.Pos())
+    }
+}
+
+// noticeTrapsNearby is the passive perception check of the avatar. Every hidden trap gets one roll against danger sense.
+// A trap under the avatar is too late to notice, it springs right after.
+func (g *GridEngine) noticeTrapsNearby(pos geometry.Point) {
+    for _, object := range g.currentMap.Objects() {
+        trap, isTrap := object.(*game.Trap)
+        if !isTrap || !trap.IsHidden() || !trap.IsArmed() || trap.Pos() == pos || geometry.DistanceChebyshev(pos, trap.Pos()) > trapNoticeRadius {
+            continue
+        }
+        if !g.playerParty.CanSee(trap.Pos()) || !trap.RollNotice() {
+            continue
+        }
+        if g.SkillCheckAvatar(game.PerceptionSkillDangerSense, trap.GetDifficulty()) {
+            trap.Discover()
+            g.flags.IncrementFlag("found_hidden_things")
+            g.Print("You noticed a trap.")
+        }
+    }
+}
@@ -343,7 +343,8 @@ func (i *InventoryWindow) loadFilter() {
     }
 
     filtermap[InventoryPageConsumables] = func(item game.Item) bool {
-        if _, ok := item.(*game.Potion); ok {
+        switch item.(type) {
+        case *game.Potion, *game.Wand, *game.Throwable:
             return true
         }
         return false
@@ -370,6 +371,12 @@ func (i *InventoryWindow) loadFilter() {
         if _, ok := item.(*game.Potion); ok {
             return false
         }
+        if _, ok := item.(*game.Wand); ok {
+            return false
+        }
+        if _, ok := item.(*game.Throwable); ok {
+            return false
+        }
         if _, ok := item.(*game.Key); ok {
             return false
         }
@@ -1,9 +1,11 @@
 package util
 
-import "github.com/hajimehoshi/ebiten/v2"
+// TicksPerSecond returns the current update rate.
+// The game sets this to ebiten.ActualTPS, headless tools keep the default.
+var TicksPerSecond = func() float64 { return 60 }
 
 func GetLoopingFrameFromTick(tick uint64, delayInSeconds float64, frameCount int) int32 {
-    oneSecondInTicks := float64(max(60, uint64(ebiten.ActualTPS())))
+    oneSecondInTicks := float64(max(60, uint64(TicksPerSecond())))
     ticksPerInterval := delayInSeconds * oneSecondInTicks
     intervallCount := float64(tick) / ticksPerInterval
     return int32(intervallCount) % int32(frameCount)